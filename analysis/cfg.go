@@ -0,0 +1,254 @@
+// Package analysis provides static analysis of CHIP-8 ROMs: control-flow
+// recovery and the tools built on top of it.
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// Node is a single decoded instruction reachable from the entry point.
+type Node struct {
+	Addr  uint16
+	Raw   uint16
+	Instr chipper.Instruction
+	Err   error    // set if the instruction could not be decoded.
+	Succs []uint16 // intra-procedural successors (calls fall through).
+}
+
+// Target returns the NNN operand of the instruction.
+func (n Node) Target() uint16 {
+	return n.Raw & 0x0FFF //nolint:mnd
+}
+
+// Sub is a subroutine: an entry point and every instruction reachable from
+// it without following calls.
+type Sub struct {
+	Entry uint16
+	Name  string
+	Addrs []uint16 // sorted.
+	addrs map[uint16]bool
+}
+
+// Contains reports whether the instruction at addr belongs to the subroutine.
+func (s *Sub) Contains(addr uint16) bool {
+	return s.addrs[addr]
+}
+
+// CFG is the recovered control-flow graph of a ROM.
+type CFG struct {
+	Base  uint16
+	ROM   []byte
+	Nodes map[uint16]*Node
+	Subs  []*Sub // sorted by entry, the first one is the entry point.
+
+	// Calls maps the address of each CallSub to its target.
+	Calls map[uint16]uint16
+
+	// External holds instructions whose target lies outside the ROM image.
+	External map[uint16]uint16
+
+	// Indirect holds the addresses of JumpToAddrNNNPlusV0 instructions, whose
+	// targets can only be approximated.
+	Indirect []uint16
+}
+
+// BuildCFG decodes every instruction reachable from base and groups them into
+// subroutines.
+func BuildCFG(rom []byte, base uint16) *CFG {
+	cfg := &CFG{
+		Base:     base,
+		ROM:      rom,
+		Nodes:    make(map[uint16]*Node),
+		Calls:    make(map[uint16]uint16),
+		External: make(map[uint16]uint16),
+	}
+
+	entries := []uint16{base}
+	seenEntry := map[uint16]bool{base: true}
+
+	for len(entries) > 0 {
+		entry := entries[0]
+		entries = entries[1:]
+
+		sub := cfg.walk(entry)
+		cfg.Subs = append(cfg.Subs, sub)
+
+		for _, addr := range sub.Addrs {
+			target, ok := cfg.Calls[addr]
+			if !ok || seenEntry[target] || !cfg.InROM(target) {
+				continue
+			}
+
+			seenEntry[target] = true
+			entries = append(entries, target)
+		}
+	}
+
+	sort.Slice(cfg.Subs[1:], func(i, j int) bool {
+		return cfg.Subs[i+1].Entry < cfg.Subs[j+1].Entry
+	})
+
+	sort.Slice(cfg.Indirect, func(i, j int) bool { return cfg.Indirect[i] < cfg.Indirect[j] })
+
+	return cfg
+}
+
+// InROM reports whether a full instruction at addr lies within the ROM image.
+func (cfg *CFG) InROM(addr uint16) bool {
+	start := int(cfg.Base)
+	end := start + len(cfg.ROM)
+
+	return int(addr) >= start && int(addr)+chipper.InstructionSize <= end
+}
+
+// InImage reports whether the byte at addr lies within the ROM image.
+func (cfg *CFG) InImage(addr uint16) bool {
+	return addr >= cfg.Base && int(addr-cfg.Base) < len(cfg.ROM)
+}
+
+// Sub returns the subroutine with the given entry, or nil.
+func (cfg *CFG) Sub(entry uint16) *Sub {
+	for _, s := range cfg.Subs {
+		if s.Entry == entry {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// Addrs returns the addresses of all reachable instructions, sorted.
+func (cfg *CFG) Addrs() []uint16 {
+	addrs := make([]uint16, 0, len(cfg.Nodes))
+	for addr := range cfg.Nodes {
+		addrs = append(addrs, addr)
+	}
+
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+
+	return addrs
+}
+
+// IsCode reports whether the byte at addr is part of a reachable instruction.
+func (cfg *CFG) IsCode(addr uint16) bool {
+	if _, ok := cfg.Nodes[addr]; ok {
+		return true
+	}
+
+	_, ok := cfg.Nodes[addr-1]
+
+	return ok
+}
+
+func (cfg *CFG) walk(entry uint16) *Sub {
+	sub := &Sub{
+		Entry: entry,
+		Name:  subName(cfg.Base, entry),
+		addrs: make(map[uint16]bool),
+	}
+
+	work := []uint16{entry}
+
+	for len(work) > 0 {
+		addr := work[len(work)-1]
+		work = work[:len(work)-1]
+
+		if sub.addrs[addr] || !cfg.InROM(addr) {
+			continue
+		}
+
+		sub.addrs[addr] = true
+		sub.Addrs = append(sub.Addrs, addr)
+
+		node := cfg.node(addr)
+		work = append(work, node.Succs...)
+	}
+
+	sort.Slice(sub.Addrs, func(i, j int) bool { return sub.Addrs[i] < sub.Addrs[j] })
+
+	return sub
+}
+
+func (cfg *CFG) node(addr uint16) *Node {
+	if node, ok := cfg.Nodes[addr]; ok {
+		return node
+	}
+
+	off := int(addr - cfg.Base)
+	raw := cfg.ROM[off : off+chipper.InstructionSize]
+
+	instr, err := chipper.Decode(raw)
+	node := &Node{
+		Addr:  addr,
+		Raw:   uint16(raw[0])<<8 | uint16(raw[1]), //nolint:mnd
+		Instr: instr,
+		Err:   err,
+	}
+
+	cfg.Nodes[addr] = node
+
+	if err != nil {
+		return node
+	}
+
+	next := addr + chipper.InstructionSize
+
+	switch instr.Op { //nolint:exhaustive
+	case chipper.JumpNNN:
+		node.Succs = cfg.target(addr, node.Target())
+
+	case chipper.CallSub:
+		cfg.Calls[addr] = node.Target()
+		cfg.target(addr, node.Target())
+		node.Succs = []uint16{next}
+
+	case chipper.ReturnFromSub:
+
+	case chipper.JumpToAddrNNNPlusV0:
+		cfg.Indirect = append(cfg.Indirect, addr)
+		node.Succs = cfg.target(addr, node.Target())
+
+	default:
+		node.Succs = []uint16{next}
+		if IsSkip(instr.Op) {
+			node.Succs = append(node.Succs, next+chipper.InstructionSize)
+		}
+	}
+
+	return node
+}
+
+// target records targets outside of the ROM image, returning the successor
+// list for the ones inside it.
+func (cfg *CFG) target(from, to uint16) []uint16 {
+	if !cfg.InROM(to) {
+		cfg.External[from] = to
+
+		return nil
+	}
+
+	return []uint16{to}
+}
+
+// IsSkip reports whether op conditionally skips the next instruction.
+func IsSkip(op chipper.Opcode) bool {
+	switch op { //nolint:exhaustive
+	case chipper.SkipIfXEqNN, chipper.SkipIfXNotEqNN,
+		chipper.SkipIfXEqY, chipper.SkipIfXNotEqY,
+		chipper.SkipIfKeyInXIsPressed, chipper.SkipIfKeyInXNotPressed:
+		return true
+	default:
+		return false
+	}
+}
+
+func subName(base, entry uint16) string {
+	if entry == base {
+		return "main"
+	}
+
+	return fmt.Sprintf("sub_%03x", entry)
+}
@@ -0,0 +1,289 @@
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// Syntax selects the notation used by the decompiler.
+type Syntax string
+
+const (
+	SyntaxOcto Syntax = "octo"
+	SyntaxC    Syntax = "c"
+)
+
+// ParseSyntax returns the Syntax named by s.
+func ParseSyntax(s string) (Syntax, error) {
+	switch Syntax(strings.ToLower(s)) {
+	case SyntaxOcto:
+		return SyntaxOcto, nil
+	case SyntaxC:
+		return SyntaxC, nil
+	default:
+		return "", fmt.Errorf("unknown syntax '%s', want one of: octo, c", s)
+	}
+}
+
+type stmtKind int
+
+const (
+	stmtInstr stmtKind = iota
+	stmtLabel
+	stmtIf
+	stmtLoop
+	stmtGoto
+	stmtBreak
+	stmtContinue
+)
+
+// stmt is a node of the structured program.
+type stmt struct {
+	kind   stmtKind
+	node   *Node
+	addr   uint16 // label address, or the target of goto/break/continue.
+	cond   cond
+	then   []stmt
+	orElse []stmt
+}
+
+// cond is the condition under which a skip instruction skips.
+type cond struct {
+	node   *Node
+	negate bool
+}
+
+func (c cond) not() cond {
+	return cond{node: c.node, negate: !c.negate}
+}
+
+type loopCtx struct {
+	head uint16
+	exit uint16
+	ok   bool
+}
+
+type decompiler struct {
+	cfg *CFG
+	sub *Sub
+}
+
+// Decompile writes structured pseudo-code for rom, loaded at base, to w.
+func Decompile(w io.Writer, rom []byte, base uint16, syntax Syntax) error {
+	cfg := BuildCFG(rom, base)
+	p := &printer{w: w, syntax: syntax, cfg: cfg}
+
+	p.header()
+
+	for _, sub := range cfg.Subs {
+		if len(sub.Addrs) == 0 {
+			continue
+		}
+
+		d := &decompiler{cfg: cfg, sub: sub}
+		body := d.block(sub.Entry, maxAddr(sub)+chipper.InstructionSize, loopCtx{})
+		body = pruneLabels(body, gotoTargets(body, make(map[uint16]bool)))
+
+		p.sub(sub, body)
+	}
+
+	p.data()
+
+	return p.err
+}
+
+func maxAddr(sub *Sub) uint16 {
+	return sub.Addrs[len(sub.Addrs)-1]
+}
+
+// block structures the instructions of the subroutine in [lo, hi).
+func (d *decompiler) block(lo, hi uint16, loop loopCtx) []stmt { //nolint:cyclop
+	var out []stmt
+
+	addr := lo
+	for addr < hi {
+		if !d.sub.Contains(addr) {
+			next, ok := d.nextAddr(addr)
+			if !ok {
+				break
+			}
+
+			addr = next
+
+			continue
+		}
+
+		if !(loop.ok && loop.head == addr) {
+			out = append(out, stmt{kind: stmtLabel, addr: addr})
+		}
+
+		if end, ok := d.backEdge(addr, hi); ok && !(loop.ok && loop.head == addr) {
+			body := d.block(addr, end, loopCtx{head: addr, exit: end + chipper.InstructionSize, ok: true})
+			out = append(out, stmt{kind: stmtLoop, then: body})
+			addr = end + chipper.InstructionSize
+
+			continue
+		}
+
+		node := d.cfg.Nodes[addr]
+
+		if node.Err == nil && IsSkip(node.Instr.Op) {
+			s, next := d.skip(node, hi, loop)
+			out = append(out, s)
+			addr = next
+
+			continue
+		}
+
+		out = append(out, d.simple(node, loop))
+		addr += chipper.InstructionSize
+	}
+
+	return out
+}
+
+// skip structures a skip instruction together with what follows it.
+func (d *decompiler) skip(node *Node, hi uint16, loop loopCtx) (stmt, uint16) {
+	skipCond := cond{node: node}
+	nextAddr := node.Addr + chipper.InstructionSize
+	after := nextAddr + chipper.InstructionSize
+
+	next, ok := d.cfg.Nodes[nextAddr]
+	if !ok || !d.sub.Contains(nextAddr) || nextAddr >= hi {
+		exit := stmt{kind: stmtGoto, addr: after}
+		if loop.ok && after == loop.exit {
+			exit.kind = stmtBreak
+		}
+
+		return stmt{kind: stmtIf, cond: skipCond, then: []stmt{exit}}, nextAddr
+	}
+
+	// skip if C; jump T; body... T: is "if C { body }".
+	if next.Err == nil && next.Instr.Op == chipper.JumpNNN {
+		target := next.Target()
+		isLoopJump := loop.ok && (target == loop.head || target == loop.exit)
+
+		if target > after && target <= hi && !isLoopJump {
+			return d.ifElse(skipCond, after, target, hi, loop)
+		}
+	}
+
+	var then stmt
+	if next.Err == nil && IsSkip(next.Instr.Op) {
+		// A skip guarding another skip: keep the inner one as a conditional goto.
+		then = stmt{
+			kind: stmtIf,
+			cond: cond{node: next},
+			then: []stmt{{kind: stmtGoto, addr: after + chipper.InstructionSize}},
+		}
+	} else {
+		then = d.simple(next, loop)
+	}
+
+	return stmt{kind: stmtIf, cond: skipCond.not(), then: []stmt{then}}, after
+}
+
+func (d *decompiler) ifElse(c cond, lo, target, hi uint16, loop loopCtx) (stmt, uint16) {
+	lastAddr := target - chipper.InstructionSize
+
+	last, ok := d.cfg.Nodes[lastAddr]
+	if ok && lastAddr >= lo && d.sub.Contains(lastAddr) && last.Err == nil && last.Instr.Op == chipper.JumpNNN {
+		end := last.Target()
+		isLoopJump := loop.ok && (end == loop.head || end == loop.exit)
+
+		if end > target && end <= hi && !isLoopJump {
+			return stmt{
+				kind:   stmtIf,
+				cond:   c,
+				then:   d.block(lo, lastAddr, loop),
+				orElse: d.block(target, end, loop),
+			}, end
+		}
+	}
+
+	return stmt{kind: stmtIf, cond: c, then: d.block(lo, target, loop)}, target
+}
+
+// backEdge returns the furthest jump before hi that loops back to head.
+func (d *decompiler) backEdge(head, hi uint16) (uint16, bool) {
+	found := false
+	end := head
+
+	for _, addr := range d.sub.Addrs {
+		if addr < head || addr >= hi {
+			continue
+		}
+
+		node := d.cfg.Nodes[addr]
+		if node.Err != nil || node.Instr.Op != chipper.JumpNNN || node.Target() != head {
+			continue
+		}
+
+		found = true
+		end = addr
+	}
+
+	return end, found
+}
+
+func (d *decompiler) nextAddr(addr uint16) (uint16, bool) {
+	i := sort.Search(len(d.sub.Addrs), func(i int) bool { return d.sub.Addrs[i] >= addr })
+	if i == len(d.sub.Addrs) {
+		return 0, false
+	}
+
+	return d.sub.Addrs[i], true
+}
+
+func (d *decompiler) simple(node *Node, loop loopCtx) stmt {
+	if node.Err != nil || node.Instr.Op != chipper.JumpNNN {
+		return stmt{kind: stmtInstr, node: node}
+	}
+
+	target := node.Target()
+
+	switch {
+	case loop.ok && target == loop.head:
+		return stmt{kind: stmtContinue, addr: target}
+	case loop.ok && target == loop.exit:
+		return stmt{kind: stmtBreak, addr: target}
+	case target == node.Addr:
+		return stmt{kind: stmtLoop}
+	}
+
+	return stmt{kind: stmtGoto, addr: target}
+}
+
+func gotoTargets(body []stmt, refs map[uint16]bool) map[uint16]bool {
+	for _, s := range body {
+		if s.kind == stmtGoto {
+			refs[s.addr] = true
+		}
+
+		gotoTargets(s.then, refs)
+		gotoTargets(s.orElse, refs)
+	}
+
+	return refs
+}
+
+// pruneLabels drops the labels no goto refers to.
+func pruneLabels(body []stmt, refs map[uint16]bool) []stmt {
+	out := body[:0]
+
+	for _, s := range body {
+		if s.kind == stmtLabel && !refs[s.addr] {
+			continue
+		}
+
+		s.then = pruneLabels(s.then, refs)
+		s.orElse = pruneLabels(s.orElse, refs)
+		out = append(out, s)
+	}
+
+	return out
+}
@@ -0,0 +1,113 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// testProgram counts v0 up to 5 in a loop, calling a subroutine each time.
+var testProgram = []byte{
+	0x60, 0x00, // 200: v0 := 0
+	0x30, 0x05, // 202: skip if v0 == 5
+	0x12, 0x08, // 204: jump 208
+	0x12, 0x0E, // 206: jump 20E
+	0x22, 0x12, // 208: call 212
+	0x70, 0x01, // 20A: v0 += 1
+	0x12, 0x02, // 20C: jump 202
+	0x12, 0x0E, // 20E: jump 20E
+	0x00, 0x00, // 210: padding
+	0xA2, 0x18, // 212: i := 218
+	0xD0, 0x01, // 214: sprite v0 v0 1
+	0x00, 0xEE, // 216: return
+	0x80, //       218: sprite data
+}
+
+func TestCFG(t *testing.T) {
+	cfg := BuildCFG(testProgram, chipper.StartAddress)
+
+	if len(cfg.Subs) != 2 {
+		t.Fatalf("want 2 subroutines, got %d", len(cfg.Subs))
+	}
+
+	if name := cfg.Subs[1].Name; name != "sub_212" {
+		t.Fatalf("want sub_212, got %s", name)
+	}
+
+	if cfg.IsCode(0x210) {
+		t.Fatalf("padding at 0x210 should not be reachable")
+	}
+
+	if cfg.IsCode(0x218) {
+		t.Fatalf("sprite data at 0x218 should not be code")
+	}
+}
+
+func TestDecompile(t *testing.T) {
+	cases := []struct {
+		syntax Syntax
+		want   []string
+	}{
+		{SyntaxOcto, []string{
+			": main",
+			"loop",
+			"while v0 != 0x05",
+			"sub_212",
+			"v0 += 0x01",
+			"again",
+			": sub_212",
+			"i := data_218",
+			"sprite v0 v0 1",
+		}},
+		{SyntaxC, []string{
+			"void main(void) {",
+			"for (;;) {",
+			"if (v0 == 0x05) break;",
+			"sub_212();",
+			"v0 = v0 + 0x01;",
+			"i = data_218;",
+			"byte data_218[1] = {",
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(string(c.syntax), func(t *testing.T) {
+			b := &strings.Builder{}
+			if err := Decompile(b, testProgram, chipper.StartAddress, c.syntax); err != nil {
+				t.Fatalf("could not decompile: %v", err)
+			}
+
+			got := b.String()
+			for _, line := range c.want {
+				if !strings.Contains(got, line) {
+					t.Fatalf("output is missing %q:\n%s", line, got)
+				}
+			}
+		})
+	}
+}
+
+func TestDecompileROMs(t *testing.T) {
+	roms, err := filepath.Glob("../testdata/*.ch8")
+	if err != nil {
+		t.Fatalf("could not list roms: %v", err)
+	}
+
+	for _, name := range roms {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			rom, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatalf("could not read rom: %v", err)
+			}
+
+			for _, syntax := range []Syntax{SyntaxOcto, SyntaxC} {
+				if err := Decompile(&strings.Builder{}, rom, chipper.StartAddress, syntax); err != nil {
+					t.Fatalf("could not decompile: %v", err)
+				}
+			}
+		})
+	}
+}
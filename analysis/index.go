@@ -0,0 +1,335 @@
+package analysis
+
+import "github.com/aalbacetef/chipper"
+
+// IndexState is what is statically known about the I register.
+type IndexState struct {
+	Known bool
+	Addr  uint16
+}
+
+func (s IndexState) merge(o IndexState) IndexState {
+	if s.Known && o.Known && s.Addr == o.Addr {
+		return s
+	}
+
+	return IndexState{}
+}
+
+// IndexValues computes, for every reachable instruction, what is known about
+// I right before it executes. Instructions that set I to a computed value, as
+// well as calls into subroutines that do, make it unknown.
+func (cfg *CFG) IndexValues() map[uint16]IndexState {
+	clobbers := cfg.clobbersIndex()
+	in := map[uint16]IndexState{cfg.Base: {Known: true}}
+	work := []uint16{cfg.Base}
+
+	propagate := func(addr uint16, state IndexState) {
+		prev, seen := in[addr]
+		if seen {
+			state = prev.merge(state)
+			if state == prev {
+				return
+			}
+		}
+
+		in[addr] = state
+		work = append(work, addr)
+	}
+
+	for len(work) > 0 {
+		addr := work[len(work)-1]
+		work = work[:len(work)-1]
+
+		node, ok := cfg.Nodes[addr]
+		if !ok {
+			continue
+		}
+
+		out := indexTransfer(node, in[addr])
+
+		if callee, isCall := cfg.Calls[addr]; isCall {
+			if cfg.InROM(callee) {
+				propagate(callee, out)
+			}
+
+			if clobbers[callee] {
+				out = IndexState{}
+			}
+		}
+
+		for _, succ := range node.Succs {
+			propagate(succ, out)
+		}
+	}
+
+	return in
+}
+
+func indexTransfer(node *Node, in IndexState) IndexState {
+	if !writesIndex(node) {
+		return in
+	}
+
+	if node.Instr.Op == chipper.StoreMemAddrNNNInRegI {
+		return IndexState{Known: true, Addr: node.Target()}
+	}
+
+	return IndexState{}
+}
+
+func writesIndex(node *Node) bool {
+	if node.Err != nil {
+		return false
+	}
+
+	switch node.Instr.Op { //nolint:exhaustive
+	case chipper.StoreMemAddrNNNInRegI, chipper.AddXToI, chipper.SetIToMemAddrOfSpriteInX:
+		return true
+	default:
+		return false
+	}
+}
+
+// clobbersIndex reports, for each subroutine entry, whether calling it may
+// change I.
+func (cfg *CFG) clobbersIndex() map[uint16]bool {
+	clobbers := make(map[uint16]bool)
+
+	for changed := true; changed; {
+		changed = false
+
+		for _, sub := range cfg.Subs {
+			if clobbers[sub.Entry] {
+				continue
+			}
+
+			for _, addr := range sub.Addrs {
+				node := cfg.Nodes[addr]
+				callee, isCall := cfg.Calls[addr]
+				if writesIndex(node) || (isCall && clobbers[callee]) || (isCall && !cfg.InROM(callee)) {
+					clobbers[sub.Entry] = true
+					changed = true
+
+					break
+				}
+			}
+		}
+	}
+
+	return clobbers
+}
+
+// anyAddr marks an I that may point anywhere, e.g. after a call outside the
+// ROM image.
+const anyAddr = 0xFFFF
+
+// indexOrigin is a StoreMemAddrNNNInRegI target I may have been derived from.
+type indexOrigin struct {
+	addr   uint16
+	offset bool // moved off addr by AddXToI.
+}
+
+type originSet map[indexOrigin]bool
+
+// union returns s with the elements of o added, and whether any were new.
+func (s originSet) union(o originSet) (originSet, bool) {
+	grew := false
+	out := s
+
+	for origin := range o {
+		if s[origin] {
+			continue
+		}
+
+		if !grew {
+			out = make(originSet, len(s)+len(o))
+			for k := range s {
+				out[k] = true
+			}
+
+			grew = true
+		}
+
+		out[origin] = true
+	}
+
+	return out, grew
+}
+
+// DataUse summarises how a ROM accesses the data at a StoreMemAddrNNNInRegI
+// target.
+type DataUse struct {
+	Extent  int  // bytes drawn by DrawSpriteInXY with I at the target.
+	Indexed bool // also reached through AddXToI or as memory, extent unknown.
+}
+
+// dataUses tracks the possible origins of I through the program and reports
+// how each target is used. anyUse is set if memory is accessed through an I of
+// unknown origin, codeWrite if code may be written to.
+func (cfg *CFG) dataUses() (uses map[uint16]*DataUse, anyUse, codeWrite bool) {
+	in := cfg.indexOrigins()
+	uses = make(map[uint16]*DataUse)
+
+	use := func(addr uint16) *DataUse {
+		if uses[addr] == nil {
+			uses[addr] = &DataUse{}
+		}
+
+		return uses[addr]
+	}
+
+	for addr, node := range cfg.Nodes {
+		if node.Instr.Op == chipper.StoreMemAddrNNNInRegI {
+			use(node.Target())
+		}
+
+		n, isWrite, accesses := memAccess(node)
+		if !accesses {
+			continue
+		}
+
+		for origin := range in[addr] {
+			switch {
+			case origin.addr == anyAddr:
+				anyUse = true
+				codeWrite = codeWrite || isWrite
+			case origin.offset || node.Instr.Op != chipper.DrawSpriteInXY || n == 0:
+				use(origin.addr).Indexed = true
+			default:
+				u := use(origin.addr)
+				u.Extent = max(u.Extent, n)
+			}
+
+			if isWrite && !origin.offset && cfg.overlapsCode(origin.addr, n) {
+				codeWrite = true
+			}
+		}
+	}
+
+	return uses, anyUse, codeWrite
+}
+
+func (cfg *CFG) overlapsCode(start uint16, n int) bool {
+	for k := 0; k < n; k++ {
+		if cfg.IsCode(start + uint16(k)) { //nolint:gosec
+			return true
+		}
+	}
+
+	return false
+}
+
+// memAccess returns the number of bytes node reads or writes through I.
+func memAccess(node *Node) (n int, isWrite, ok bool) {
+	if node.Err != nil {
+		return 0, false, false
+	}
+
+	const bcdLen = 3
+
+	x := operandX(node)
+
+	switch node.Instr.Op { //nolint:exhaustive
+	case chipper.DrawSpriteInXY:
+		return node.Instr.Operands[2], false, true
+	case chipper.Fill0ToXWithValueInAddrI:
+		return x + 1, false, true
+	case chipper.Store0ToXInI:
+		return x + 1, true, true
+	case chipper.StoreBCDOfXInI:
+		return bcdLen, true, true
+	default:
+		return 0, false, false
+	}
+}
+
+// indexOrigins computes, for every reachable instruction, the set of origins I
+// may have right before it executes. Unlike IndexValues it follows I through
+// returns, so that every use of a target is accounted for.
+func (cfg *CFG) indexOrigins() map[uint16]originSet {
+	in := map[uint16]originSet{cfg.Base: {}}
+	exits := make(map[uint16]originSet)
+	clobbers := cfg.clobbersIndex()
+
+	subsOf := make(map[uint16][]uint16)
+	for _, sub := range cfg.Subs {
+		for _, addr := range sub.Addrs {
+			subsOf[addr] = append(subsOf[addr], sub.Entry)
+		}
+	}
+
+	addrs := cfg.Addrs()
+
+	for changed := true; changed; {
+		changed = false
+
+		propagate := func(addr uint16, state originSet) {
+			merged, grew := in[addr].union(state)
+			if _, seen := in[addr]; !seen || grew {
+				in[addr] = merged
+				changed = true
+			}
+		}
+
+		for _, addr := range addrs {
+			state, ok := in[addr]
+			if !ok {
+				continue
+			}
+
+			node := cfg.Nodes[addr]
+			out := originTransfer(node, state)
+
+			if node.Err == nil && node.Instr.Op == chipper.ReturnFromSub {
+				for _, entry := range subsOf[addr] {
+					if merged, grew := exits[entry].union(out); grew {
+						exits[entry] = merged
+						changed = true
+					}
+				}
+			}
+
+			if callee, isCall := cfg.Calls[addr]; isCall {
+				switch {
+				case !cfg.InROM(callee):
+					out, _ = out.union(originSet{{addr: anyAddr}: true})
+				case clobbers[callee]:
+					propagate(callee, out)
+					out, _ = out.union(exits[callee])
+				default:
+					propagate(callee, out)
+				}
+			}
+
+			for _, succ := range node.Succs {
+				propagate(succ, out)
+			}
+		}
+	}
+
+	return in
+}
+
+func originTransfer(node *Node, in originSet) originSet {
+	if node.Err != nil {
+		return in
+	}
+
+	switch node.Instr.Op { //nolint:exhaustive
+	case chipper.StoreMemAddrNNNInRegI:
+		return originSet{{addr: node.Target()}: true}
+	case chipper.SetIToMemAddrOfSpriteInX:
+		return originSet{}
+	case chipper.AddXToI:
+		out := make(originSet, len(in))
+		for origin := range in {
+			origin.offset = origin.addr != anyAddr
+			out[origin] = true
+		}
+
+		return out
+	default:
+		return in
+	}
+}
@@ -0,0 +1,286 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// Severity ranks how likely a Finding is to be a real problem.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// ParseSeverity returns the Severity named by s.
+func ParseSeverity(s string) (Severity, error) {
+	for _, sev := range []Severity{SeverityInfo, SeverityWarning, SeverityError} {
+		if sev.String() == s {
+			return sev, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown severity '%s', want one of: info, warning, error", s)
+}
+
+// Finding is a single problem reported by Lint.
+type Finding struct {
+	Addr     uint16
+	Severity Severity
+	Check    string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%#04x %-7s %-16s %s", f.Addr, f.Severity, f.Check, f.Message)
+}
+
+// LintOptions describes the machine the ROM is checked against.
+type LintOptions struct {
+	Base      uint16
+	RAMSize   int
+	StackSize int
+
+	// FontAddress and FontSize locate the font ROMs should not write over.
+	FontAddress uint16
+	FontSize    int
+}
+
+// DefaultLintOptions matches the emulator defaults used by cmd/emu.
+func DefaultLintOptions() LintOptions {
+	return LintOptionsFor(chipper.DefaultConfig())
+}
+
+// LintOptionsFor returns the options checking ROMs against the machine cfg
+// describes.
+func LintOptionsFor(cfg chipper.Config) LintOptions {
+	font := cfg.Font
+	if font == nil {
+		font = chipper.DefaultFont()
+	}
+
+	return LintOptions{
+		Base:        cfg.StartAddress,
+		RAMSize:     cfg.RAMSize,
+		StackSize:   cfg.StackSize,
+		FontAddress: cfg.FontAddress,
+		FontSize:    font.Size(),
+	}
+}
+
+type linter struct {
+	cfg      *CFG
+	opts     LintOptions
+	index    map[uint16]IndexState
+	findings []Finding
+}
+
+// Lint statically checks rom for likely problems. Only reachable code is
+// inspected.
+func Lint(rom []byte, opts LintOptions) []Finding {
+	cfg := BuildCFG(rom, opts.Base)
+	l := &linter{cfg: cfg, opts: opts, index: cfg.IndexValues()}
+
+	for _, addr := range cfg.Addrs() {
+		node := cfg.Nodes[addr]
+		if node.Err != nil {
+			l.report(addr, SeverityError, "unknown-opcode", "reachable instruction %#04x cannot be decoded", node.Raw)
+
+			continue
+		}
+
+		l.checkTargets(node)
+		l.checkMemory(node)
+		l.checkAmbiguous(node)
+	}
+
+	l.checkCallDepth()
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		return l.findings[i].Addr < l.findings[j].Addr
+	})
+
+	return l.findings
+}
+
+func (l *linter) report(addr uint16, sev Severity, check, format string, args ...any) {
+	l.findings = append(l.findings, Finding{
+		Addr:     addr,
+		Severity: sev,
+		Check:    check,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) checkTargets(node *Node) {
+	target := node.Target()
+
+	switch node.Instr.Op { //nolint:exhaustive
+	case chipper.JumpNNN, chipper.CallSub:
+		if int(target)+chipper.InstructionSize > l.opts.RAMSize {
+			l.report(node.Addr, SeverityError, "jump-out-of-ram", "%s to %#03x is outside of RAM (%d bytes)",
+				node.Instr.Op, target, l.opts.RAMSize)
+
+			return
+		}
+
+		if _, ok := l.cfg.External[node.Addr]; ok {
+			l.report(node.Addr, SeverityWarning, "jump-out-of-rom", "%s to %#03x is outside of the ROM image",
+				node.Instr.Op, target)
+		}
+
+	case chipper.JumpToAddrNNNPlusV0:
+		const maxV0 = 0xFF
+
+		if int(target)+maxV0+chipper.InstructionSize > l.opts.RAMSize {
+			l.report(node.Addr, SeverityWarning, "jump-out-of-ram", "%s %#03x may land outside of RAM for large V0",
+				node.Instr.Op, target)
+		}
+	}
+}
+
+// checkMemory looks at the instructions accessing memory through I, when I is
+// statically known.
+func (l *linter) checkMemory(node *Node) {
+	state := l.index[node.Addr]
+	if !state.Known {
+		return
+	}
+
+	start := int(state.Addr)
+	x := node.Instr.Operands[0]
+
+	switch node.Instr.Op { //nolint:exhaustive
+	case chipper.Store0ToXInI:
+		l.checkRange(node, start, x+1, true)
+	case chipper.StoreBCDOfXInI:
+		const bcdLen = 3
+		l.checkRange(node, start, bcdLen, true)
+	case chipper.Fill0ToXWithValueInAddrI:
+		l.checkRange(node, start, x+1, false)
+	case chipper.DrawSpriteInXY:
+		l.checkRange(node, start, node.Instr.Operands[2], false)
+	}
+}
+
+func (l *linter) checkRange(node *Node, start, n int, isWrite bool) {
+	end := start + n
+
+	if end > l.opts.RAMSize {
+		l.report(node.Addr, SeverityError, "memory-overrun", "%s accesses %#03x-%#03x, past the end of RAM (%d bytes)",
+			node.Instr.Op, start, end-1, l.opts.RAMSize)
+
+		return
+	}
+
+	if !isWrite {
+		return
+	}
+
+	fontStart := int(l.opts.FontAddress)
+	fontEnd := fontStart + l.opts.FontSize
+	base := int(l.opts.Base)
+
+	switch {
+	case start < fontEnd && end > fontStart:
+		l.report(node.Addr, SeverityError, "font-write", "%s overwrites the font at %#03x-%#03x",
+			node.Instr.Op, max(start, fontStart), min(end, fontEnd)-1)
+	case start < base:
+		l.report(node.Addr, SeverityWarning, "interpreter-write", "%s writes to the interpreter area at %#03x",
+			node.Instr.Op, start)
+	}
+
+	for addr := start; addr < end; addr++ {
+		if addr >= base && l.cfg.IsCode(uint16(addr)) { //nolint:gosec
+			l.report(node.Addr, SeverityWarning, "self-modifying", "%s writes over code at %#03x",
+				node.Instr.Op, addr)
+
+			return
+		}
+	}
+}
+
+// checkAmbiguous flags instructions whose behaviour differs between CHIP-8
+// dialects.
+func (l *linter) checkAmbiguous(node *Node) {
+	var msg string
+
+	switch node.Instr.Op { //nolint:exhaustive
+	case chipper.StoreYShiftedRightInX, chipper.StoreYShiftedLeftInX:
+		msg = "shifts VY on the COSMAC VIP but VX on CHIP-48/SCHIP"
+	case chipper.Store0ToXInI, chipper.Fill0ToXWithValueInAddrI:
+		msg = "increments I by X+1 on the COSMAC VIP, by X on CHIP-48 and leaves it unchanged on SCHIP"
+	case chipper.JumpToAddrNNNPlusV0:
+		msg = "adds V0 on the COSMAC VIP but VX (BXNN) on CHIP-48/SCHIP"
+	case chipper.SetXToXORY, chipper.SetXToXANDY, chipper.SetXToXXORY:
+		msg = "resets VF on the COSMAC VIP but not on CHIP-48/SCHIP"
+	default:
+		return
+	}
+
+	l.report(node.Addr, SeverityInfo, "ambiguous-opcode", "%s %s", node.Instr.Op, msg)
+}
+
+// checkCallDepth reports recursion and call chains deeper than the stack.
+func (l *linter) checkCallDepth() {
+	depths := make(map[uint16]int)
+	onStack := make(map[uint16]bool)
+
+	var depth func(sub *Sub) int
+
+	depth = func(sub *Sub) int {
+		if d, ok := depths[sub.Entry]; ok {
+			return d
+		}
+
+		onStack[sub.Entry] = true
+		maxDepth := 0
+
+		for _, addr := range sub.Addrs {
+			callee, ok := l.cfg.Calls[addr]
+			if !ok {
+				continue
+			}
+
+			if onStack[callee] {
+				l.report(addr, SeverityWarning, "recursion", "recursive call to %#03x, call depth is unbounded", callee)
+
+				continue
+			}
+
+			d := 1
+			if target := l.cfg.Sub(callee); target != nil {
+				d += depth(target)
+			}
+
+			maxDepth = max(maxDepth, d)
+		}
+
+		onStack[sub.Entry] = false
+		depths[sub.Entry] = maxDepth
+
+		return maxDepth
+	}
+
+	main := l.cfg.Subs[0]
+	if d := depth(main); d > l.opts.StackSize {
+		l.report(main.Entry, SeverityError, "stack-overflow", "call chain is %d deep, the stack holds %d", d, l.opts.StackSize)
+	}
+}
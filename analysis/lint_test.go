@@ -0,0 +1,120 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/aalbacetef/chipper"
+)
+
+func TestLint(t *testing.T) {
+	cases := []struct {
+		label string
+		rom   []byte
+		check string
+		sev   Severity
+		addr  uint16
+	}{
+		{
+			"unknown opcode",
+			[]byte{0x60, 0x01, 0x80, 0x08},
+			"unknown-opcode", SeverityError, 0x202,
+		},
+		{
+			"font write",
+			[]byte{0xA0, 0x10, 0xF0, 0x33, 0x12, 0x04},
+			"font-write", SeverityError, 0x202,
+		},
+		{
+			"memory overrun",
+			[]byte{0xAF, 0xFE, 0xF5, 0x55, 0x12, 0x04},
+			"memory-overrun", SeverityError, 0x202,
+		},
+		{
+			"self-modifying code",
+			[]byte{0xA2, 0x00, 0xF0, 0x55, 0x12, 0x04},
+			"self-modifying", SeverityWarning, 0x202,
+		},
+		{
+			"jump outside of RAM",
+			[]byte{0x1F, 0xFF},
+			"jump-out-of-ram", SeverityError, 0x200,
+		},
+		{
+			"stack overflow",
+			[]byte{
+				0x22, 0x04, // 200: call 204
+				0x12, 0x02, // 202: jump 202
+				0x22, 0x08, // 204: call 208
+				0x00, 0xEE, // 206: return
+				0x00, 0xEE, // 208: return
+			},
+			"stack-overflow", SeverityError, 0x200,
+		},
+		{
+			"recursion",
+			[]byte{
+				0x22, 0x04, // 200: call 204
+				0x12, 0x02, // 202: jump 202
+				0x22, 0x04, // 204: call 204
+				0x00, 0xEE, // 206: return
+			},
+			"recursion", SeverityWarning, 0x204,
+		},
+		{
+			"ambiguous opcode",
+			[]byte{0x80, 0x16, 0x12, 0x02},
+			"ambiguous-opcode", SeverityInfo, 0x200,
+		},
+	}
+
+	opts := DefaultLintOptions()
+	opts.StackSize = 1
+
+	for _, c := range cases {
+		t.Run(c.label, func(t *testing.T) {
+			findings := Lint(c.rom, opts)
+
+			for _, f := range findings {
+				if f.Check == c.check && f.Addr == c.addr && f.Severity == c.sev {
+					return
+				}
+			}
+
+			t.Fatalf("want %s %s at %#04x, got %v", c.sev, c.check, c.addr, findings)
+		})
+	}
+
+	t.Run("clean rom", func(t *testing.T) {
+		rom := []byte{0xA2, 0x06, 0xD0, 0x01, 0x12, 0x04, 0x80}
+
+		if findings := Lint(rom, DefaultLintOptions()); len(findings) != 0 {
+			t.Fatalf("expected no findings, got %v", findings)
+		}
+	})
+
+	t.Run("font of the platform", func(t *testing.T) {
+		// BCD to 0x0A0, past the default font but inside the SCHIP one.
+		rom := []byte{0xA0, 0xA0, 0xF0, 0x33, 0x12, 0x04}
+
+		schip, _ := chipper.PresetByName("schip")
+
+		for _, c := range []struct {
+			opts  LintOptions
+			check string
+		}{
+			{DefaultLintOptions(), "interpreter-write"},
+			{LintOptionsFor(schip), "font-write"},
+		} {
+			findings := Lint(rom, c.opts)
+			if len(findings) != 1 || findings[0].Check != c.check {
+				t.Errorf("font at %#03x: want a single %s, got %v", c.opts.FontAddress, c.check, findings)
+			}
+		}
+	})
+
+	t.Run("default options", func(t *testing.T) {
+		if opts := DefaultLintOptions(); opts.Base != chipper.StartAddress {
+			t.Fatalf("want base %#0x, got %#0x", chipper.StartAddress, opts.Base)
+		}
+	})
+}
@@ -0,0 +1,385 @@
+package analysis
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/aalbacetef/chipper"
+)
+
+const (
+	maxOptimizePasses = 8
+	maxMemAccess      = 16 // the most bytes a single instruction reads through I.
+)
+
+// OptimizeStats counts what Optimize changed.
+type OptimizeStats struct {
+	OrigSize         int
+	Size             int
+	DeadBytes        int // unreachable bytes no StoreMemAddrNNNInRegI refers to.
+	RedundantLoads   int // StoreNNInX of a value the register already holds.
+	JumpChains       int // jumps retargeted past another jump, or to the next instruction.
+	DuplicateSprites int // sprites replaced by an identical one earlier in the ROM.
+}
+
+func (s OptimizeStats) String() string {
+	return fmt.Sprintf(
+		"%d -> %d bytes (dead bytes: %d, redundant loads: %d, jump chains: %d, duplicate sprites: %d)",
+		s.OrigSize, s.Size, s.DeadBytes, s.RedundantLoads, s.JumpChains, s.DuplicateSprites,
+	)
+}
+
+// Optimize rewrites rom, loaded at base, into an equivalent and usually
+// smaller ROM, relocating every address operand of the remaining code.
+//
+// The analysis is conservative: a ROM that may write over its own code is
+// returned unchanged, and one using JumpToAddrNNNPlusV0, jumping outside of its
+// image or accessing memory through an I of unknown origin only gets its jump
+// chains shortened, as nothing can safely be removed from it. Data reached
+// through pointers stored in data is not tracked, so the result should still be
+// checked with Verify.
+func Optimize(rom []byte, base uint16) ([]byte, OptimizeStats) {
+	stats := OptimizeStats{OrigSize: len(rom)}
+	out := append([]byte(nil), rom...)
+
+	for pass := 0; pass < maxOptimizePasses; pass++ {
+		var changed bool
+
+		out, changed = optimizePass(out, base, &stats)
+		if !changed {
+			break
+		}
+	}
+
+	stats.Size = len(out)
+
+	return out, stats
+}
+
+type optimizer struct {
+	cfg   *CFG
+	rom   []byte
+	uses  map[uint16]*DataUse
+	del   map[uint16]bool // bytes to remove.
+	keep  map[uint16]bool // bytes that must stay where they are.
+	stats *OptimizeStats
+}
+
+func optimizePass(rom []byte, base uint16, stats *OptimizeStats) ([]byte, bool) {
+	rom = append([]byte(nil), rom...)
+
+	cfg := BuildCFG(rom, base)
+	if _, _, codeWrite := cfg.dataUses(); codeWrite {
+		return rom, false
+	}
+
+	threaded := threadJumps(cfg, rom)
+	stats.JumpChains += threaded
+
+	cfg = BuildCFG(rom, base)
+	uses, anyUse, _ := cfg.dataUses()
+
+	if anyUse || len(cfg.Indirect) > 0 || len(cfg.External) > 0 {
+		return rom, threaded > 0
+	}
+
+	o := &optimizer{
+		cfg:   cfg,
+		rom:   rom,
+		uses:  uses,
+		del:   make(map[uint16]bool),
+		keep:  make(map[uint16]bool),
+		stats: stats,
+	}
+
+	o.protect()
+	o.removeRedundantLoads()
+	o.mergeSprites()
+	o.removeDeadBytes()
+
+	if len(o.del) == 0 {
+		return rom, threaded > 0
+	}
+
+	return o.relocate(), true
+}
+
+// threadJumps points jumps and calls landing on a jump at its final target,
+// returning how many it changed.
+func threadJumps(cfg *CFG, rom []byte) int {
+	count := 0
+
+	for _, addr := range cfg.Addrs() {
+		node := cfg.Nodes[addr]
+		if node.Err != nil || (node.Instr.Op != chipper.JumpNNN && node.Instr.Op != chipper.CallSub) {
+			continue
+		}
+
+		target := node.Target()
+		final := target
+		seen := make(map[uint16]bool)
+
+		for {
+			next, ok := cfg.Nodes[final]
+			if !ok || seen[final] || next.Err != nil || next.Instr.Op != chipper.JumpNNN {
+				break
+			}
+
+			seen[final] = true
+			final = next.Target()
+		}
+
+		if final == target || seen[final] {
+			continue
+		}
+
+		setTarget(rom, cfg.Base, addr, final)
+
+		count++
+	}
+
+	return count
+}
+
+// protect marks the bytes that must not be removed: the instruction following
+// a skip, since the skip would land elsewhere, and any code read as data.
+func (o *optimizer) protect() {
+	for addr, node := range o.cfg.Nodes {
+		if node.Err == nil && IsSkip(node.Instr.Op) {
+			o.keepRange(addr+chipper.InstructionSize, chipper.InstructionSize)
+		}
+	}
+
+	for target, use := range o.uses {
+		n := use.Extent
+		if use.Indexed {
+			n = maxMemAccess
+		}
+
+		o.keepRange(target, n)
+	}
+}
+
+func (o *optimizer) keepRange(start uint16, n int) {
+	for k := 0; k < n; k++ {
+		o.keep[start+uint16(k)] = true //nolint:gosec
+	}
+}
+
+func (o *optimizer) removable(start uint16, n int) bool {
+	for k := 0; k < n; k++ {
+		addr := start + uint16(k) //nolint:gosec
+		if o.keep[addr] || o.del[addr] || !o.cfg.InImage(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (o *optimizer) remove(start uint16, n int) {
+	for k := 0; k < n; k++ {
+		o.del[start+uint16(k)] = true //nolint:gosec
+	}
+}
+
+// removeRedundantLoads drops register loads of values already held, as well as
+// jumps to the next instruction.
+func (o *optimizer) removeRedundantLoads() {
+	regs := o.cfg.RegisterValues()
+
+	for _, addr := range o.cfg.Addrs() {
+		node := o.cfg.Nodes[addr]
+		if node.Err != nil || !o.removable(addr, chipper.InstructionSize) {
+			continue
+		}
+
+		switch node.Instr.Op { //nolint:exhaustive
+		case chipper.StoreNNInX:
+			state, x := regs[addr], operandX(node)
+			if state.Known[x] && state.Val[x] == byte(node.Raw) {
+				o.remove(addr, chipper.InstructionSize)
+				o.stats.RedundantLoads++
+			}
+
+		case chipper.JumpNNN:
+			if node.Target() == addr+chipper.InstructionSize {
+				o.remove(addr, chipper.InstructionSize)
+				o.stats.JumpChains++
+			}
+		}
+	}
+}
+
+// mergeSprites points the StoreMemAddrNNNInRegI instructions of a sprite only
+// ever drawn at a known height to an identical earlier sprite, and removes it.
+func (o *optimizer) mergeSprites() {
+	var kept []uint16
+
+	for _, target := range o.spriteTargets() {
+		n := o.uses[target].Extent
+		data := o.data(target, n)
+
+		leader, found := uint16(0), false
+
+		for _, prev := range kept {
+			if o.uses[prev].Extent >= n && bytes.Equal(o.data(prev, n), data) {
+				leader, found = prev, true
+
+				break
+			}
+		}
+
+		if !found || !o.exclusive(target, n) {
+			kept = append(kept, target)
+
+			continue
+		}
+
+		for addr, node := range o.cfg.Nodes {
+			if node.Err == nil && node.Instr.Op == chipper.StoreMemAddrNNNInRegI && node.Target() == target {
+				setTarget(o.rom, o.cfg.Base, addr, leader)
+			}
+		}
+
+		o.remove(target, n)
+		o.stats.DuplicateSprites++
+	}
+}
+
+// spriteTargets returns, in order, the targets only used as sprites of a
+// known height lying entirely outside of the code.
+func (o *optimizer) spriteTargets() []uint16 {
+	var targets []uint16
+
+	for addr := o.cfg.Base; o.cfg.InImage(addr); addr++ {
+		use, ok := o.uses[addr]
+		if !ok || use.Indexed || use.Extent == 0 || !o.cfg.InImage(addr+uint16(use.Extent)-1) { //nolint:gosec
+			continue
+		}
+
+		if !o.cfg.overlapsCode(addr, use.Extent) {
+			targets = append(targets, addr)
+		}
+	}
+
+	return targets
+}
+
+// exclusive reports whether [start, start+n) is only accessed through start.
+func (o *optimizer) exclusive(start uint16, n int) bool {
+	end := int(start) + n
+
+	for target, use := range o.uses {
+		if target == start {
+			continue
+		}
+
+		reach := int(target) + use.Extent
+		if use.Indexed {
+			reach = o.nextCode(target)
+		}
+
+		if int(target) < end && reach > int(start) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// nextCode returns the address of the first code byte at or after addr, or
+// the end of the image.
+func (o *optimizer) nextCode(addr uint16) int {
+	for ; o.cfg.InImage(addr); addr++ {
+		if o.cfg.IsCode(addr) {
+			break
+		}
+	}
+
+	return int(addr)
+}
+
+func (o *optimizer) data(start uint16, n int) []byte {
+	off := int(start - o.cfg.Base)
+	if off+n > len(o.rom) {
+		return nil
+	}
+
+	return o.rom[off : off+n]
+}
+
+// removeDeadBytes removes the bytes that are neither code nor part of the data
+// following a StoreMemAddrNNNInRegI target.
+func (o *optimizer) removeDeadBytes() {
+	referenced := false
+
+	for addr := o.cfg.Base; o.cfg.InImage(addr); addr++ {
+		if o.cfg.IsCode(addr) {
+			referenced = false
+
+			continue
+		}
+
+		if _, ok := o.uses[addr]; ok {
+			referenced = true
+		}
+
+		if referenced || !o.removable(addr, 1) {
+			continue
+		}
+
+		o.remove(addr, 1)
+		o.stats.DeadBytes++
+	}
+}
+
+// relocate returns the ROM without the removed bytes, with the address
+// operand of every remaining instruction adjusted.
+func (o *optimizer) relocate() []byte {
+	base := o.cfg.Base
+	shift := make([]uint16, len(o.rom)+1) // removed bytes before each offset.
+	out := make([]byte, 0, len(o.rom))
+
+	for off, b := range o.rom {
+		shift[off+1] = shift[off]
+
+		if o.del[base+uint16(off)] { //nolint:gosec
+			shift[off+1]++
+
+			continue
+		}
+
+		out = append(out, b)
+	}
+
+	moved := func(addr uint16) uint16 {
+		if addr < base || int(addr-base) > len(o.rom) {
+			return addr
+		}
+
+		return addr - shift[addr-base]
+	}
+
+	for addr, node := range o.cfg.Nodes {
+		if node.Err != nil || o.del[addr] {
+			continue
+		}
+
+		switch node.Instr.Op { //nolint:exhaustive
+		case chipper.JumpNNN, chipper.CallSub, chipper.StoreMemAddrNNNInRegI, chipper.JumpToAddrNNNPlusV0:
+			off := int(addr - base)
+			target := uint16(o.rom[off]&0x0F)<<8 | uint16(o.rom[off+1]) //nolint:mnd
+			setTarget(out, base, moved(addr), moved(target))
+		}
+	}
+
+	return out
+}
+
+// setTarget replaces the NNN operand of the instruction at addr.
+func setTarget(rom []byte, base, addr, target uint16) {
+	off := int(addr - base)
+	rom[off] = rom[off]&0xF0 | byte(target>>8&0x0F) //nolint:mnd
+	rom[off+1] = byte(target)
+}
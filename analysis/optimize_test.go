@@ -0,0 +1,114 @@
+package analysis
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aalbacetef/chipper"
+)
+
+//nolint:gochecknoglobals
+var optimizeProgram = []byte{
+	0x60, 0x00, // 200: v0 := 0
+	0x61, 0x00, // 202: v1 := 0
+	0x12, 0x08, // 204: jump 208
+	0xFF, 0xFF, // 206: unreachable
+	0x12, 0x0A, // 208: jump 20A
+	0x60, 0x00, // 20A: v0 := 0, already 0
+	0xA2, 0x16, // 20C: i := 216
+	0xD0, 0x15, // 20E: sprite v0 v1 5
+	0xA2, 0x1B, // 210: i := 21B
+	0xD0, 0x15, // 212: sprite v0 v1 5
+	0x12, 0x14, // 214: jump 214
+	0xF0, 0x90, 0x90, 0x90, 0xF0, // 216: sprite
+	0xF0, 0x90, 0x90, 0x90, 0xF0, // 21B: same sprite
+}
+
+func TestOptimize(t *testing.T) {
+	got, stats := Optimize(optimizeProgram, chipper.StartAddress)
+
+	want := []byte{
+		0x60, 0x00, // 200: v0 := 0
+		0x61, 0x00, // 202: v1 := 0
+		0xA2, 0x0E, // 204: i := 20E
+		0xD0, 0x15, // 206: sprite v0 v1 5
+		0xA2, 0x0E, // 208: i := 20E
+		0xD0, 0x15, // 20A: sprite v0 v1 5
+		0x12, 0x0C, // 20C: jump 20C
+		0xF0, 0x90, 0x90, 0x90, 0xF0, // 20E: sprite
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Fatalf("\nwant: % x\n got: % x", want, got)
+	}
+
+	wantStats := OptimizeStats{
+		OrigSize:         len(optimizeProgram),
+		Size:             len(want),
+		DeadBytes:        4,
+		RedundantLoads:   1,
+		JumpChains:       2,
+		DuplicateSprites: 1,
+	}
+
+	if stats != wantStats {
+		t.Fatalf("want %+v, got %+v", wantStats, stats)
+	}
+
+	if err := Verify(optimizeProgram, got, DefaultVerifyOptions()); err != nil {
+		t.Fatalf("optimized program does not verify: %v", err)
+	}
+}
+
+func TestOptimizeUnsafe(t *testing.T) {
+	rom := []byte{
+		0xA2, 0x00, // 200: i := 200
+		0xF0, 0x55, // 202: save v0, over the code
+		0x12, 0x04, // 204: jump 204
+		0xFF, 0xFF, // 206: unreachable
+	}
+
+	got, stats := Optimize(rom, chipper.StartAddress)
+	if !bytes.Equal(got, rom) || stats.Size != len(rom) {
+		t.Fatalf("self-modifying ROM should be left untouched, got % x", got)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	other := append([]byte(nil), optimizeProgram...)
+	other[len(other)-1] = 0xF8
+
+	if err := Verify(optimizeProgram, other, DefaultVerifyOptions()); err == nil {
+		t.Fatal("expected ROMs drawing different sprites to differ")
+	}
+}
+
+func TestOptimizeROMs(t *testing.T) {
+	roms, err := filepath.Glob("../testdata/*.ch8")
+	if err != nil {
+		t.Fatalf("could not list roms: %v", err)
+	}
+
+	opts := DefaultVerifyOptions()
+	opts.Draws = 500
+
+	for _, name := range roms {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			rom, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatalf("could not read rom: %v", err)
+			}
+
+			got, stats := Optimize(rom, chipper.StartAddress)
+			if stats.Size > stats.OrigSize {
+				t.Fatalf("optimized ROM grew: %v", stats)
+			}
+
+			if err := Verify(rom, got, opts); err != nil {
+				t.Fatalf("optimized ROM does not verify (%v): %v", stats, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,507 @@
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// printer renders structured statements in either Octo or C-like syntax.
+type printer struct {
+	w      io.Writer
+	syntax Syntax
+	cfg    *CFG
+	err    error
+
+	// defined holds the labels emitted by the subroutine being printed.
+	defined map[uint16]bool
+}
+
+func (p *printer) printf(depth int, format string, args ...any) {
+	if p.err != nil {
+		return
+	}
+
+	_, p.err = fmt.Fprintf(p.w, strings.Repeat("\t", depth)+format+"\n", args...)
+}
+
+func (p *printer) header() {
+	comment := "#"
+	if p.syntax == SyntaxC {
+		comment = "//"
+	}
+
+	p.printf(0, "%s decompiled by chipper (base 0x%03x, %d bytes)", comment, p.cfg.Base, len(p.cfg.ROM))
+	p.printf(0, "")
+}
+
+func (p *printer) sub(sub *Sub, body []stmt) {
+	p.defined = make(map[uint16]bool)
+	p.collectLabels(body)
+
+	if p.syntax == SyntaxC {
+		p.printf(0, "void %s(void) {", sub.Name)
+		p.stmts(1, body)
+		p.printf(0, "}")
+		p.printf(0, "")
+
+		return
+	}
+
+	p.printf(0, ": %s", sub.Name)
+	p.stmts(1, body)
+	p.printf(0, "")
+}
+
+func (p *printer) collectLabels(body []stmt) {
+	for _, s := range body {
+		if s.kind == stmtLabel {
+			p.defined[s.addr] = true
+		}
+
+		p.collectLabels(s.then)
+		p.collectLabels(s.orElse)
+	}
+}
+
+func (p *printer) stmts(depth int, body []stmt) {
+	for _, s := range body {
+		p.stmt(depth, s)
+	}
+}
+
+func (p *printer) stmt(depth int, s stmt) { //nolint:cyclop
+	switch s.kind {
+	case stmtLabel:
+		if p.syntax == SyntaxC {
+			p.printf(depth-1, "%s:", labelName(s.addr))
+
+			return
+		}
+
+		p.printf(0, ": %s", labelName(s.addr))
+
+	case stmtInstr, stmtGoto, stmtBreak, stmtContinue:
+		p.printf(depth, "%s", p.single(s))
+
+	case stmtIf:
+		p.ifStmt(depth, s)
+
+	case stmtLoop:
+		if p.syntax == SyntaxC {
+			p.printf(depth, "for (;;) {")
+			p.stmts(depth+1, s.then)
+			p.printf(depth, "}")
+
+			return
+		}
+
+		p.printf(depth, "loop")
+		p.stmts(depth+1, s.then)
+		p.printf(depth, "again")
+	}
+}
+
+func (p *printer) ifStmt(depth int, s stmt) {
+	c := p.cond(s.cond)
+	isSingle := len(s.then) == 1 && len(s.orElse) == 0 && s.then[0].kind != stmtIf && s.then[0].kind != stmtLoop
+
+	if p.syntax == SyntaxC {
+		if isSingle {
+			p.printf(depth, "if (%s) %s", c, p.single(s.then[0]))
+
+			return
+		}
+
+		p.printf(depth, "if (%s) {", c)
+		p.stmts(depth+1, s.then)
+
+		if len(s.orElse) > 0 {
+			p.printf(depth, "} else {")
+			p.stmts(depth+1, s.orElse)
+		}
+
+		p.printf(depth, "}")
+
+		return
+	}
+
+	if p.isWhile(s) {
+		p.printf(depth, "while %s", p.cond(s.cond.not()))
+
+		return
+	}
+
+	if isSingle {
+		p.printf(depth, "if %s then %s", c, p.single(s.then[0]))
+
+		return
+	}
+
+	p.printf(depth, "if %s begin", c)
+	p.stmts(depth+1, s.then)
+
+	if len(s.orElse) > 0 {
+		p.printf(depth, "else")
+		p.stmts(depth+1, s.orElse)
+	}
+
+	p.printf(depth, "end")
+}
+
+func (p *printer) single(s stmt) string {
+	switch s.kind { //nolint:exhaustive
+	case stmtGoto:
+		if p.syntax == SyntaxC {
+			return fmt.Sprintf("goto %s;", p.label(s.addr))
+		}
+
+		return "jump " + p.label(s.addr)
+
+	case stmtBreak:
+		if p.syntax == SyntaxC {
+			return "break;"
+		}
+
+		return "jump " + p.label(s.addr)
+
+	case stmtContinue:
+		if p.syntax == SyntaxC {
+			return "continue;"
+		}
+
+		return "jump " + p.label(s.addr)
+
+	default:
+		if p.syntax == SyntaxC {
+			return p.instrC(s.node)
+		}
+
+		return p.instrOcto(s.node)
+	}
+}
+
+// isWhile reports whether s is a conditional break, which Octo spells as
+// "while" with the opposite condition.
+func (p *printer) isWhile(s stmt) bool {
+	return p.syntax == SyntaxOcto && len(s.then) == 1 && len(s.orElse) == 0 && s.then[0].kind == stmtBreak
+}
+
+// label names a jump target, falling back to its address if the target was
+// not emitted as a label.
+func (p *printer) label(addr uint16) string {
+	if p.defined[addr] {
+		return labelName(addr)
+	}
+
+	if p.syntax == SyntaxC {
+		return fmt.Sprintf("*0x%03x", addr)
+	}
+
+	return fmt.Sprintf("0x%03x", addr)
+}
+
+// cond renders the condition under which a skip instruction skips.
+func (p *printer) cond(c cond) string {
+	node := c.node
+	args := node.Instr.Operands
+	vx := reg(args[0])
+	vy := reg(args[1])
+	nn := byteArg(node)
+
+	eq, ne := "==", "!="
+	if c.negate {
+		eq, ne = ne, eq
+	}
+
+	pressed, notPressed := "key", "-key"
+	if p.syntax == SyntaxC {
+		pressed, notPressed = "pressed", "not pressed"
+	}
+
+	if c.negate {
+		pressed, notPressed = notPressed, pressed
+	}
+
+	keyFmt := "%s %s"
+	if p.syntax == SyntaxC {
+		keyFmt = "key(%s) %s"
+	}
+
+	switch node.Instr.Op { //nolint:exhaustive
+	case chipper.SkipIfXEqNN:
+		return fmt.Sprintf("%s %s 0x%02x", vx, eq, nn)
+	case chipper.SkipIfXNotEqNN:
+		return fmt.Sprintf("%s %s 0x%02x", vx, ne, nn)
+	case chipper.SkipIfXEqY:
+		return fmt.Sprintf("%s %s %s", vx, eq, vy)
+	case chipper.SkipIfXNotEqY:
+		return fmt.Sprintf("%s %s %s", vx, ne, vy)
+	case chipper.SkipIfKeyInXIsPressed:
+		return fmt.Sprintf(keyFmt, vx, pressed)
+	case chipper.SkipIfKeyInXNotPressed:
+		return fmt.Sprintf(keyFmt, vx, notPressed)
+	default:
+		return fmt.Sprintf("<%s>", node.Instr.Op)
+	}
+}
+
+func (p *printer) callee(node *Node) string {
+	if sub := p.cfg.Sub(node.Target()); sub != nil {
+		return sub.Name
+	}
+
+	return fmt.Sprintf("0x%03x", node.Target())
+}
+
+func (p *printer) addrRef(node *Node) string {
+	target := node.Target()
+	if p.cfg.InImage(target) && !p.cfg.IsCode(target) {
+		return dataName(target)
+	}
+
+	return fmt.Sprintf("0x%03x", target)
+}
+
+func (p *printer) instrOcto(node *Node) string { //nolint:cyclop,funlen
+	if node.Err != nil {
+		return fmt.Sprintf("0x%02x 0x%02x # %v", node.Raw>>8, node.Raw&0xFF, node.Err) //nolint:mnd
+	}
+
+	args := node.Instr.Operands
+	vx, vy := reg(args[0]), reg(args[1])
+	nn := byteArg(node)
+
+	switch node.Instr.Op { //nolint:exhaustive
+	case chipper.Clear:
+		return "clear"
+	case chipper.ReturnFromSub:
+		return "return"
+	case chipper.JumpNNN:
+		return fmt.Sprintf("jump 0x%03x", node.Target())
+	case chipper.CallSub:
+		if p.cfg.Sub(node.Target()) == nil {
+			return fmt.Sprintf(":call 0x%03x", node.Target())
+		}
+
+		return p.callee(node)
+	case chipper.StoreNNInX:
+		return fmt.Sprintf("%s := 0x%02x", vx, nn)
+	case chipper.AddNNToX:
+		return fmt.Sprintf("%s += 0x%02x", vx, nn)
+	case chipper.StoreYinX:
+		return fmt.Sprintf("%s := %s", vx, vy)
+	case chipper.SetXToXORY:
+		return fmt.Sprintf("%s |= %s", vx, vy)
+	case chipper.SetXToXANDY:
+		return fmt.Sprintf("%s &= %s", vx, vy)
+	case chipper.SetXToXXORY:
+		return fmt.Sprintf("%s ^= %s", vx, vy)
+	case chipper.AddYToX:
+		return fmt.Sprintf("%s += %s", vx, vy)
+	case chipper.SubYFromX:
+		return fmt.Sprintf("%s -= %s", vx, vy)
+	case chipper.StoreYShiftedRightInX:
+		return fmt.Sprintf("%s >>= %s", vx, vy)
+	case chipper.SetXToYMinusX:
+		return fmt.Sprintf("%s =- %s", vx, vy)
+	case chipper.StoreYShiftedLeftInX:
+		return fmt.Sprintf("%s <<= %s", vx, vy)
+	case chipper.StoreMemAddrNNNInRegI:
+		return "i := " + p.addrRef(node)
+	case chipper.JumpToAddrNNNPlusV0:
+		return fmt.Sprintf("jump0 0x%03x", node.Target())
+	case chipper.SetXToRandomNumWithMaskNN:
+		return fmt.Sprintf("%s := random 0x%02x", vx, nn)
+	case chipper.DrawSpriteInXY:
+		return fmt.Sprintf("sprite %s %s %d", vx, vy, args[2])
+	case chipper.StoreValDTInX:
+		return vx + " := delay"
+	case chipper.WaitForKeyAndStoreInX:
+		return vx + " := key"
+	case chipper.SetDTToX:
+		return "delay := " + vx
+	case chipper.SetSTToX:
+		return "buzzer := " + vx
+	case chipper.AddXToI:
+		return "i += " + vx
+	case chipper.SetIToMemAddrOfSpriteInX:
+		return "i := hex " + vx
+	case chipper.StoreBCDOfXInI:
+		return "bcd " + vx
+	case chipper.Store0ToXInI:
+		return "save " + vx
+	case chipper.Fill0ToXWithValueInAddrI:
+		return "load " + vx
+	default:
+		return fmt.Sprintf("0x%02x 0x%02x # %s", node.Raw>>8, node.Raw&0xFF, node.Instr.Op) //nolint:mnd
+	}
+}
+
+func (p *printer) instrC(node *Node) string { //nolint:cyclop,funlen
+	if node.Err != nil {
+		return fmt.Sprintf("raw(0x%04x); // %v", node.Raw, node.Err)
+	}
+
+	args := node.Instr.Operands
+	vx, vy := reg(args[0]), reg(args[1])
+	nn := byteArg(node)
+
+	switch node.Instr.Op { //nolint:exhaustive
+	case chipper.Clear:
+		return "clear();"
+	case chipper.ReturnFromSub:
+		return "return;"
+	case chipper.JumpNNN:
+		return fmt.Sprintf("goto *0x%03x;", node.Target())
+	case chipper.CallSub:
+		return p.callee(node) + "();"
+	case chipper.ExecNNN:
+		return fmt.Sprintf("machine_code(0x%03x);", node.Target())
+	case chipper.Nop:
+		return "nop();"
+	case chipper.StoreNNInX:
+		return fmt.Sprintf("%s = 0x%02x;", vx, nn)
+	case chipper.AddNNToX:
+		return fmt.Sprintf("%s = %s + 0x%02x;", vx, vx, nn)
+	case chipper.StoreYinX:
+		return fmt.Sprintf("%s = %s;", vx, vy)
+	case chipper.SetXToXORY:
+		return fmt.Sprintf("%s = %s | %s;", vx, vx, vy)
+	case chipper.SetXToXANDY:
+		return fmt.Sprintf("%s = %s & %s;", vx, vx, vy)
+	case chipper.SetXToXXORY:
+		return fmt.Sprintf("%s = %s ^ %s;", vx, vx, vy)
+	case chipper.AddYToX:
+		return fmt.Sprintf("%s = %s + %s; // vf = carry", vx, vx, vy)
+	case chipper.SubYFromX:
+		return fmt.Sprintf("%s = %s - %s; // vf = !borrow", vx, vx, vy)
+	case chipper.StoreYShiftedRightInX:
+		return fmt.Sprintf("%s = %s >> 1; // vf = lsb", vx, vy)
+	case chipper.SetXToYMinusX:
+		return fmt.Sprintf("%s = %s - %s; // vf = !borrow", vx, vy, vx)
+	case chipper.StoreYShiftedLeftInX:
+		return fmt.Sprintf("%s = %s << 1; // vf = msb", vx, vy)
+	case chipper.StoreMemAddrNNNInRegI:
+		return fmt.Sprintf("i = %s;", p.addrRef(node))
+	case chipper.JumpToAddrNNNPlusV0:
+		return fmt.Sprintf("goto *(0x%03x + v0);", node.Target())
+	case chipper.SetXToRandomNumWithMaskNN:
+		return fmt.Sprintf("%s = rand() & 0x%02x;", vx, nn)
+	case chipper.DrawSpriteInXY:
+		return fmt.Sprintf("vf = draw(%s, %s, %d);", vx, vy, args[2])
+	case chipper.StoreValDTInX:
+		return vx + " = delay;"
+	case chipper.WaitForKeyAndStoreInX:
+		return vx + " = wait_key();"
+	case chipper.SetDTToX:
+		return fmt.Sprintf("delay = %s;", vx)
+	case chipper.SetSTToX:
+		return fmt.Sprintf("sound = %s;", vx)
+	case chipper.AddXToI:
+		return fmt.Sprintf("i = i + %s;", vx)
+	case chipper.SetIToMemAddrOfSpriteInX:
+		return fmt.Sprintf("i = font(%s);", vx)
+	case chipper.StoreBCDOfXInI:
+		return fmt.Sprintf("bcd(%s);", vx)
+	case chipper.Store0ToXInI:
+		return fmt.Sprintf("save(v0..%s);", vx)
+	case chipper.Fill0ToXWithValueInAddrI:
+		return fmt.Sprintf("load(v0..%s);", vx)
+	default:
+		return fmt.Sprintf("raw(0x%04x); // %s", node.Raw, node.Instr.Op)
+	}
+}
+
+// data prints every byte of the ROM that is not reachable code, split at the
+// addresses loaded into I.
+func (p *printer) data() {
+	refs := make(map[uint16]bool)
+
+	for _, node := range p.cfg.Nodes {
+		if node.Err == nil && node.Instr.Op == chipper.StoreMemAddrNNNInRegI {
+			refs[node.Target()] = true
+		}
+	}
+
+	for _, chunk := range dataChunks(p.cfg, refs) {
+		p.chunk(chunk.addr, chunk.data)
+	}
+}
+
+func (p *printer) chunk(addr uint16, data []byte) {
+	const perLine = 8
+
+	vals := make([]string, len(data))
+	for k, b := range data {
+		vals[k] = fmt.Sprintf("0x%02x", b)
+	}
+
+	if p.syntax == SyntaxC {
+		p.printf(0, "byte %s[%d] = {", dataName(addr), len(data))
+
+		for k := 0; k < len(vals); k += perLine {
+			p.printf(1, "%s,", strings.Join(vals[k:min(k+perLine, len(vals))], ", "))
+		}
+
+		p.printf(0, "};")
+
+		return
+	}
+
+	p.printf(0, ": %s", dataName(addr))
+
+	for k := 0; k < len(vals); k += perLine {
+		p.printf(1, "%s", strings.Join(vals[k:min(k+perLine, len(vals))], " "))
+	}
+}
+
+type dataChunk struct {
+	addr uint16
+	data []byte
+}
+
+func dataChunks(cfg *CFG, refs map[uint16]bool) []dataChunk {
+	var chunks []dataChunk
+
+	cur := -1
+
+	for k, b := range cfg.ROM {
+		addr := cfg.Base + uint16(k) //nolint:gosec
+		if cfg.IsCode(addr) {
+			cur = -1
+
+			continue
+		}
+
+		if cur < 0 || refs[addr] {
+			chunks = append(chunks, dataChunk{addr: addr})
+			cur = len(chunks) - 1
+		}
+
+		chunks[cur].data = append(chunks[cur].data, b)
+	}
+
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].addr < chunks[j].addr })
+
+	return chunks
+}
+
+func reg(x int) string {
+	return fmt.Sprintf("v%x", x)
+}
+
+func byteArg(node *Node) byte {
+	return byte(node.Raw & 0xFF) //nolint:mnd
+}
+
+func labelName(addr uint16) string {
+	return fmt.Sprintf("L_%03x", addr)
+}
+
+func dataName(addr uint16) string {
+	return fmt.Sprintf("data_%03x", addr)
+}
@@ -0,0 +1,182 @@
+package analysis
+
+import "github.com/aalbacetef/chipper"
+
+const allRegs = 0xFFFF
+
+// RegState is what is statically known about V0-VF.
+type RegState struct {
+	Known [chipper.RegisterCount]bool
+	Val   [chipper.RegisterCount]byte
+}
+
+func (s RegState) merge(o RegState) RegState {
+	for r := range s.Known {
+		s.Known[r] = s.Known[r] && o.Known[r] && s.Val[r] == o.Val[r]
+	}
+
+	return s
+}
+
+func (s *RegState) set(r int, v byte) {
+	s.Known[r] = true
+	s.Val[r] = v
+}
+
+func (s *RegState) clobber(mask uint16) {
+	for r := range s.Known {
+		if mask&(1<<r) != 0 {
+			s.Known[r] = false
+		}
+	}
+}
+
+// RegisterValues computes, for every reachable instruction, which registers
+// hold a statically known value right before it executes. Nothing is assumed
+// about the registers at the entry point or on entry to a subroutine.
+func (cfg *CFG) RegisterValues() map[uint16]RegState {
+	written := cfg.writtenRegs()
+	in := map[uint16]RegState{cfg.Base: {}}
+	work := []uint16{cfg.Base}
+
+	for _, sub := range cfg.Subs[1:] {
+		in[sub.Entry] = RegState{}
+		work = append(work, sub.Entry)
+	}
+
+	propagate := func(addr uint16, state RegState) {
+		prev, seen := in[addr]
+		if seen {
+			state = prev.merge(state)
+			if state == prev {
+				return
+			}
+		}
+
+		in[addr] = state
+		work = append(work, addr)
+	}
+
+	for len(work) > 0 {
+		addr := work[len(work)-1]
+		work = work[:len(work)-1]
+
+		node, ok := cfg.Nodes[addr]
+		if !ok {
+			continue
+		}
+
+		out := regTransfer(node, in[addr])
+
+		if callee, isCall := cfg.Calls[addr]; isCall {
+			mask, known := written[callee]
+			if !known {
+				mask = allRegs
+			}
+
+			out.clobber(mask)
+		}
+
+		for _, succ := range node.Succs {
+			propagate(succ, out)
+		}
+	}
+
+	return in
+}
+
+func regTransfer(node *Node, in RegState) RegState {
+	if node.Err != nil {
+		return in
+	}
+
+	out := in
+	x, y := operandX(node), operandY(node)
+	nn := byte(node.Raw)
+
+	out.clobber(regsWritten(node))
+
+	switch node.Instr.Op { //nolint:exhaustive
+	case chipper.StoreNNInX:
+		out.set(x, nn)
+	case chipper.AddNNToX:
+		if in.Known[x] {
+			out.set(x, in.Val[x]+nn)
+		}
+	case chipper.StoreYinX:
+		if in.Known[y] {
+			out.set(x, in.Val[y])
+		}
+	}
+
+	return out
+}
+
+// regsWritten returns the mask of the registers node may write.
+func regsWritten(node *Node) uint16 {
+	if node.Err != nil {
+		return 0
+	}
+
+	const vf = 1 << 0xF
+
+	x := operandX(node)
+	vx := uint16(1) << x
+
+	switch node.Instr.Op { //nolint:exhaustive
+	case chipper.StoreNNInX, chipper.AddNNToX, chipper.StoreYinX,
+		chipper.SetXToRandomNumWithMaskNN, chipper.StoreValDTInX, chipper.WaitForKeyAndStoreInX:
+		return vx
+	case chipper.SetXToXORY, chipper.SetXToXANDY, chipper.SetXToXXORY,
+		chipper.AddYToX, chipper.SubYFromX, chipper.SetXToYMinusX,
+		chipper.StoreYShiftedRightInX, chipper.StoreYShiftedLeftInX:
+		return vx | vf
+	case chipper.DrawSpriteInXY:
+		return vf
+	case chipper.Fill0ToXWithValueInAddrI:
+		return vx<<1 - 1
+	default:
+		return 0
+	}
+}
+
+// writtenRegs returns, for each subroutine entry, the registers calling it may
+// write, including through nested calls.
+func (cfg *CFG) writtenRegs() map[uint16]uint16 {
+	written := make(map[uint16]uint16)
+
+	for changed := true; changed; {
+		changed = false
+
+		for _, sub := range cfg.Subs {
+			mask := written[sub.Entry]
+
+			for _, addr := range sub.Addrs {
+				mask |= regsWritten(cfg.Nodes[addr])
+
+				if callee, isCall := cfg.Calls[addr]; isCall {
+					if calleeMask, ok := written[callee]; ok {
+						mask |= calleeMask
+					} else if cfg.Sub(callee) == nil {
+						mask = allRegs
+					}
+				}
+			}
+
+			if prev, ok := written[sub.Entry]; !ok || prev != mask {
+				written[sub.Entry] = mask
+				changed = true
+			}
+		}
+	}
+
+	return written
+}
+
+func operandX(node *Node) int {
+	return int(node.Raw >> 8 & 0xF) //nolint:mnd
+}
+
+func operandY(node *Node) int {
+	return int(node.Raw >> 4 & 0xF) //nolint:mnd
+}
@@ -0,0 +1,135 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// Sprite is a block of sprite data drawn by DrawSpriteInXY: Height rows of
+// 8 pixels starting at Addr.
+type Sprite struct {
+	Addr    uint16
+	Height  int
+	Data    []byte
+	Static  bool // found by following I to a DrawSpriteInXY.
+	Dynamic bool // seen while running the ROM.
+}
+
+type spriteKey struct {
+	addr   uint16
+	height int
+}
+
+// StaticSprites returns the sprites drawn by a DrawSpriteInXY whose I is
+// statically known, i.e. set by a StoreMemAddrNNNInRegI on every path.
+func StaticSprites(cfg *CFG) []Sprite {
+	index := cfg.IndexValues()
+	found := make(map[spriteKey]Sprite)
+
+	for addr, node := range cfg.Nodes {
+		if node.Err != nil || node.Instr.Op != chipper.DrawSpriteInXY {
+			continue
+		}
+
+		state := index[addr]
+		height := node.Instr.Operands[2]
+
+		if !state.Known || height == 0 || !cfg.InImage(state.Addr) {
+			continue
+		}
+
+		start := int(state.Addr - cfg.Base)
+		end := min(start+height, len(cfg.ROM))
+
+		found[spriteKey{state.Addr, height}] = Sprite{
+			Addr:   state.Addr,
+			Height: height,
+			Data:   append([]byte(nil), cfg.ROM[start:end]...),
+			Static: true,
+		}
+	}
+
+	return sortedSprites(found)
+}
+
+// SpriteRecorder collects the sprites drawn while a ROM runs. Register its
+// Trace method with Emulator.AddTraceFunc.
+type SpriteRecorder struct {
+	found map[spriteKey]Sprite
+}
+
+func NewSpriteRecorder() *SpriteRecorder {
+	return &SpriteRecorder{found: make(map[spriteKey]Sprite)}
+}
+
+// Trace records the value of I and the height of every sprite drawn.
+func (r *SpriteRecorder) Trace(emu *chipper.Emulator, instr chipper.Instruction) {
+	if instr.Op != chipper.DrawSpriteInXY {
+		return
+	}
+
+	height := instr.Operands[2]
+	key := spriteKey{emu.Index, height}
+
+	if _, ok := r.found[key]; ok || height == 0 {
+		return
+	}
+
+	start := int(emu.Index)
+	if start >= len(emu.RAM) {
+		return
+	}
+
+	end := min(start+height, len(emu.RAM))
+
+	r.found[key] = Sprite{
+		Addr:    emu.Index,
+		Height:  height,
+		Data:    append([]byte(nil), emu.RAM[start:end]...),
+		Dynamic: true,
+	}
+}
+
+// Sprites returns the sprites recorded so far, sorted by address.
+func (r *SpriteRecorder) Sprites() []Sprite {
+	return sortedSprites(r.found)
+}
+
+// MergeSprites combines lists of sprites, joining the ones with the same
+// address and height.
+func MergeSprites(lists ...[]Sprite) []Sprite {
+	merged := make(map[spriteKey]Sprite)
+
+	for _, list := range lists {
+		for _, s := range list {
+			key := spriteKey{s.Addr, s.Height}
+
+			if prev, ok := merged[key]; ok {
+				s.Static = s.Static || prev.Static
+				s.Dynamic = s.Dynamic || prev.Dynamic
+			}
+
+			merged[key] = s
+		}
+	}
+
+	return sortedSprites(merged)
+}
+
+func sortedSprites(m map[spriteKey]Sprite) []Sprite {
+	sprites := make([]Sprite, 0, len(m))
+	for _, s := range m {
+		sprites = append(sprites, s)
+	}
+
+	sort.Slice(sprites, func(i, j int) bool {
+		if sprites[i].Addr != sprites[j].Addr {
+			return sprites[i].Addr < sprites[j].Addr
+		}
+
+		return sprites[i].Height < sprites[j].Height
+	})
+
+	return sprites
+}
@@ -0,0 +1,71 @@
+package analysis
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aalbacetef/chipper"
+)
+
+func TestSprites(t *testing.T) {
+	want := Sprite{Addr: 0x218, Height: 1, Data: []byte{0x80}}
+
+	t.Run("static", func(t *testing.T) {
+		sprites := StaticSprites(BuildCFG(testProgram, chipper.StartAddress))
+		if len(sprites) != 1 {
+			t.Fatalf("want 1 sprite, got %d", len(sprites))
+		}
+
+		checkSprite(t, sprites[0], want)
+	})
+
+	t.Run("dynamic", func(t *testing.T) {
+		display, err := chipper.NewDebugDisplay(64, 32)
+		if err != nil {
+			t.Fatalf("could not create display: %v", err)
+		}
+
+		emu, err := chipper.NewEmulator(16, 4096, display, &chipper.StubKeyInputSource{})
+		if err != nil {
+			t.Fatalf("could not create emulator: %v", err)
+		}
+
+		if err := emu.Load(bytes.NewReader(testProgram)); err != nil {
+			t.Fatalf("could not load: %v", err)
+		}
+
+		rec := NewSpriteRecorder()
+		emu.AddTraceFunc(rec.Trace)
+
+		const steps = 40
+		for k := 0; k < steps; k++ {
+			if err := emu.Step(); err != nil {
+				t.Fatalf("step %d: %v", k, err)
+			}
+		}
+
+		sprites := rec.Sprites()
+		if len(sprites) != 1 {
+			t.Fatalf("want 1 sprite, got %d", len(sprites))
+		}
+
+		checkSprite(t, sprites[0], want)
+
+		merged := MergeSprites(StaticSprites(BuildCFG(testProgram, chipper.StartAddress)), sprites)
+		if len(merged) != 1 || !merged[0].Static || !merged[0].Dynamic {
+			t.Fatalf("expected a single static and dynamic sprite, got %+v", merged)
+		}
+	})
+}
+
+func checkSprite(t *testing.T, got, want Sprite) {
+	t.Helper()
+
+	if got.Addr != want.Addr || got.Height != want.Height {
+		t.Fatalf("want sprite at %#0x (height %d), got %#0x (height %d)", want.Addr, want.Height, got.Addr, got.Height)
+	}
+
+	if !bytes.Equal(got.Data, want.Data) {
+		t.Fatalf("want data %v, got %v", want.Data, got.Data)
+	}
+}
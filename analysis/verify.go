@@ -0,0 +1,209 @@
+package analysis
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// VerifyOptions controls how Verify runs the two ROMs.
+type VerifyOptions struct {
+	Draws                int // display updates to compare.
+	InstructionsPerFrame int // instructions between two timer ticks.
+	Seed                 int64
+	Quirks               chipper.Quirks
+}
+
+// DefaultVerifyOptions compares 2000 display updates at 10 instructions per
+// frame.
+func DefaultVerifyOptions() VerifyOptions {
+	const (
+		draws = 2000
+		ipf   = 10
+	)
+
+	return VerifyOptions{
+		Draws:                draws,
+		InstructionsPerFrame: ipf,
+		Seed:                 1,
+		Quirks:               chipper.DefaultQuirks(),
+	}
+}
+
+// Verify runs want and got side by side and compares their displays after
+// every update, i.e. every Clear or DrawSpriteInXY. Both get the same random
+// numbers and the same key presses, which change between updates rather than
+// on a clock, since an optimized ROM runs fewer instructions between them.
+// It returns an error describing the first update on which the displays
+// differ, or on which only one of the ROMs fails or stops drawing.
+func Verify(want, got []byte, opts VerifyOptions) error {
+	a, err := newVerifyMachine(want, opts)
+	if err != nil {
+		return fmt.Errorf("could not start original ROM: %w", err)
+	}
+
+	b, err := newVerifyMachine(got, opts)
+	if err != nil {
+		return fmt.Errorf("could not start optimized ROM: %w", err)
+	}
+
+	for draw := 0; draw < opts.Draws; draw++ {
+		a.keys.advance()
+		b.keys.advance()
+
+		drewA, errA := a.nextDraw(opts.InstructionsPerFrame)
+		drewB, errB := b.nextDraw(opts.InstructionsPerFrame)
+
+		if !sameDisplay(a.emu.Display, b.emu.Display) {
+			return fmt.Errorf("update %d: displays differ\noriginal:\n%s\noptimized:\n%s",
+				draw, a.emu.Display, b.emu.Display)
+		}
+
+		switch {
+		case errA != nil && errB != nil:
+			return nil
+		case errA != nil:
+			return fmt.Errorf("update %d: only the original ROM failed: %w", draw, errA)
+		case errB != nil:
+			return fmt.Errorf("update %d: only the optimized ROM failed: %w", draw, errB)
+		case drewA != drewB:
+			return fmt.Errorf("update %d: only one of the ROMs stopped drawing", draw)
+		case !drewA:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+type verifyMachine struct {
+	emu    *chipper.Emulator
+	keys   *randomKeys
+	steps  int
+	drawAt int // address of the display instruction being executed, or -1.
+}
+
+func newVerifyMachine(rom []byte, opts VerifyOptions) (*verifyMachine, error) {
+	const (
+		stackSize = 16
+		ramSize   = 4096
+		w         = 64
+		h         = 32
+	)
+
+	display, err := chipper.NewDebugDisplay(w, h)
+	if err != nil {
+		return nil, fmt.Errorf("could not create display: %w", err)
+	}
+
+	keys := &randomKeys{rng: rand.New(rand.NewSource(opts.Seed))} //nolint:gosec
+
+	emu, err := chipper.NewEmulator(stackSize, ramSize, display, keys)
+	if err != nil {
+		return nil, fmt.Errorf("could not create emulator: %w", err)
+	}
+
+	emu.Quirks = opts.Quirks
+	emu.SetRand(rand.New(rand.NewSource(opts.Seed + 1))) //nolint:gosec
+
+	if err := emu.Load(bytes.NewReader(rom)); err != nil {
+		return nil, fmt.Errorf("could not load ROM: %w", err)
+	}
+
+	m := &verifyMachine{emu: emu, keys: keys, drawAt: -1}
+	emu.AddTraceFunc(m.trace)
+
+	return m, nil
+}
+
+func (m *verifyMachine) trace(emu *chipper.Emulator, instr chipper.Instruction) {
+	if instr.Op == chipper.DrawSpriteInXY || instr.Op == chipper.Clear {
+		m.drawAt = int(emu.PC) - chipper.InstructionSize
+	}
+}
+
+// nextDraw runs until a display instruction completes, ticking the timers
+// every ipf instructions. A DrawSpriteInXY waiting for the vertical blank does
+// not count. It gives up, returning false, after ten idle seconds.
+func (m *verifyMachine) nextDraw(ipf int) (bool, error) {
+	const idleFrames = 600
+
+	for k := 0; k < ipf*idleFrames; k++ {
+		m.drawAt = -1
+
+		if err := m.emu.Step(); err != nil {
+			return false, err
+		}
+
+		m.steps++
+		if m.steps%ipf == 0 {
+			m.emu.TickTimers()
+		}
+
+		if m.drawAt >= 0 && int(m.emu.PC) != m.drawAt {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func sameDisplay(a, b chipper.Display) bool {
+	if a.Bounds() != b.Bounds() {
+		return false
+	}
+
+	same := true
+
+	_ = chipper.Each(a, func(x, y int) error {
+		if !chipper.ColorEq(a.At(x, y), b.At(x, y)) {
+			same = false
+		}
+
+		return nil
+	})
+
+	return same
+}
+
+// randomKeys is a KeyInputSource pressing and releasing keys at random, the
+// same way for every source created with the same seed.
+type randomKeys struct {
+	rng     *rand.Rand
+	pressed [chipper.NumKeys]bool
+}
+
+// advance toggles a random key, every few frames on average.
+func (k *randomKeys) advance() {
+	const oneIn = 8
+
+	if k.rng.Intn(oneIn) == 0 {
+		key := k.rng.Intn(chipper.NumKeys)
+		k.pressed[key] = !k.pressed[key]
+	}
+}
+
+func (k *randomKeys) Get(key int) bool {
+	if key < 0 || key >= chipper.NumKeys {
+		return false
+	}
+
+	return k.pressed[key]
+}
+
+func (k *randomKeys) Set(key int, v bool) {
+	if key < 0 || key >= chipper.NumKeys {
+		return
+	}
+
+	k.pressed[key] = v
+}
+
+func (k *randomKeys) WaitUntilKeypress() <-chan int {
+	ch := make(chan int, 1)
+	ch <- k.rng.Intn(chipper.NumKeys)
+
+	return ch
+}
@@ -0,0 +1,173 @@
+package chipper
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Disassemble returns raw in the syntax of its opcode's spec, e.g.
+// "ADD V1, V2" for 0x8124. Unknown instructions are returned as data words,
+// e.g. "DW 0xFFFF".
+func Disassemble(raw uint16) string {
+	spec, ok := SpecOf(decodeOpcode(raw))
+	if !ok {
+		return fmt.Sprintf("DW 0x%04X", raw)
+	}
+
+	mnemonic, _, _ := strings.Cut(spec.Syntax, " ")
+	tokens := syntaxOperands(spec.Syntax)
+	operand := 0
+
+	for k, tok := range tokens {
+		if !isOperand(tok) {
+			continue
+		}
+
+		f := spec.Operands[operand]
+		operand++
+
+		switch f.Name {
+		case "VX", "VY":
+			tokens[k] = fmt.Sprintf("V%X", f.Get(raw))
+		case "N":
+			tokens[k] = strconv.Itoa(f.Get(raw))
+		default:
+			tokens[k] = fmt.Sprintf("0x%0*X", f.Bits/4, f.Get(raw)) //nolint:mnd
+		}
+	}
+
+	if len(tokens) == 0 {
+		return mnemonic
+	}
+
+	return mnemonic + " " + strings.Join(tokens, ", ")
+}
+
+// Encode returns the instruction op with the operands args, in the order
+// of its spec's syntax.
+func Encode(op Opcode, args ...int) (uint16, error) {
+	spec, ok := SpecOf(op)
+	if !ok {
+		return 0, fmt.Errorf("unknown opcode '%s'", op)
+	}
+
+	if len(args) != len(spec.Operands) {
+		return 0, ArgCountError{want: len(spec.Operands), got: len(args)}
+	}
+
+	raw := spec.Value
+
+	for k, f := range spec.Operands {
+		if args[k] < 0 || args[k] >= 1<<f.Bits {
+			return 0, fmt.Errorf("%s: %s out of range: %#x", op, f.Name, args[k])
+		}
+
+		raw |= uint16(args[k]) << f.Shift
+	}
+
+	return raw, nil
+}
+
+// Assemble encodes a single instruction in Cowgod's syntax, such as
+// "LD V1, 0x2A" or "DRW V0, V1, 5". Numbers are decimal, or hex with a 0x or
+// # prefix.
+func Assemble(line string) (uint16, error) {
+	mnemonic, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+	operands := syntaxOperands(strings.TrimSpace(line))
+
+	for _, spec := range opcodeSpecs {
+		want, _, _ := strings.Cut(spec.Syntax, " ")
+		if !strings.EqualFold(mnemonic, want) {
+			continue
+		}
+
+		args, ok := matchSyntax(syntaxOperands(spec.Syntax), operands)
+		if ok {
+			return Encode(spec.Op, args...)
+		}
+	}
+
+	return 0, fmt.Errorf("unknown instruction '%s'", strings.TrimSpace(line))
+}
+
+// matchSyntax returns the operands in got if they fit the syntax tokens.
+func matchSyntax(tokens, got []string) ([]int, bool) {
+	if len(tokens) != len(got) {
+		return nil, false
+	}
+
+	args := make([]int, 0, len(tokens))
+
+	for k, tok := range tokens {
+		var (
+			n  int
+			ok bool
+		)
+
+		switch tok {
+		case "VX", "VY":
+			n, ok = parseRegister(got[k])
+		case "N", "NN", "NNN":
+			n, ok = parseNumber(got[k])
+		default:
+			if !strings.EqualFold(tok, got[k]) {
+				return nil, false
+			}
+
+			continue
+		}
+
+		if !ok {
+			return nil, false
+		}
+
+		args = append(args, n)
+	}
+
+	return args, true
+}
+
+func parseRegister(s string) (int, bool) {
+	if len(s) != 2 || (s[0] != 'V' && s[0] != 'v') { //nolint:mnd
+		return 0, false
+	}
+
+	n, err := strconv.ParseUint(s[1:], 16, 4)
+
+	return int(n), err == nil
+}
+
+func parseNumber(s string) (int, bool) {
+	if rest, ok := strings.CutPrefix(s, "#"); ok {
+		s = "0x" + rest
+	}
+
+	n, err := strconv.ParseUint(s, 0, 16)
+
+	return int(n), err == nil
+}
+
+// syntaxOperands returns the comma-separated operands after the mnemonic.
+func syntaxOperands(syntax string) []string {
+	_, rest, _ := strings.Cut(syntax, " ")
+	if strings.TrimSpace(rest) == "" {
+		return nil
+	}
+
+	ops := strings.Split(rest, ",")
+	for k := range ops {
+		ops[k] = strings.TrimSpace(ops[k])
+	}
+
+	return ops
+}
+
+func isOperand(tok string) bool {
+	switch tok {
+	case "VX", "VY", "N", "NN", "NNN":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,46 @@
+// Package audio generates the sound of the CHIP-8 buzzer, which beeps while
+// SoundTimer is above zero, as a stream of PCM samples.
+package audio
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	// DefaultSampleRate is the number of samples per second.
+	DefaultSampleRate = 44100
+
+	// DefaultFrequency is the pitch of the beep, in Hz.
+	DefaultFrequency = 440
+
+	// DefaultVolume is the amplitude of the beep, as a fraction of full scale.
+	DefaultVolume = 0.25
+
+	// FrameRate is the rate at which the emulator's timers tick.
+	FrameRate = 60
+)
+
+var ErrNoSamples = errors.New("no samples written")
+
+// Sink receives signed 16-bit mono PCM samples. Sinks may not keep samples
+// after Write returns.
+type Sink interface {
+	Write(samples []int16) error
+}
+
+// Buffer is a Sink keeping every sample in memory.
+type Buffer struct {
+	Samples []int16
+}
+
+func (b *Buffer) Write(samples []int16) error {
+	b.Samples = append(b.Samples, samples...)
+
+	return nil
+}
+
+// volume converts a fraction of full scale into an amplitude.
+func volume(v float64) int16 {
+	return int16(math.Round(min(max(v, 0), 1) * math.MaxInt16))
+}
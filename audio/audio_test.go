@@ -0,0 +1,168 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aalbacetef/chipper"
+)
+
+func TestBeeperTiming(t *testing.T) {
+	buf := &Buffer{}
+	b := NewBeeper(buf)
+	b.SampleRate = 1000
+	b.Frequency = 100
+
+	sizes := make(map[int]int)
+
+	for k := 0; k < 60; k++ {
+		before := len(buf.Samples)
+
+		if err := b.Generate(true); err != nil {
+			t.Fatalf("could not generate: %v", err)
+		}
+
+		sizes[len(buf.Samples)-before]++
+	}
+
+	// 1000/60 samples per frame, without drifting.
+	if len(buf.Samples) != 1000 || b.Len() != 1000 || b.Sounding() != 1000 {
+		t.Fatalf("want 1000 samples a second, got %d", len(buf.Samples))
+	}
+
+	if sizes[16]+sizes[17] != 60 {
+		t.Fatalf("want frames of 16 or 17 samples, got %v", sizes)
+	}
+
+	// a 100Hz square wave changes sign 200 times a second.
+	changes := 0
+
+	for k := 1; k < len(buf.Samples); k++ {
+		if (buf.Samples[k] > 0) != (buf.Samples[k-1] > 0) {
+			changes++
+		}
+	}
+
+	if changes != 199 {
+		t.Fatalf("want 199 sign changes, got %d", changes)
+	}
+
+	if buf.Samples[0] != volume(DefaultVolume) {
+		t.Fatalf("want amplitude %d, got %d", volume(DefaultVolume), buf.Samples[0])
+	}
+}
+
+func TestBeeperFollowsSoundTimer(t *testing.T) {
+	rom := []byte{
+		0x60, 0x05, // LD V0, 5
+		0xF0, 0x18, // LD ST, V0
+		0x12, 0x04, // JP 0x204
+	}
+
+	display, err := chipper.NewDebugDisplay(64, 32)
+	if err != nil {
+		t.Fatalf("could not create display: %v", err)
+	}
+
+	emu, err := chipper.NewEmulator(16, 4096, display, &chipper.StubKeyInputSource{})
+	if err != nil {
+		t.Fatalf("could not create emulator: %v", err)
+	}
+
+	if err := emu.Load(bytes.NewReader(rom)); err != nil {
+		t.Fatalf("could not load rom: %v", err)
+	}
+
+	buf := &Buffer{}
+	b := NewBeeper(buf)
+	b.SampleRate = 600
+	emu.AddFrameFunc(b.Frame)
+
+	for k := 0; k < 10; k++ {
+		if err := emu.RunFrame(10); err != nil {
+			t.Fatalf("could not run frame: %v", err)
+		}
+	}
+
+	if b.Err() != nil {
+		t.Fatalf("unexpected error: %v", b.Err())
+	}
+
+	// 10 samples per frame, the first 5 frames beep.
+	if b.Len() != 100 || b.Sounding() != 50 {
+		t.Fatalf("want 50 of 100 samples sounding, got %d of %d", b.Sounding(), b.Len())
+	}
+
+	for k, s := range buf.Samples {
+		if (s != 0) != (k < 50) {
+			t.Fatalf("sample %d: unexpected value %d", k, s)
+		}
+	}
+}
+
+func TestWAVWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+
+	fd, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("could not create file: %v", err)
+	}
+
+	w := NewWAVWriter(fd, 8000)
+
+	if err := w.Write([]int16{1, -1, 0x7FFF}); err != nil {
+		t.Fatalf("could not write: %v", err)
+	}
+
+	if err := w.Write([]int16{-0x8000}); err != nil {
+		t.Fatalf("could not write: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("could not close: %v", err)
+	}
+
+	if err := fd.Close(); err != nil {
+		t.Fatalf("could not close file: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read file: %v", err)
+	}
+
+	if len(data) != 44+8 {
+		t.Fatalf("want 52 bytes, got %d", len(data))
+	}
+
+	le := binary.LittleEndian
+	checks := []struct {
+		name string
+		got  uint32
+		want uint32
+	}{
+		{"riff size", le.Uint32(data[4:]), uint32(len(data) - 8)},
+		{"channels", uint32(le.Uint16(data[22:])), 1},
+		{"sample rate", le.Uint32(data[24:]), 8000},
+		{"bits per sample", uint32(le.Uint16(data[34:])), 16},
+		{"data size", le.Uint32(data[40:]), 8},
+	}
+
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Fatalf("%s: want %d, got %d", c.name, c.want, c.got)
+		}
+	}
+
+	if got := int16(le.Uint16(data[50:])); got != -0x8000 {
+		t.Fatalf("want the last sample to be -32768, got %d", got)
+	}
+
+	if err := NewWAVWriter(fd, 8000).Close(); !errors.Is(err, ErrNoSamples) {
+		t.Fatalf("want ErrNoSamples, got %v", err)
+	}
+}
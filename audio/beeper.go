@@ -0,0 +1,103 @@
+package audio
+
+import (
+	"fmt"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// Beeper is a square wave buzzer. Register its Frame method with
+// Emulator.AddFrameFunc: every emulated frame it writes a frame's worth of
+// samples to its sink, the tone if the buzzer was on, silence otherwise. The
+// number of samples follows emulated time exactly, carrying the remainder of
+// SampleRate/60 from frame to frame.
+type Beeper struct {
+	SampleRate int
+	Frequency  int
+	Volume     float64
+
+	sink      Sink
+	frames    int64 // frames generated.
+	generated int64 // samples generated.
+	sounded   int64 // samples generated with the tone on.
+	buf       []int16
+	err       error
+}
+
+func NewBeeper(sink Sink) *Beeper {
+	return &Beeper{
+		SampleRate: DefaultSampleRate,
+		Frequency:  DefaultFrequency,
+		Volume:     DefaultVolume,
+		sink:       sink,
+	}
+}
+
+// Frame writes the samples for the frame that just ended. Errors are reported
+// by Err.
+func (b *Beeper) Frame(emu *chipper.Emulator) {
+	if b.err != nil {
+		return
+	}
+
+	if err := b.Generate(emu.Sounding()); err != nil {
+		b.err = err
+	}
+}
+
+// Generate writes the samples of one frame, with the tone if on is set.
+func (b *Beeper) Generate(on bool) error {
+	rate := int64(b.SampleRate)
+	end := (b.frames + 1) * rate / FrameRate
+	n := int(end - b.frames*rate/FrameRate)
+
+	b.frames++
+
+	if cap(b.buf) < n {
+		b.buf = make([]int16, n)
+	}
+
+	buf := b.buf[:n]
+	amp := volume(b.Volume)
+	freq := int64(b.Frequency)
+
+	for k := range buf {
+		t := b.generated + int64(k)
+
+		switch {
+		case !on || freq <= 0:
+			buf[k] = 0
+		case t*2*freq/rate%2 == 0:
+			buf[k] = amp
+		default:
+			buf[k] = -amp
+		}
+	}
+
+	b.generated += int64(n)
+
+	if on {
+		b.sounded += int64(n)
+	}
+
+	if err := b.sink.Write(buf); err != nil {
+		return fmt.Errorf("could not write samples: %w", err)
+	}
+
+	return nil
+}
+
+// Err returns the first error met writing to the sink.
+func (b *Beeper) Err() error {
+	return b.err
+}
+
+// Len returns the number of samples generated.
+func (b *Beeper) Len() int64 {
+	return b.generated
+}
+
+// Sounding returns the number of samples generated with the tone on.
+func (b *Beeper) Sounding() int64 {
+	return b.sounded
+}
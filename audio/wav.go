@@ -0,0 +1,105 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// WAVWriter is a Sink writing 16-bit mono PCM WAV files. The sizes in the
+// header are only known at the end, so it needs to seek back and patch them:
+// call Close once done.
+type WAVWriter struct {
+	w          io.WriteSeeker
+	sampleRate int
+	samples    int64
+	headerDone bool
+	buf        []byte
+}
+
+const (
+	wavHeaderSize  = 44
+	bytesPerSample = 2
+)
+
+func NewWAVWriter(w io.WriteSeeker, sampleRate int) *WAVWriter {
+	return &WAVWriter{w: w, sampleRate: sampleRate}
+}
+
+func (w *WAVWriter) Write(samples []int16) error {
+	if !w.headerDone {
+		if _, err := w.w.Write(wavHeader(w.sampleRate, 0)); err != nil {
+			return fmt.Errorf("could not write header: %w", err)
+		}
+
+		w.headerDone = true
+	}
+
+	w.buf = w.buf[:0]
+	for _, s := range samples {
+		w.buf = binary.LittleEndian.AppendUint16(w.buf, uint16(s)) //nolint:gosec
+	}
+
+	if _, err := w.w.Write(w.buf); err != nil {
+		return fmt.Errorf("could not write samples: %w", err)
+	}
+
+	w.samples += int64(len(samples))
+
+	return nil
+}
+
+// Len returns the number of samples written.
+func (w *WAVWriter) Len() int64 {
+	return w.samples
+}
+
+// Close fills in the sizes in the header. It does not close the underlying
+// writer.
+func (w *WAVWriter) Close() error {
+	if !w.headerDone {
+		return ErrNoSamples
+	}
+
+	if _, err := w.w.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("could not seek to header: %w", err)
+	}
+
+	if _, err := w.w.Write(wavHeader(w.sampleRate, w.samples)); err != nil {
+		return fmt.Errorf("could not write header: %w", err)
+	}
+
+	if _, err := w.w.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("could not seek to end: %w", err)
+	}
+
+	return nil
+}
+
+func wavHeader(sampleRate int, samples int64) []byte {
+	const (
+		fmtSize = 16
+		pcm     = 1
+		mono    = 1
+	)
+
+	dataSize := uint32(samples * bytesPerSample) //nolint:gosec
+	rate := uint32(sampleRate)                   //nolint:gosec
+
+	h := make([]byte, 0, wavHeaderSize)
+	h = append(h, "RIFF"...)
+	h = binary.LittleEndian.AppendUint32(h, wavHeaderSize-8+dataSize)
+	h = append(h, "WAVE"...)
+	h = append(h, "fmt "...)
+	h = binary.LittleEndian.AppendUint32(h, fmtSize)
+	h = binary.LittleEndian.AppendUint16(h, pcm)
+	h = binary.LittleEndian.AppendUint16(h, mono)
+	h = binary.LittleEndian.AppendUint32(h, rate)
+	h = binary.LittleEndian.AppendUint32(h, rate*bytesPerSample)
+	h = binary.LittleEndian.AppendUint16(h, bytesPerSample)
+	h = binary.LittleEndian.AppendUint16(h, bytesPerSample*8) //nolint:mnd
+	h = append(h, "data"...)
+	h = binary.LittleEndian.AppendUint32(h, dataSize)
+
+	return h
+}
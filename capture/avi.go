@@ -0,0 +1,292 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// DefaultJPEGQuality keeps the blocky pixels of the display sharp.
+const DefaultJPEGQuality = 95
+
+// AVIWriter streams frames as a Motion JPEG AVI. Sizes in the headers are only
+// known at the end, so it needs to seek back and patch them: call Close once
+// done. Register its Frame method with Emulator.AddFrameFunc.
+type AVIWriter struct {
+	w       io.WriteSeeker
+	display chipper.Display
+	opts    Options
+	quality int
+	size    image.Point
+	seen    int
+	err     error
+
+	offset  int64    // bytes written.
+	patches aviPatch // where the headers hold sizes.
+	index   []aviIndexEntry
+	buf     bytes.Buffer
+}
+
+type aviPatch struct {
+	riffSize    int64
+	totalFrames int64
+	length      int64
+	moviSize    int64
+	moviStart   int64 // position of the 'movi' fourcc, which idx1 offsets are relative to.
+}
+
+type aviIndexEntry struct {
+	offset uint32
+	size   uint32
+}
+
+const (
+	aviHasIndex = 0x10
+	aviKeyFrame = 0x10
+)
+
+var errAVISize = errors.New("avi files are limited to 4GiB")
+
+// NewAVIWriter writes JPEG frames of the given quality, in [1, 100].
+func NewAVIWriter(w io.WriteSeeker, d chipper.Display, opts Options, quality int) *AVIWriter {
+	return &AVIWriter{w: w, display: d, opts: opts, quality: quality}
+}
+
+// Frame writes the display as the next frame of the video, unless skipped.
+// Errors are reported by Close.
+func (a *AVIWriter) Frame(_ *chipper.Emulator) {
+	img := Render(a.display, a.opts)
+
+	skip := a.opts.skip(a.seen)
+	a.seen++
+
+	if skip || a.err != nil {
+		return
+	}
+
+	a.err = a.writeFrame(img)
+}
+
+func (a *AVIWriter) writeFrame(img image.Image) error {
+	if len(a.index) == 0 {
+		a.size = img.Bounds().Size()
+
+		if err := a.writeHeader(); err != nil {
+			return fmt.Errorf("could not write header: %w", err)
+		}
+	}
+
+	if img.Bounds().Size() != a.size {
+		return fmt.Errorf("frame %d is %v, want %v", len(a.index), img.Bounds().Size(), a.size)
+	}
+
+	a.buf.Reset()
+
+	if err := jpeg.Encode(&a.buf, img, &jpeg.Options{Quality: a.quality}); err != nil {
+		return fmt.Errorf("could not encode frame %d: %w", len(a.index), err)
+	}
+
+	entry := aviIndexEntry{
+		offset: uint32(a.offset - a.patches.moviStart), //nolint:gosec
+		size:   uint32(a.buf.Len()),                    //nolint:gosec
+	}
+
+	if err := a.chunk("00dc", a.buf.Bytes()); err != nil {
+		return fmt.Errorf("could not write frame %d: %w", len(a.index), err)
+	}
+
+	a.index = append(a.index, entry)
+
+	return nil
+}
+
+func (a *AVIWriter) writeHeader() error {
+	const (
+		mainHeaderSize   = 56
+		streamHeaderSize = 56
+		bitmapHeaderSize = 40
+		strlSize         = 4 + 8 + streamHeaderSize + 8 + bitmapHeaderSize
+		hdrlSize         = 4 + 8 + mainHeaderSize + 8 + strlSize
+		microseconds     = 1_000_000
+	)
+
+	scale := uint32(a.opts.FrameSkip + 1)      //nolint:gosec
+	w, h := uint32(a.size.X), uint32(a.size.Y) //nolint:gosec
+
+	a.writeString("RIFF")
+	a.patches.riffSize = a.offset
+	a.writeU32(0)
+	a.writeString("AVI ")
+
+	a.writeString("LIST")
+	a.writeU32(hdrlSize)
+	a.writeString("hdrl")
+
+	a.writeString("avih")
+	a.writeU32(mainHeaderSize)
+	a.writeU32(microseconds * scale / FrameRate)
+	a.writeU32(0) // max bytes per second.
+	a.writeU32(0) // padding granularity.
+	a.writeU32(aviHasIndex)
+	a.patches.totalFrames = a.offset
+	a.writeU32(0)
+	a.writeU32(0) // initial frames.
+	a.writeU32(1) // streams.
+	a.writeU32(0) // suggested buffer size.
+	a.writeU32(w)
+	a.writeU32(h)
+	a.write(make([]byte, 16)) //nolint:mnd // reserved.
+
+	a.writeString("LIST")
+	a.writeU32(strlSize)
+	a.writeString("strl")
+
+	a.writeString("strh")
+	a.writeU32(streamHeaderSize)
+	a.writeString("vids")
+	a.writeString("MJPG")
+	a.writeU32(0) // flags.
+	a.writeU32(0) // priority and language.
+	a.writeU32(0) // initial frames.
+	a.writeU32(scale)
+	a.writeU32(FrameRate)
+	a.writeU32(0) // start.
+	a.patches.length = a.offset
+	a.writeU32(0)
+	a.writeU32(0)          // suggested buffer size.
+	a.writeU32(0xFFFFFFFF) // default quality.
+	a.writeU32(0)          // sample size.
+	a.writeU16(0)
+	a.writeU16(0)
+	a.writeU16(uint16(w)) //nolint:gosec
+	a.writeU16(uint16(h)) //nolint:gosec
+
+	a.writeString("strf")
+	a.writeU32(bitmapHeaderSize)
+	a.writeU32(bitmapHeaderSize)
+	a.writeU32(w)
+	a.writeU32(h)
+	a.writeU16(1)  // planes.
+	a.writeU16(24) //nolint:mnd // bits per pixel.
+	a.writeString("MJPG")
+	a.writeU32(w * h * 3)     //nolint:mnd
+	a.write(make([]byte, 16)) //nolint:mnd // resolution and color counts.
+
+	a.writeString("LIST")
+	a.patches.moviSize = a.offset
+	a.writeU32(0)
+	a.patches.moviStart = a.offset
+	a.writeString("movi")
+
+	return a.err
+}
+
+// chunk writes a RIFF chunk, padded to an even size.
+func (a *AVIWriter) chunk(id string, data []byte) error {
+	a.writeString(id)
+	a.writeU32(uint32(len(data))) //nolint:gosec
+	a.write(data)
+
+	if len(data)%2 == 1 {
+		a.write([]byte{0})
+	}
+
+	if a.err == nil && a.offset > int64(^uint32(0)) {
+		a.err = errAVISize
+	}
+
+	return a.err
+}
+
+func (a *AVIWriter) write(p []byte) {
+	if a.err != nil {
+		return
+	}
+
+	n, err := a.w.Write(p)
+	a.offset += int64(n)
+	a.err = err
+}
+
+func (a *AVIWriter) writeString(s string) {
+	a.write([]byte(s))
+}
+
+func (a *AVIWriter) writeU32(v uint32) {
+	a.write(binary.LittleEndian.AppendUint32(nil, v))
+}
+
+func (a *AVIWriter) writeU16(v uint16) {
+	a.write(binary.LittleEndian.AppendUint16(nil, v))
+}
+
+// patch overwrites the 32-bit value at pos.
+func (a *AVIWriter) patch(pos int64, v uint32) {
+	if a.err != nil {
+		return
+	}
+
+	if _, err := a.w.Seek(pos, io.SeekStart); err != nil {
+		a.err = err
+
+		return
+	}
+
+	_, a.err = a.w.Write(binary.LittleEndian.AppendUint32(nil, v))
+}
+
+// Len returns the number of frames written.
+func (a *AVIWriter) Len() int {
+	return len(a.index)
+}
+
+// Close writes the index and fills in the sizes in the headers, returning the
+// first error met while writing the video. It does not close the underlying
+// writer.
+func (a *AVIWriter) Close() error {
+	if a.err != nil {
+		return a.err
+	}
+
+	n := len(a.index)
+	if n == 0 {
+		return ErrNoFrames
+	}
+
+	moviEnd := a.offset
+
+	idx := make([]byte, 0, 16*n) //nolint:mnd
+	for _, e := range a.index {
+		idx = append(idx, "00dc"...)
+		idx = binary.LittleEndian.AppendUint32(idx, aviKeyFrame)
+		idx = binary.LittleEndian.AppendUint32(idx, e.offset)
+		idx = binary.LittleEndian.AppendUint32(idx, e.size)
+	}
+
+	if err := a.chunk("idx1", idx); err != nil {
+		return fmt.Errorf("could not write index: %w", err)
+	}
+
+	end := a.offset
+
+	a.patch(a.patches.riffSize, uint32(end-8))                       //nolint:gosec,mnd
+	a.patch(a.patches.totalFrames, uint32(n))                        //nolint:gosec
+	a.patch(a.patches.length, uint32(n))                             //nolint:gosec
+	a.patch(a.patches.moviSize, uint32(moviEnd-a.patches.moviStart)) //nolint:gosec
+
+	if a.err == nil {
+		_, a.err = a.w.Seek(end, io.SeekStart)
+	}
+
+	if a.err != nil {
+		return fmt.Errorf("could not finish the headers: %w", a.err)
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+// Package capture records the output of a chipper.Display as PNG screenshots,
+// animated GIFs and Y4M or AVI videos, optionally post-processed by filters.
+package capture
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// FrameRate is the rate at which the emulator's timers tick, and so the rate
+// at which frames are captured.
+const FrameRate = 60
+
+// Options controls how frames are rendered.
+type Options struct {
+	// Palette holds the colors from a clear to a set pixel, in that order. If
+	// nil, the display's own colors are used. Colors in between are only used
+	// for the shades produced by filters.
+	Palette color.Palette
+
+	// Scale is the size in pixels of a display pixel. Values below 1 are
+	// treated as 1.
+	Scale int
+
+	// FrameSkip is the number of frames dropped after each recorded frame.
+	FrameSkip int
+
+	// Filters post-process frames, in order, after they are scaled.
+	Filters []Filter
+}
+
+func DefaultOptions() Options {
+	return Options{Scale: 1}
+}
+
+// colors returns the colors from a clear to a set pixel.
+func (o Options) colors(d chipper.Display) color.Palette {
+	if len(o.Palette) >= 2 { //nolint:mnd
+		return o.Palette
+	}
+
+	return color.Palette{d.ColorClear(), d.ColorSet()}
+}
+
+// palette returns the colors of a clear and a set pixel.
+func (o Options) palette(d chipper.Display) color.Palette {
+	c := o.colors(d)
+
+	return color.Palette{c[0], c[len(c)-1]}
+}
+
+// skip reports whether the frame numbered seen, counting from 0, is dropped.
+func (o Options) skip(seen int) bool {
+	return seen%(o.FrameSkip+1) != 0
+}
+
+func (o Options) scale() int {
+	return max(o.Scale, 1)
+}
+
+// Snapshot renders the current contents of d into a paletted image.
+func Snapshot(d chipper.Display, opts Options) *image.Paletted {
+	b := d.Bounds()
+	s := opts.scale()
+	img := image.NewPaletted(image.Rect(0, 0, b.Dx()*s, b.Dy()*s), opts.palette(d))
+	set := d.ColorSet()
+
+	_ = chipper.Each(d, func(x, y int) error {
+		if !chipper.ColorEq(d.At(x, y), set) {
+			return nil
+		}
+
+		px, py := (x-b.Min.X)*s, (y-b.Min.Y)*s
+		for dy := 0; dy < s; dy++ {
+			for dx := 0; dx < s; dx++ {
+				img.SetColorIndex(px+dx, py+dy, chipper.ColorSet)
+			}
+		}
+
+		return nil
+	})
+
+	return img
+}
+
+// Render snapshots d and runs the frame through the filters of opts. Without
+// filters it is the same as Snapshot.
+func Render(d chipper.Display, opts Options) *image.Paletted {
+	if len(opts.Filters) == 0 {
+		return Snapshot(d, opts)
+	}
+
+	intensity := opts
+	intensity.Palette = color.Palette{color.Gray{Y: 0}, color.Gray{Y: 0xFF}}
+
+	var img image.Image = Snapshot(d, intensity)
+	for _, f := range opts.Filters {
+		img = f.Apply(img)
+	}
+
+	g := toGray(img)
+	out := image.NewPaletted(g.Rect, gradient(opts.colors(d)))
+	copy(out.Pix, g.Pix)
+
+	return out
+}
+
+// WritePNG writes a PNG screenshot of d to w.
+func WritePNG(w io.Writer, d chipper.Display, opts Options) error {
+	if err := png.Encode(w, Render(d, opts)); err != nil {
+		return fmt.Errorf("could not encode png: %w", err)
+	}
+
+	return nil
+}
+
+// Palettes are named palettes accepted by ParsePalette.
+var Palettes = map[string]string{ //nolint:gochecknoglobals
+	"green":   "0a140a,33ff66",
+	"amber":   "140c00,ffb000",
+	"paper":   "f4f0e6,222222",
+	"gameboy": "9bbc0f,8bac0f,306230,0f380f",
+}
+
+// ParsePalette parses the name of one of Palettes, or a comma separated list
+// of at least two hex colors from a clear to a set pixel, e.g. "000000,ffffff"
+// or "#1d2021,#ebdbb2".
+func ParsePalette(s string) (color.Palette, error) {
+	const least = 2
+
+	if named, ok := Palettes[strings.ToLower(s)]; ok {
+		s = named
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) < least {
+		return nil, fmt.Errorf("palette '%s': expected at least %d colors, got %d", s, least, len(parts))
+	}
+
+	p := make(color.Palette, 0, len(parts))
+
+	for _, part := range parts {
+		c, err := parseColor(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("palette '%s': %w", s, err)
+		}
+
+		p = append(p, c)
+	}
+
+	return p, nil
+}
+
+func parseColor(s string) (color.Color, error) {
+	const rgbLen = 3
+
+	raw, err := hex.DecodeString(strings.TrimPrefix(s, "#"))
+	if err != nil || len(raw) != rgbLen {
+		return nil, fmt.Errorf("invalid color '%s', want RRGGBB", s)
+	}
+
+	return color.RGBA{R: raw[0], G: raw[1], B: raw[2], A: 0xFF}, nil //nolint:mnd
+}
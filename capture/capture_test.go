@@ -0,0 +1,115 @@
+package capture
+
+import (
+	"bytes"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+
+	"github.com/aalbacetef/chipper"
+)
+
+func newDisplay(t *testing.T) *chipper.DebugDisplay {
+	t.Helper()
+
+	d, err := chipper.NewDebugDisplay(8, 4)
+	if err != nil {
+		t.Fatalf("could not create display: %v", err)
+	}
+
+	return d
+}
+
+func TestSnapshot(t *testing.T) {
+	d := newDisplay(t)
+	d.Set(1, 2, d.ColorSet())
+
+	opts := Options{Scale: 3, Palette: color.Palette{color.Black, color.RGBA{0xFF, 0, 0, 0xFF}}}
+	img := Snapshot(d, opts)
+
+	if got := img.Bounds().Size(); got.X != 24 || got.Y != 12 {
+		t.Fatalf("want 24x12 image, got %v", got)
+	}
+
+	for _, p := range [][2]int{{3, 6}, {5, 8}} {
+		if img.ColorIndexAt(p[0], p[1]) != chipper.ColorSet {
+			t.Fatalf("pixel %v should be set", p)
+		}
+	}
+
+	for _, p := range [][2]int{{2, 6}, {6, 6}, {3, 9}} {
+		if img.ColorIndexAt(p[0], p[1]) != chipper.ColorClear {
+			t.Fatalf("pixel %v should be clear", p)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WritePNG(buf, d, opts); err != nil {
+		t.Fatalf("could not write png: %v", err)
+	}
+
+	decoded, err := png.Decode(buf)
+	if err != nil {
+		t.Fatalf("could not decode png: %v", err)
+	}
+
+	if !chipper.ColorEq(decoded.At(4, 7), opts.Palette[1]) {
+		t.Fatalf("want palette color, got %v", decoded.At(4, 7))
+	}
+}
+
+func TestParsePalette(t *testing.T) {
+	p, err := ParsePalette("#000000, ffb000")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !chipper.ColorEq(p[1], color.RGBA{0xFF, 0xB0, 0, 0xFF}) {
+		t.Fatalf("unexpected color %v", p[1])
+	}
+
+	for _, s := range []string{"000000", "000000,fff", "000000,zzzzzz", "0,0,0"} {
+		if _, err := ParsePalette(s); err == nil {
+			t.Errorf("expected error parsing '%s'", s)
+		}
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	d := newDisplay(t)
+	rec := NewRecorder(d, Options{FrameSkip: 1})
+
+	if err := rec.WriteGIF(&bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error with no frames")
+	}
+
+	// frames 0-3 are blank, 4-9 have a pixel set. Odd frames are skipped.
+	for k := 0; k < 10; k++ {
+		if k == 4 {
+			d.Set(0, 0, d.ColorSet())
+		}
+
+		rec.Frame(nil)
+	}
+
+	if rec.Len() != 2 {
+		t.Fatalf("want 2 distinct frames, got %d", rec.Len())
+	}
+
+	buf := &bytes.Buffer{}
+	if err := rec.WriteGIF(buf); err != nil {
+		t.Fatalf("could not write gif: %v", err)
+	}
+
+	anim, err := gif.DecodeAll(buf)
+	if err != nil {
+		t.Fatalf("could not decode gif: %v", err)
+	}
+
+	// 4 frames are 6.67cs, 6 frames are 10cs.
+	want := []int{6, 10}
+	if len(anim.Delay) != len(want) || anim.Delay[0] != want[0] || anim.Delay[1] != want[1] {
+		t.Fatalf("want delays %v, got %v", want, anim.Delay)
+	}
+}
@@ -0,0 +1,338 @@
+package capture
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Filter post-processes a frame. Filters work on the intensity of each pixel,
+// from 0 for a clear pixel to 0xFF for a set one, and return an *image.Gray:
+// the palette is applied after the last filter, so they compose with any
+// palette. A filter may keep state between frames, so each recording needs its
+// own.
+type Filter interface {
+	Apply(img image.Image) image.Image
+}
+
+// FilterFunc is a Filter without state.
+type FilterFunc func(img image.Image) image.Image
+
+func (f FilterFunc) Apply(img image.Image) image.Image {
+	return f(img)
+}
+
+// toGray returns img as a grayscale image with its origin at (0, 0).
+func toGray(img image.Image) *image.Gray {
+	b := img.Bounds()
+	if g, ok := img.(*image.Gray); ok && b.Min == (image.Point{}) {
+		return g
+	}
+
+	g := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			g.Set(x, y, img.At(x+b.Min.X, y+b.Min.Y))
+		}
+	}
+
+	return g
+}
+
+// clampAt returns the intensity at (x, y), clamping the point to the image so
+// edge pixels see themselves as their missing neighbours.
+func clampAt(g *image.Gray, x, y int) uint8 {
+	b := g.Bounds()
+	x = min(max(x, b.Min.X), b.Max.X-1)
+	y = min(max(y, b.Min.Y), b.Max.Y-1)
+
+	return g.GrayAt(x, y).Y
+}
+
+// Phosphor emulates the slow decay of a CRT's phosphor: a pixel lights up at
+// once, but fades by Decay every frame after it is cleared instead of
+// vanishing. This also hides the flicker of sprites being erased and redrawn.
+type Phosphor struct {
+	Decay float64 // fraction of its intensity a pixel keeps each frame, in [0, 1).
+
+	level []float64
+	size  image.Point
+}
+
+func NewPhosphor(decay float64) *Phosphor {
+	return &Phosphor{Decay: decay}
+}
+
+func (p *Phosphor) Apply(img image.Image) image.Image {
+	g := toGray(img)
+	size := g.Bounds().Size()
+
+	if size != p.size {
+		p.size = size
+		p.level = make([]float64, size.X*size.Y)
+	}
+
+	out := image.NewGray(g.Bounds())
+
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			k := y*size.X + x
+			p.level[k] = max(float64(g.GrayAt(x, y).Y), p.level[k]*p.Decay)
+			out.Pix[out.PixOffset(x, y)] = uint8(p.level[k] + 0.5) //nolint:mnd
+		}
+	}
+
+	return out
+}
+
+// Scale returns a filter enlarging frames n times with nearest neighbour
+// sampling.
+func Scale(n int) Filter {
+	n = max(n, 1)
+
+	return FilterFunc(func(img image.Image) image.Image {
+		g := toGray(img)
+		b := g.Bounds()
+		out := image.NewGray(image.Rect(0, 0, b.Dx()*n, b.Dy()*n))
+
+		for y := 0; y < out.Rect.Dy(); y++ {
+			for x := 0; x < out.Rect.Dx(); x++ {
+				out.SetGray(x, y, g.GrayAt(x/n, y/n))
+			}
+		}
+
+		return out
+	})
+}
+
+// Scale2x doubles the size of a frame with the Scale2x (EPX) algorithm, which
+// rounds off the corners of diagonal edges instead of making them blocky.
+var Scale2x Filter = FilterFunc(scale2x) //nolint:gochecknoglobals
+
+func scale2x(img image.Image) image.Image {
+	g := toGray(img)
+	b := g.Bounds()
+	out := image.NewGray(image.Rect(0, 0, b.Dx()*2, b.Dy()*2)) //nolint:mnd
+
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			p := g.GrayAt(x, y).Y
+			up, down := clampAt(g, x, y-1), clampAt(g, x, y+1)
+			left, right := clampAt(g, x-1, y), clampAt(g, x+1, y)
+
+			e0, e1, e2, e3 := p, p, p, p
+
+			if left == up && left != down && up != right {
+				e0 = up
+			}
+
+			if up == right && up != left && right != down {
+				e1 = right
+			}
+
+			if down == left && down != right && left != up {
+				e2 = left
+			}
+
+			if right == down && right != up && down != left {
+				e3 = down
+			}
+
+			out.Pix[out.PixOffset(2*x, 2*y)] = e0
+			out.Pix[out.PixOffset(2*x+1, 2*y)] = e1
+			out.Pix[out.PixOffset(2*x, 2*y+1)] = e2
+			out.Pix[out.PixOffset(2*x+1, 2*y+1)] = e3
+		}
+	}
+
+	return out
+}
+
+// HQ2x doubles the size of a frame in the manner of hq2x: each corner of a
+// pixel is blended with its neighbours depending on which of them look alike,
+// smoothing edges with intermediate shades. Rather than hq2x's table of 256
+// neighbourhood patterns, it uses the three cases that matter for
+// monochrome frames: an edge crossing the corner, a lone differing diagonal
+// and no edge at all.
+var HQ2x Filter = FilterFunc(hq2x) //nolint:gochecknoglobals
+
+// hqThreshold is the luminance difference under which hq2x considers two
+// pixels alike.
+const hqThreshold = 48
+
+func alike(a, b uint8) bool {
+	d := int(a) - int(b)
+
+	return d <= hqThreshold && d >= -hqThreshold
+}
+
+func hq2x(img image.Image) image.Image {
+	g := toGray(img)
+	b := g.Bounds()
+	out := image.NewGray(image.Rect(0, 0, b.Dx()*2, b.Dy()*2)) //nolint:mnd
+
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			p := g.GrayAt(x, y).Y
+
+			for _, c := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				dx, dy := 2*c[0]-1, 2*c[1]-1
+				horiz := clampAt(g, x+dx, y)
+				vert := clampAt(g, x, y+dy)
+				diag := clampAt(g, x+dx, y+dy)
+
+				out.Pix[out.PixOffset(2*x+c[0], 2*y+c[1])] = hqCorner(p, horiz, vert, diag)
+			}
+		}
+	}
+
+	return out
+}
+
+// hqCorner returns the intensity of the corner of p between its horizontal,
+// vertical and diagonal neighbours.
+func hqCorner(p, horiz, vert, diag uint8) uint8 {
+	blend := func(wp, wh, wv, wd int) uint8 {
+		sum := wp*int(p) + wh*int(horiz) + wv*int(vert) + wd*int(diag)
+
+		return uint8((sum + (wp+wh+wv+wd)/2) / (wp + wh + wv + wd)) //nolint:gosec
+	}
+
+	switch {
+	case alike(horiz, vert) && !alike(p, horiz) && !alike(p, diag):
+		return blend(2, 3, 3, 0) //nolint:mnd
+	case alike(horiz, vert) && !alike(p, horiz):
+		return blend(6, 1, 1, 0) //nolint:mnd
+	case alike(p, horiz) && alike(p, vert) && !alike(p, diag):
+		return blend(3, 0, 0, 1) //nolint:mnd
+	default:
+		return p
+	}
+}
+
+// Scanlines returns a filter darkening every other row by strength, in [0, 1],
+// like the gaps between the lines of a CRT. It works best on frames already
+// scaled up.
+func Scanlines(strength float64) Filter {
+	keep := 1 - min(max(strength, 0), 1)
+
+	return FilterFunc(func(img image.Image) image.Image {
+		g := toGray(img)
+		b := g.Bounds()
+		out := image.NewGray(b)
+		copy(out.Pix, g.Pix)
+
+		for y := 1; y < b.Dy(); y += 2 {
+			row := out.Pix[out.PixOffset(0, y):out.PixOffset(b.Dx(), y)]
+			for k, v := range row {
+				row[k] = uint8(float64(v)*keep + 0.5) //nolint:mnd
+			}
+		}
+
+		return out
+	})
+}
+
+// gradient spreads the colors of p evenly over the 256 intensities, blending
+// between neighbouring colors.
+func gradient(p color.Palette) color.Palette {
+	const levels = 256
+
+	out := make(color.Palette, levels)
+	stops := len(p) - 1
+
+	for v := 0; v < levels; v++ {
+		pos := v * stops
+		k, rem := pos/(levels-1), pos%(levels-1)
+
+		if k == stops {
+			out[v] = p[k]
+
+			continue
+		}
+
+		out[v] = lerp(p[k], p[k+1], rem, levels-1)
+	}
+
+	return out
+}
+
+func lerp(a, b color.Color, num, den int) color.Color {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+
+	mix := func(u, v uint32) uint8 {
+		return uint8((int(u>>8)*(den-num) + int(v>>8)*num) / den) //nolint:gosec,mnd
+	}
+
+	return color.RGBA{R: mix(r1, r2), G: mix(g1, g2), B: mix(b1, b2), A: mix(a1, a2)}
+}
+
+// ParseFilters parses a comma separated list of filters, applied in order.
+// Each is a name with an optional parameter:
+//
+//	phosphor[=decay]     fading pixels, decay defaults to 0.7.
+//	scale=n              nearest neighbour scaling.
+//	scale2x, epx         Scale2x upscaling.
+//	hq2x                 hq2x style upscaling.
+//	scanlines[=strength] darkened odd rows, strength defaults to 0.4.
+func ParseFilters(s string) ([]Filter, error) {
+	const (
+		defaultDecay     = 0.7
+		defaultScanlines = 0.4
+	)
+
+	filters := make([]Filter, 0)
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, param, hasParam := strings.Cut(part, "=")
+
+		switch strings.ToLower(name) {
+		case "phosphor":
+			decay, err := parseParam(param, hasParam, defaultDecay)
+			if err != nil || decay < 0 || decay >= 1 {
+				return nil, fmt.Errorf("filter '%s': decay must be in [0, 1)", part)
+			}
+
+			filters = append(filters, NewPhosphor(decay))
+		case "scale":
+			n, err := strconv.Atoi(param)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("filter '%s': want scale=n with n >= 1", part)
+			}
+
+			filters = append(filters, Scale(n))
+		case "scale2x", "epx":
+			filters = append(filters, Scale2x)
+		case "hq2x":
+			filters = append(filters, HQ2x)
+		case "scanlines":
+			strength, err := parseParam(param, hasParam, defaultScanlines)
+			if err != nil || strength < 0 || strength > 1 {
+				return nil, fmt.Errorf("filter '%s': strength must be in [0, 1]", part)
+			}
+
+			filters = append(filters, Scanlines(strength))
+		default:
+			return nil, fmt.Errorf("unknown filter '%s'", name)
+		}
+	}
+
+	return filters, nil
+}
+
+func parseParam(param string, hasParam bool, fallback float64) (float64, error) {
+	if !hasParam {
+		return fallback, nil
+	}
+
+	return strconv.ParseFloat(param, 64)
+}
@@ -0,0 +1,165 @@
+package capture
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// grayImage builds an image from rows of '#' (set) and '.' (clear) pixels.
+func grayImage(rows ...string) *image.Gray {
+	g := image.NewGray(image.Rect(0, 0, len(rows[0]), len(rows)))
+
+	for y, row := range rows {
+		for x, c := range row {
+			if c == '#' {
+				g.SetGray(x, y, color.Gray{Y: 0xFF})
+			}
+		}
+	}
+
+	return g
+}
+
+func TestPhosphor(t *testing.T) {
+	p := NewPhosphor(0.5)
+	lit := grayImage("#.")
+	dark := grayImage("..")
+
+	want := []uint8{0xFF, 0x80, 0x40, 0xFF}
+
+	for k, img := range []*image.Gray{lit, dark, dark, lit} {
+		got := p.Apply(img).(*image.Gray).GrayAt(0, 0).Y
+		if got != want[k] {
+			t.Fatalf("frame %d: want intensity %#x, got %#x", k, want[k], got)
+		}
+	}
+}
+
+func TestScale2x(t *testing.T) {
+	// the gaps of a diagonal line get filled in.
+	got := toGray(Scale2x.Apply(grayImage(
+		"....",
+		".#..",
+		"..#.",
+		"....",
+	)))
+
+	if got.Bounds().Dx() != 8 || got.Bounds().Dy() != 8 {
+		t.Fatalf("want an 8x8 image, got %v", got.Bounds())
+	}
+
+	for _, p := range [][2]int{{2, 2}, {3, 3}, {4, 3}, {3, 4}, {5, 5}} {
+		if got.GrayAt(p[0], p[1]).Y != 0xFF {
+			t.Fatalf("pixel %v should be set", p)
+		}
+	}
+
+	for _, p := range [][2]int{{5, 2}, {2, 5}, {0, 0}} {
+		if got.GrayAt(p[0], p[1]).Y != 0 {
+			t.Fatalf("pixel %v should be clear", p)
+		}
+	}
+}
+
+func TestHQ2x(t *testing.T) {
+	flat := toGray(HQ2x.Apply(grayImage("###", "###")))
+	for _, v := range flat.Pix {
+		if v != 0xFF {
+			t.Fatal("a flat image should not change")
+		}
+	}
+
+	// the edge of the diagonal gets intermediate shades.
+	got := toGray(HQ2x.Apply(grayImage(
+		"#..",
+		"##.",
+		"###",
+	)))
+
+	shaded := 0
+
+	for _, v := range got.Pix {
+		if v != 0 && v != 0xFF {
+			shaded++
+		}
+	}
+
+	if shaded == 0 {
+		t.Fatal("expected the edge to be smoothed")
+	}
+}
+
+func TestScanlines(t *testing.T) {
+	got := toGray(Scanlines(0.5).Apply(grayImage("#", "#", "#")))
+	want := []uint8{0xFF, 0x80, 0xFF}
+
+	for y, v := range want {
+		if got.GrayAt(0, y).Y != v {
+			t.Fatalf("row %d: want %#x, got %#x", y, v, got.GrayAt(0, y).Y)
+		}
+	}
+}
+
+func TestRenderFilters(t *testing.T) {
+	d := newDisplay(t)
+	d.Set(1, 1, d.ColorSet())
+
+	filters, err := ParseFilters("phosphor=0.5, scale=2, scanlines=1")
+	if err != nil {
+		t.Fatalf("could not parse filters: %v", err)
+	}
+
+	palette, err := ParsePalette("gameboy")
+	if err != nil {
+		t.Fatalf("could not parse palette: %v", err)
+	}
+
+	opts := Options{Scale: 1, Palette: palette, Filters: filters}
+	img := Render(d, opts)
+
+	if got := img.Bounds().Size(); got.X != 16 || got.Y != 8 {
+		t.Fatalf("want 16x8 image, got %v", got)
+	}
+
+	if !chipper.ColorEq(img.At(2, 2), palette[3]) {
+		t.Fatalf("want the set color, got %v", img.At(2, 2))
+	}
+
+	if !chipper.ColorEq(img.At(2, 3), palette[0]) {
+		t.Fatalf("want the scanline to be the clear color, got %v", img.At(2, 3))
+	}
+
+	d.Set(1, 1, d.ColorClear())
+
+	if faded := Render(d, opts).At(2, 2); chipper.ColorEq(faded, palette[0]) || chipper.ColorEq(faded, palette[3]) {
+		t.Fatalf("want the cleared pixel to fade, got %v", faded)
+	}
+
+	for _, s := range []string{"blur", "phosphor=1", "scale=0", "scanlines=x"} {
+		if _, err := ParseFilters(s); err == nil {
+			t.Errorf("expected error parsing '%s'", s)
+		}
+	}
+}
+
+func TestGradient(t *testing.T) {
+	p := color.Palette{color.Black, color.RGBA{0x80, 0, 0, 0xFF}, color.White}
+	g := gradient(p)
+
+	if len(g) != 256 {
+		t.Fatalf("want 256 colors, got %d", len(g))
+	}
+
+	for k, want := range map[int]color.Color{0: p[0], 0xFF: p[2]} {
+		if !chipper.ColorEq(g[k], want) {
+			t.Fatalf("level %#x: want %v, got %v", k, want, g[k])
+		}
+	}
+
+	if r, _, _, _ := g[0x7F].RGBA(); r>>8 < 0x7E || r>>8 > 0x80 {
+		t.Fatalf("want the middle color near the middle stop, got %v", g[0x7F])
+	}
+}
@@ -0,0 +1,88 @@
+package capture
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+
+	"github.com/aalbacetef/chipper"
+)
+
+var ErrNoFrames = errors.New("no frames recorded")
+
+// Recorder captures a Display once per emulated frame. Register its Frame
+// method with Emulator.AddFrameFunc.
+type Recorder struct {
+	display chipper.Display
+	opts    Options
+	frames  []*image.Paletted
+	ticks   []int // emulated frames each recorded frame stays on screen.
+	seen    int
+}
+
+func NewRecorder(d chipper.Display, opts Options) *Recorder {
+	return &Recorder{display: d, opts: opts}
+}
+
+// Frame captures the display, unless the frame is skipped. A frame identical
+// to the previous one extends it rather than being stored again. Skipped
+// frames still go through the filters, so those keeping state see every frame.
+func (r *Recorder) Frame(_ *chipper.Emulator) {
+	img := Render(r.display, r.opts)
+
+	skip := r.opts.skip(r.seen)
+	r.seen++
+
+	n := len(r.frames)
+	if skip && n > 0 {
+		r.ticks[n-1]++
+
+		return
+	}
+
+	if n > 0 && bytes.Equal(img.Pix, r.frames[n-1].Pix) {
+		r.ticks[n-1]++
+
+		return
+	}
+
+	r.frames = append(r.frames, img)
+	r.ticks = append(r.ticks, 1)
+}
+
+// Len returns the number of distinct frames recorded.
+func (r *Recorder) Len() int {
+	return len(r.frames)
+}
+
+// WriteGIF encodes the recorded frames as an animated GIF. Frame delays follow
+// emulated time, carrying rounding errors over so the total stays exact.
+func (r *Recorder) WriteGIF(w io.Writer) error {
+	if len(r.frames) == 0 {
+		return ErrNoFrames
+	}
+
+	const centiseconds = 100
+
+	anim := &gif.GIF{
+		Image: r.frames,
+		Delay: make([]int, len(r.frames)),
+	}
+
+	elapsed := 0
+
+	for k, ticks := range r.ticks {
+		start := elapsed * centiseconds / FrameRate
+		elapsed += ticks
+		anim.Delay[k] = elapsed*centiseconds/FrameRate - start
+	}
+
+	if err := gif.EncodeAll(w, anim); err != nil {
+		return fmt.Errorf("could not encode gif: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestY4MWriter(t *testing.T) {
+	d := newDisplay(t)
+	buf := &bytes.Buffer{}
+	y := NewY4MWriter(buf, d, Options{Scale: 1, FrameSkip: 1})
+
+	for k := 0; k < 5; k++ {
+		d.Set(k, 0, d.ColorSet())
+		y.Frame(nil)
+	}
+
+	if err := y.Close(); err != nil {
+		t.Fatalf("could not close: %v", err)
+	}
+
+	if y.Len() != 3 {
+		t.Fatalf("want 3 frames, got %d", y.Len())
+	}
+
+	header := "YUV4MPEG2 W8 H4 F60:2 Ip A1:1 C444\n"
+	frameSize := len("FRAME\n") + 3*8*4
+
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte(header)) {
+		t.Fatalf("unexpected header: %q", data[:len(header)])
+	}
+
+	if want := len(header) + 3*frameSize; len(data) != want {
+		t.Fatalf("want %d bytes, got %d", want, len(data))
+	}
+
+	// the last frame, taken after the fifth pixel was set.
+	luma := data[len(header)+2*frameSize+len("FRAME\n"):]
+	for x := 0; x < 8; x++ {
+		if lit := luma[x] > 0x80; lit != (x < 5) {
+			t.Fatalf("pixel %d: unexpected luma %#x", x, luma[x])
+		}
+	}
+
+	if err := NewY4MWriter(&bytes.Buffer{}, d, Options{}).Close(); !errors.Is(err, ErrNoFrames) {
+		t.Fatalf("want ErrNoFrames, got %v", err)
+	}
+}
+
+func TestAVIWriter(t *testing.T) {
+	d := newDisplay(t)
+	path := filepath.Join(t.TempDir(), "out.avi")
+
+	fd, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("could not create file: %v", err)
+	}
+
+	a := NewAVIWriter(fd, d, Options{Scale: 2}, DefaultJPEGQuality)
+
+	for k := 0; k < 4; k++ {
+		d.Set(k, k, d.ColorSet())
+		a.Frame(nil)
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatalf("could not close: %v", err)
+	}
+
+	if err := fd.Close(); err != nil {
+		t.Fatalf("could not close file: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read file: %v", err)
+	}
+
+	chunks, err := readRIFF(data)
+	if err != nil {
+		t.Fatalf("invalid avi: %v", err)
+	}
+
+	if got := binary.LittleEndian.Uint32(chunks["avih"][16:]); got != 4 {
+		t.Fatalf("want 4 frames in the main header, got %d", got)
+	}
+
+	if got := binary.LittleEndian.Uint32(chunks["strh"][32:]); got != 4 {
+		t.Fatalf("want a stream length of 4, got %d", got)
+	}
+
+	if got := len(chunks["idx1"]); got != 4*16 {
+		t.Fatalf("want 4 index entries, got %d bytes", got)
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(chunks["00dc"]))
+	if err != nil {
+		t.Fatalf("could not decode the first frame: %v", err)
+	}
+
+	if got := img.Bounds().Size(); got.X != 16 || got.Y != 8 {
+		t.Fatalf("want a 16x8 frame, got %v", got)
+	}
+}
+
+// readRIFF checks the sizes of every chunk and list in data, returning the
+// first chunk with each id.
+func readRIFF(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "AVI " {
+		return nil, errors.New("not an avi file")
+	}
+
+	if size := binary.LittleEndian.Uint32(data[4:]); int(size) != len(data)-8 {
+		return nil, fmt.Errorf("riff size is %d, file has %d bytes", size, len(data)-8)
+	}
+
+	chunks := make(map[string][]byte)
+
+	var walk func(b []byte) error
+
+	walk = func(b []byte) error {
+		for len(b) > 0 {
+			if len(b) < 8 {
+				return errors.New("truncated chunk header")
+			}
+
+			id, size := string(b[:4]), int(binary.LittleEndian.Uint32(b[4:]))
+			if 8+size > len(b) {
+				return fmt.Errorf("chunk '%s' of %d bytes overflows its parent", id, size)
+			}
+
+			body := b[8 : 8+size]
+
+			if id == "LIST" {
+				if err := walk(body[4:]); err != nil {
+					return err
+				}
+			} else if _, seen := chunks[id]; !seen {
+				chunks[id] = body
+			}
+
+			b = b[8+size+size%2:]
+		}
+
+		return nil
+	}
+
+	return chunks, walk(data[12:])
+}
@@ -0,0 +1,112 @@
+package capture
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// Y4MWriter streams frames as an uncompressed YUV4MPEG2 video, with full
+// resolution chroma so no color is lost. Like Recorder, register its Frame
+// method with Emulator.AddFrameFunc, and call Close once done.
+type Y4MWriter struct {
+	w       *bufio.Writer
+	display chipper.Display
+	opts    Options
+	size    image.Point
+	planes  []byte
+	seen    int
+	frames  int
+	err     error
+}
+
+func NewY4MWriter(w io.Writer, d chipper.Display, opts Options) *Y4MWriter {
+	return &Y4MWriter{w: bufio.NewWriter(w), display: d, opts: opts}
+}
+
+// Frame writes the display as the next frame of the video, unless skipped.
+// Errors are reported by Close.
+func (y *Y4MWriter) Frame(_ *chipper.Emulator) {
+	img := Render(y.display, y.opts)
+
+	skip := y.opts.skip(y.seen)
+	y.seen++
+
+	if skip || y.err != nil {
+		return
+	}
+
+	y.err = y.writeFrame(img)
+}
+
+func (y *Y4MWriter) writeFrame(img image.Image) error {
+	b := img.Bounds()
+
+	if y.frames == 0 {
+		y.size = b.Size()
+		y.planes = make([]byte, 3*b.Dx()*b.Dy()) //nolint:mnd
+
+		_, err := fmt.Fprintf(
+			y.w, "YUV4MPEG2 W%d H%d F%d:%d Ip A1:1 C444\n",
+			b.Dx(), b.Dy(), FrameRate, y.opts.FrameSkip+1,
+		)
+		if err != nil {
+			return fmt.Errorf("could not write header: %w", err)
+		}
+	}
+
+	if b.Size() != y.size {
+		return fmt.Errorf("frame %d is %v, want %v", y.frames, b.Size(), y.size)
+	}
+
+	n := b.Dx() * b.Dy()
+
+	for py := 0; py < b.Dy(); py++ {
+		for px := 0; px < b.Dx(); px++ {
+			r, g, bl, _ := img.At(px+b.Min.X, py+b.Min.Y).RGBA()
+			yy, cb, cr := color.RGBToYCbCr(uint8(r>>8), uint8(g>>8), uint8(bl>>8)) //nolint:mnd
+
+			k := py*b.Dx() + px
+			y.planes[k], y.planes[n+k], y.planes[2*n+k] = yy, cb, cr
+		}
+	}
+
+	if _, err := io.WriteString(y.w, "FRAME\n"); err != nil {
+		return fmt.Errorf("could not write frame %d: %w", y.frames, err)
+	}
+
+	if _, err := y.w.Write(y.planes); err != nil {
+		return fmt.Errorf("could not write frame %d: %w", y.frames, err)
+	}
+
+	y.frames++
+
+	return nil
+}
+
+// Len returns the number of frames written.
+func (y *Y4MWriter) Len() int {
+	return y.frames
+}
+
+// Close flushes the video, returning the first error met while writing it.
+// It does not close the underlying writer.
+func (y *Y4MWriter) Close() error {
+	if y.err != nil {
+		return y.err
+	}
+
+	if y.frames == 0 {
+		return ErrNoFrames
+	}
+
+	if err := y.w.Flush(); err != nil {
+		return fmt.Errorf("could not flush: %w", err)
+	}
+
+	return nil
+}
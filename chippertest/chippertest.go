@@ -0,0 +1,384 @@
+// Package chippertest runs CHIP-8 programs from Go tests.
+//
+//	m := chippertest.New(t, rom, chippertest.Options{})
+//	m.RunUntilPC(0x220)
+//	m.Press(5)
+//	m.RunFrames(10)
+//	m.AssertRegister(t, 0x3, 0x2A)
+//	m.AssertScreen(t, `
+//		#####...
+//		#...#...
+//	`)
+//
+// Runs are deterministic: timers tick once per frame regardless of the wall
+// clock, and CXNN draws from a source seeded with Options.Seed.
+package chippertest
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aalbacetef/chipper"
+)
+
+const (
+	DefaultWidth     = 64
+	DefaultHeight    = 32
+	DefaultStackSize = 16
+	DefaultRAMSize   = 4096
+	DefaultIPF       = 10
+	DefaultMaxSteps  = 1_000_000
+)
+
+// Options configures a Machine. The zero value runs a 64x32 CHIP-8 at 10
+// instructions per frame with the emulator's default quirks.
+type Options struct {
+	Width, Height int
+	StackSize     int
+	RAMSize       int
+
+	// IPF is the number of instructions per frame.
+	IPF int
+
+	// Quirks replaces chipper.DefaultQuirks if set.
+	Quirks *chipper.Quirks
+
+	// Seed seeds the random numbers of CXNN.
+	Seed int64
+
+	// MaxSteps bounds RunUntilPC.
+	MaxSteps int
+}
+
+func (o Options) withDefaults() Options {
+	defaults := []struct {
+		v   *int
+		def int
+	}{
+		{&o.Width, DefaultWidth},
+		{&o.Height, DefaultHeight},
+		{&o.StackSize, DefaultStackSize},
+		{&o.RAMSize, DefaultRAMSize},
+		{&o.IPF, DefaultIPF},
+		{&o.MaxSteps, DefaultMaxSteps},
+	}
+
+	for _, d := range defaults {
+		if *d.v <= 0 {
+			*d.v = d.def
+		}
+	}
+
+	return o
+}
+
+// Machine is an emulator running a ROM for a test. Its methods fail the test
+// it was created with if the emulator returns an error.
+type Machine struct {
+	t       testing.TB
+	opts    Options
+	emu     *chipper.Emulator
+	display *chipper.DebugDisplay
+	keys    *keypad
+}
+
+// New loads rom into a new emulator.
+func New(t testing.TB, rom []byte, opts Options) *Machine {
+	t.Helper()
+
+	opts = opts.withDefaults()
+
+	display, err := chipper.NewDebugDisplay(opts.Width, opts.Height)
+	if err != nil {
+		t.Fatalf("could not create display: %v", err)
+	}
+
+	m := &Machine{t: t, opts: opts, display: display}
+	m.keys = &keypad{m: m}
+
+	emu, err := chipper.NewEmulator(opts.StackSize, opts.RAMSize, display, m.keys)
+	if err != nil {
+		t.Fatalf("could not create emulator: %v", err)
+	}
+
+	if opts.Quirks != nil {
+		emu.Quirks = *opts.Quirks
+	}
+
+	emu.SetRand(rand.New(rand.NewSource(opts.Seed))) //nolint:gosec
+
+	if err := emu.Load(bytes.NewReader(rom)); err != nil {
+		t.Fatalf("could not load rom: %v", err)
+	}
+
+	m.emu = emu
+
+	return m
+}
+
+// Assemble assembles one instruction per line, see chipper.Assemble, into a
+// ROM.
+func Assemble(t testing.TB, lines ...string) []byte {
+	t.Helper()
+
+	rom := make([]byte, 0, len(lines)*chipper.InstructionSize)
+
+	for _, line := range lines {
+		raw, err := chipper.Assemble(line)
+		if err != nil {
+			t.Fatalf("could not assemble '%s': %v", line, err)
+		}
+
+		rom = append(rom, byte(raw>>8), byte(raw))
+	}
+
+	return rom
+}
+
+// Emulator returns the emulator, to inspect or change its state directly.
+func (m *Machine) Emulator() *chipper.Emulator {
+	return m.emu
+}
+
+// Step executes a single instruction.
+func (m *Machine) Step() {
+	m.t.Helper()
+
+	pc := m.emu.PC
+	if err := m.emu.Step(); err != nil {
+		m.t.Fatalf("error at PC=%#03x: %v", pc, err)
+	}
+}
+
+// RunFrames runs n frames of Options.IPF instructions, each followed by a
+// timer tick.
+func (m *Machine) RunFrames(n int) {
+	m.t.Helper()
+
+	for k := 0; k < n; k++ {
+		for i := 0; i < m.opts.IPF; i++ {
+			m.Step()
+		}
+
+		m.emu.TickTimers()
+	}
+}
+
+// RunUntilPC runs until PC is addr, ticking the timers every Options.IPF
+// instructions. It fails the test if addr is not reached within
+// Options.MaxSteps instructions.
+func (m *Machine) RunUntilPC(addr uint16) {
+	m.t.Helper()
+
+	for k := 0; k < m.opts.MaxSteps; k++ {
+		if m.emu.PC == addr {
+			return
+		}
+
+		m.Step()
+
+		if k%m.opts.IPF == m.opts.IPF-1 {
+			m.emu.TickTimers()
+		}
+	}
+
+	m.t.Fatalf("PC did not reach %#03x in %d steps, it is at %#03x", addr, m.opts.MaxSteps, m.emu.PC)
+}
+
+// Press holds key down until Release is called.
+func (m *Machine) Press(key int) {
+	m.keys.Set(key, true)
+}
+
+// Release lets go of key.
+func (m *Machine) Release(key int) {
+	m.keys.Set(key, false)
+}
+
+// Screen returns the display one row per line, '#' for set pixels and '.'
+// for clear ones.
+func (m *Machine) Screen() string {
+	sb := &strings.Builder{}
+
+	for y := 0; y < m.opts.Height; y++ {
+		for x := 0; x < m.opts.Width; x++ {
+			if m.display.Pixel(x, y) {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+// AssertScreen checks the display against golden, in the format of Screen.
+// Leading and trailing blank lines and the indentation of each line are
+// ignored, and rows may be shorter than the display: missing pixels must be
+// clear, as must missing rows.
+func (m *Machine) AssertScreen(t testing.TB, golden string) {
+	t.Helper()
+
+	want := normalizeScreen(golden, m.opts.Width, m.opts.Height)
+	got := m.Screen()
+
+	if got != want {
+		t.Errorf("screen does not match (+ extra, - missing):\n%s", Diff(want, got))
+	}
+}
+
+// AssertRegister checks VX, with x from 0 to F.
+func (m *Machine) AssertRegister(t testing.TB, x int, want byte) {
+	t.Helper()
+
+	if x < 0 || x >= chipper.RegisterCount {
+		t.Fatalf("no register V%d", x)
+	}
+
+	if got := m.emu.V[x]; got != want {
+		t.Errorf("V%X: want %#02x, got %#02x", x, want, got)
+	}
+}
+
+// AssertRAM checks the bytes in RAM starting at addr.
+func (m *Machine) AssertRAM(t testing.TB, addr uint16, want []byte) {
+	t.Helper()
+
+	end := int(addr) + len(want)
+	if end > len(m.emu.RAM) {
+		t.Fatalf("%d bytes at %#03x are past the end of RAM", len(want), addr)
+	}
+
+	if got := m.emu.RAM[addr:end]; !bytes.Equal(got, want) {
+		t.Errorf("RAM at %#03x: want % x, got % x", addr, want, got)
+	}
+}
+
+// normalizeScreen turns golden text into the format of Screen.
+func normalizeScreen(golden string, w, h int) string {
+	lines := strings.Split(strings.Trim(golden, "\n"), "\n")
+	sb := &strings.Builder{}
+
+	for y := 0; y < h; y++ {
+		row := ""
+		if y < len(lines) {
+			row = strings.TrimSpace(lines[y])
+		}
+
+		if len(row) > w {
+			row = row[:w]
+		}
+
+		sb.WriteString(row + strings.Repeat(".", w-len(row)) + "\n")
+	}
+
+	return sb.String()
+}
+
+// Diff draws got over want, both in the format of Screen, marking pixels only
+// set in got with '+' and pixels only set in want with '-'. Rows that differ
+// are flagged with '>'.
+func Diff(want, got string) string {
+	wantRows := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	gotRows := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	sb := &strings.Builder{}
+
+	row := func(rows []string, y int) string {
+		if y < len(rows) {
+			return rows[y]
+		}
+
+		return ""
+	}
+
+	for y := 0; y < max(len(wantRows), len(gotRows)); y++ {
+		w, g := row(wantRows, y), row(gotRows, y)
+		line := make([]byte, max(len(w), len(g)))
+		differs := false
+
+		for x := range line {
+			wantSet := x < len(w) && w[x] == '#'
+			gotSet := x < len(g) && g[x] == '#'
+
+			switch {
+			case wantSet == gotSet && gotSet:
+				line[x] = '#'
+			case wantSet == gotSet:
+				line[x] = '.'
+			case gotSet:
+				line[x] = '+'
+				differs = true
+			default:
+				line[x] = '-'
+				differs = true
+			}
+		}
+
+		marker := "  "
+		if differs {
+			marker = "> "
+		}
+
+		fmt.Fprintf(sb, "%s%s\n", marker, line)
+	}
+
+	return sb.String()
+}
+
+// keypad is the Machine's KeyInputSource. FX0A takes the lowest key held
+// down, and fails the test if there is none, as nothing could press one
+// while the emulator waits.
+type keypad struct {
+	mu   sync.Mutex
+	m    *Machine
+	keys [chipper.NumKeys]bool
+}
+
+func (k *keypad) Get(key int) bool {
+	if key < 0 || key >= chipper.NumKeys {
+		return false
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.keys[key]
+}
+
+func (k *keypad) Set(key int, v bool) {
+	if key < 0 || key >= chipper.NumKeys {
+		k.m.t.Fatalf("no key %d", key)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys[key] = v
+}
+
+func (k *keypad) WaitUntilKeypress() <-chan int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	ch := make(chan int, 1)
+
+	for key, pressed := range k.keys {
+		if pressed {
+			ch <- key
+
+			return ch
+		}
+	}
+
+	k.m.t.Helper()
+	k.m.t.Fatalf("FX0A at PC=%#03x waits for a key, call Press first", k.m.emu.PC-chipper.InstructionSize)
+
+	return ch
+}
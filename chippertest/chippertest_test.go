@@ -0,0 +1,87 @@
+package chippertest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// program draws the font's 0, stores the BCD of 42 at 0x300, then waits for a
+// key and loops forever.
+func program(t *testing.T) []byte {
+	t.Helper()
+
+	return Assemble(t,
+		"LD V1, 0",
+		"LD F, V1",
+		"DRW V1, V1, 5",
+		"LD V3, 42",
+		"LD I, 0x300",
+		"LD B, V3",
+		"LD V2, K", // 0x20C
+		"JP 0x20E",
+	)
+}
+
+func TestMachine(t *testing.T) {
+	m := New(t, program(t), Options{})
+
+	m.RunUntilPC(0x20C)
+	m.AssertScreen(t, `
+		####
+		#..#
+		#..#
+		#..#
+		####
+	`)
+	m.AssertRegister(t, 3, 42)
+	m.AssertRAM(t, 0x300, []byte{0, 4, 2})
+
+	m.Press(7)
+	m.RunFrames(2)
+	m.AssertRegister(t, 2, 7)
+
+	if m.Emulator().PC != 0x20E {
+		t.Fatalf("want PC at 0x20E, got %#03x", m.Emulator().PC)
+	}
+}
+
+// recorder is a testing.TB recording failures instead of failing.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertFailures(t *testing.T) {
+	m := New(t, program(t), Options{})
+	m.RunUntilPC(0x20C)
+
+	r := &recorder{TB: t}
+
+	m.AssertScreen(r, "###\n#.#")
+	m.AssertRegister(r, 3, 41)
+	m.AssertRAM(r, 0x300, []byte{0, 4, 3})
+
+	if len(r.errors) != 3 {
+		t.Fatalf("want 3 failures, got %d: %v", len(r.errors), r.errors)
+	}
+
+	if !strings.Contains(r.errors[0], "> ###+") || !strings.Contains(r.errors[0], "> #.-+") {
+		t.Errorf("want a diff of the screen, got:\n%s", r.errors[0])
+	}
+}
+
+func TestDiff(t *testing.T) {
+	got := Diff("#.\n..\n", "#.\n.#\n")
+	want := "  #.\n> .+\n"
+
+	if got != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/analysis"
+)
+
+func runDecompile(args []string) error {
+	fs := flag.NewFlagSet("decompile", flag.ExitOnError)
+	syntaxName := string(analysis.SyntaxOcto)
+	fs.StringVar(&syntaxName, "syntax", syntaxName, "output syntax: octo or c")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: chipper decompile [flags] rom.ch8")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("could not parse flags: %w", err)
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+
+		return fmt.Errorf("expected exactly one ROM, got %d", fs.NArg())
+	}
+
+	syntax, err := analysis.ParseSyntax(syntaxName)
+	if err != nil {
+		return err
+	}
+
+	rom, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("could not read ROM: %w", err)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	if err := analysis.Decompile(w, rom, chipper.StartAddress, syntax); err != nil {
+		return fmt.Errorf("could not decompile: %w", err)
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("could not write output: %w", err)
+	}
+
+	return nil
+}
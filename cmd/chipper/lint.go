@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/analysis"
+	"github.com/aalbacetef/chipper/internal/cli"
+)
+
+// errLintFailed makes chipper lint exit with a non-zero status, so it can
+// gate CI.
+var errLintFailed = errors.New("error(s) found")
+
+func runLint(args []string) error {
+	defaults := analysis.DefaultLintOptions()
+	ramSize := defaults.RAMSize
+	stackSize := defaults.StackSize
+	minSeverity := analysis.SeverityInfo.String()
+	machine := cli.Machine{}
+
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	fs.IntVar(&ramSize, "ram", ramSize, "RAM size in bytes, the platform's if not set")
+	fs.IntVar(&stackSize, "stack", stackSize, "stack size, the platform's if not set")
+	machine.AddPlatformFlags(fs)
+	fs.StringVar(&minSeverity, "severity", minSeverity, "minimum severity to report: info, warning or error, errors fail the lint whatever it is")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: chipper lint [flags] rom.ch8")
+		fmt.Fprintln(fs.Output(), "\nexits with status 1 if any error-level finding is found.")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("could not parse flags: %w", err)
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+
+		return fmt.Errorf("expected exactly one ROM, got %d", fs.NArg())
+	}
+
+	threshold, err := analysis.ParseSeverity(minSeverity)
+	if err != nil {
+		return err
+	}
+
+	opts := analysis.LintOptionsFor(machine.Config(chipper.DefaultConfig()))
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "ram":
+			opts.RAMSize = ramSize
+		case "stack":
+			opts.StackSize = stackSize
+		}
+	})
+
+	rom, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("could not read ROM: %w", err)
+	}
+
+	errCount := 0
+
+	for _, f := range analysis.Lint(rom, opts) {
+		if f.Severity == analysis.SeverityError {
+			errCount++
+		}
+
+		if f.Severity >= threshold {
+			fmt.Println(f)
+		}
+	}
+
+	if errCount > 0 {
+		return fmt.Errorf("%d %w", errCount, errLintFailed)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+func commands() map[string]command {
+	return map[string]command{
+		"decompile": {"decompile a ROM into structured pseudo-code", runDecompile},
+		"lint":      {"report likely problems in a ROM", runLint},
+		"optimize":  {"shrink a ROM and verify it still behaves the same", runOptimize},
+		"run":       {"run ROMs headless for a number of frames and print display hashes", runRun},
+		"test":      {"run ROM test specs and report the results as TAP or JUnit XML", runTest},
+	}
+}
+
+func main() {
+	cmds := commands()
+
+	if len(os.Args) < 2 { //nolint:mnd
+		usage(cmds)
+		os.Exit(2) //nolint:mnd
+	}
+
+	name := os.Args[1]
+
+	cmd, ok := cmds[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command '%s'\n\n", name)
+		usage(cmds)
+		os.Exit(2) //nolint:mnd
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+func usage(cmds map[string]command) {
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "usage: chipper <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, cmds[name].usage)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/analysis"
+	"github.com/aalbacetef/chipper/romdb"
+)
+
+func runOptimize(args []string) error {
+	opts := analysis.DefaultVerifyOptions()
+	out := ""
+	verify := true
+
+	fs := flag.NewFlagSet("optimize", flag.ExitOnError)
+	fs.StringVar(&out, "o", out, "output file (default: <rom>.opt.ch8)")
+	fs.BoolVar(&verify, "verify", verify, "run both ROMs side by side and compare their displays")
+	fs.IntVar(&opts.Draws, "draws", opts.Draws, "display updates to compare when verifying")
+	fs.IntVar(&opts.InstructionsPerFrame, "ipf", opts.InstructionsPerFrame, "instructions per frame when verifying")
+	fs.Int64Var(&opts.Seed, "seed", opts.Seed, "seed for the random numbers and key presses used when verifying")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: chipper optimize [flags] rom.ch8")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("could not parse flags: %w", err)
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+
+		return fmt.Errorf("expected exactly one ROM, got %d", fs.NArg())
+	}
+
+	path := fs.Arg(0)
+
+	rom, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read ROM: %w", err)
+	}
+
+	optimized, stats := analysis.Optimize(rom, chipper.StartAddress)
+	fmt.Println(stats)
+
+	if verify {
+		if entry, ok := romdb.Lookup(rom); ok {
+			opts.Quirks = entry.Quirks()
+		}
+
+		if err := analysis.Verify(rom, optimized, opts); err != nil {
+			return fmt.Errorf("optimized ROM does not match the original: %w", err)
+		}
+
+		fmt.Println("verified: displays match")
+	}
+
+	if out == "" {
+		ext := filepath.Ext(path)
+		out = strings.TrimSuffix(path, ext) + ".opt" + ext
+	}
+
+	if err := os.WriteFile(out, optimized, 0o644); err != nil { //nolint:gosec
+		return fmt.Errorf("could not write optimized ROM: %w", err)
+	}
+
+	return nil
+}
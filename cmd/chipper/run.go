@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/capture"
+	"github.com/aalbacetef/chipper/internal/cli"
+	"github.com/aalbacetef/chipper/keyscript"
+	"github.com/aalbacetef/chipper/romdb"
+	"github.com/aalbacetef/chipper/terminal"
+)
+
+const (
+	defaultIPF = 10
+	frameRate  = 60
+)
+
+var errFailed = errors.New("some ROMs failed")
+
+type runOptions struct {
+	frames int
+	ipf    int // 0 uses the tick rate of the platform or the ROM database.
+	seed   int64
+	script *keyscript.Script
+	useDB  bool
+	live   func(emu *chipper.Emulator) // called after every frame, if set.
+
+	// machine is the machine of the flags. With a platform, the ROM
+	// database is not used.
+	machine cli.Machine
+}
+
+// runResult is the outcome of running a ROM.
+type runResult struct {
+	ROM    string        `json:"rom"`
+	Frames int           `json:"frames"`
+	Hash   string        `json:"hash"`
+	Error  string        `json:"error,omitempty"`
+	State  machineState  `json:"-"`
+	Frame  chipper.Frame `json:"-"`
+}
+
+// machineState is the state of the emulator at the end of a run.
+type machineState struct {
+	PC    uint16                      `json:"pc"`
+	I     uint16                      `json:"i"`
+	V     [chipper.RegisterCount]byte `json:"v"`
+	DT    byte                        `json:"dt"`
+	ST    byte                        `json:"st"`
+	Stack []uint16                    `json:"stack"`
+	RAM   string                      `json:"ram"` // hex.
+}
+
+func runRun(args []string) error {
+	opts := runOptions{frames: 600, seed: 1, useDB: true}
+	useTerm := false
+	scriptPath := ""
+	pngPath := ""
+	statePath := ""
+	checkPath := ""
+	asJSON := false
+	jobs := runtime.NumCPU()
+
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.BoolVar(&useTerm, "term", useTerm, "draw the display in the terminal in real time, instead of running as fast as possible")
+	fs.IntVar(&opts.frames, "frames", opts.frames, "frames to run for")
+	fs.IntVar(&opts.ipf, "ipf", opts.ipf, "instructions per frame, 0 uses the tick rate of the platform or the ROM database, or 10")
+	fs.Int64Var(&opts.seed, "seed", opts.seed, "seed for the random numbers of CXNN")
+	fs.BoolVar(&opts.useDB, "romdb", opts.useDB, "look ROMs up in the ROM database and apply their settings")
+	opts.machine.AddFlags(fs)
+	fs.StringVar(&scriptPath, "script", scriptPath, "play the keypad from this script, see the keyscript package")
+	fs.StringVar(&pngPath, "png", pngPath, "write the final display as a PNG to this path, a directory when running several ROMs")
+	fs.StringVar(&statePath, "state", statePath, "write the final registers and RAM as JSON to this path, a directory when running several ROMs")
+	fs.StringVar(&checkPath, "check", checkPath, "compare the hashes with this output of a previous run")
+	fs.BoolVar(&asJSON, "json", asJSON, "print results as JSON lines")
+	fs.IntVar(&jobs, "j", jobs, "ROMs to run in parallel")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: chipper run [flags] rom.ch8|directory")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("could not parse flags: %w", err)
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+
+		return fmt.Errorf("expected exactly one ROM or directory, got %d", fs.NArg())
+	}
+
+	paths, many, err := romPaths(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if useTerm && many {
+		return errors.New("only a single ROM can be run with -term")
+	}
+
+	if scriptPath != "" {
+		if opts.script, err = keyscript.ParseFile(scriptPath); err != nil {
+			return err
+		}
+	}
+
+	if useTerm {
+		renderer := terminal.NewRenderer(os.Stdout, terminal.HalfBlock)
+		defer renderer.Close()
+
+		next := time.Now()
+		opts.live = func(emu *chipper.Emulator) {
+			_ = renderer.Render(emu.Frame().Current)
+
+			next = next.Add(time.Second / frameRate)
+			time.Sleep(time.Until(next))
+		}
+	}
+
+	results := runAll(paths, opts, jobs)
+
+	if err := writeOutputs(results, many, pngPath, statePath); err != nil {
+		return err
+	}
+
+	failed := false
+
+	for _, res := range results {
+		if err := printResult(os.Stdout, res, asJSON); err != nil {
+			return err
+		}
+
+		failed = failed || res.Error != ""
+	}
+
+	if checkPath != "" {
+		if err := checkHashes(checkPath, results); err != nil {
+			return err
+		}
+	}
+
+	if failed {
+		return errFailed
+	}
+
+	return nil
+}
+
+// romPaths returns the ROM at path, or the .ch8 files in it if it is a
+// directory.
+func romPaths(path string) ([]string, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not read ROM: %w", err)
+	}
+
+	if !info.IsDir() {
+		return []string{path}, false, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(path, "*.ch8"))
+	if err != nil {
+		return nil, false, fmt.Errorf("could not list ROMs: %w", err)
+	}
+
+	if len(paths) == 0 {
+		return nil, false, fmt.Errorf("no .ch8 files in '%s'", path)
+	}
+
+	sort.Strings(paths)
+
+	return paths, true, nil
+}
+
+// runAll runs the ROMs, jobs at a time, returning their results in order.
+func runAll(paths []string, opts runOptions, jobs int) []runResult {
+	results := make([]runResult, len(paths))
+	work := make(chan int)
+	wg := sync.WaitGroup{}
+
+	for k := 0; k < max(jobs, 1); k++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for n := range work {
+				results[n] = runROM(paths[n], opts)
+			}
+		}()
+	}
+
+	for k := range paths {
+		work <- k
+	}
+
+	close(work)
+	wg.Wait()
+
+	return results
+}
+
+// runROM runs the ROM at path for opts.frames frames, or until it faults.
+func runROM(path string, opts runOptions) runResult {
+	res := runResult{ROM: path}
+
+	fail := func(err error) runResult {
+		res.Error = err.Error()
+
+		return res
+	}
+
+	rom, err := os.ReadFile(path)
+	if err != nil {
+		return fail(fmt.Errorf("could not read ROM: %w", err))
+	}
+
+	cfg := chipper.DefaultConfig()
+
+	if entry, ok := romdb.Lookup(rom); ok && opts.useDB && opts.machine.Platform == nil {
+		cfg = entry.Config()
+	}
+
+	cfg = opts.machine.Config(cfg)
+
+	emu, err := headlessEmulator(cfg, opts.script, opts.seed)
+	if err != nil {
+		return fail(err)
+	}
+
+	ipf := firstPositive(opts.ipf, cfg.TickRate, defaultIPF)
+
+	if err := emu.Load(bytes.NewReader(rom)); err != nil {
+		return fail(err)
+	}
+
+	for res.Frames < opts.frames {
+		if err := emu.RunFrame(ipf); err != nil {
+			res.Error = fmt.Sprintf("frame %d: %v", res.Frames, err)
+
+			break
+		}
+
+		res.Frames++
+
+		if opts.live != nil {
+			opts.live(emu)
+		}
+	}
+
+	res.Frame = emu.Frame()
+	res.Hash = screenHash(emu)
+	res.State = machineState{
+		PC:    emu.PC,
+		I:     emu.Index,
+		V:     emu.V,
+		DT:    emu.DelayTimer,
+		ST:    emu.SoundTimer,
+		Stack: emu.Stack.Values(),
+		RAM:   hex.EncodeToString(emu.RAM),
+	}
+
+	return res
+}
+
+// headlessEmulator returns the machine cfg describes, drawing to a bitmap,
+// with the keypad played by script, if not nil, and CXNN seeded with seed.
+func headlessEmulator(cfg chipper.Config, script *keyscript.Script, seed int64) (*chipper.Emulator, error) {
+	display, err := chipper.NewBitmap(cfg.Width, cfg.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	if script == nil {
+		script = &keyscript.Script{}
+	}
+
+	player := keyscript.NewPlayer(script)
+
+	emu, err := chipper.NewEmulatorWithConfig(cfg, display, player)
+	if err != nil {
+		return nil, err
+	}
+
+	emu.SetRand(rand.New(rand.NewSource(seed))) //nolint:gosec
+	player.Attach(emu)
+
+	return emu, nil
+}
+
+// screenHash returns the SHA-256 of the presented frame, in hex.
+func screenHash(emu *chipper.Emulator) string {
+	sum := sha256.Sum256(emu.Frame().Current.Bytes())
+
+	return hex.EncodeToString(sum[:])
+}
+
+func printResult(w io.Writer, res runResult, asJSON bool) error {
+	if asJSON {
+		data, err := json.Marshal(res)
+		if err != nil {
+			return fmt.Errorf("could not encode result: %w", err)
+		}
+
+		_, err = fmt.Fprintln(w, string(data))
+
+		return err
+	}
+
+	line := fmt.Sprintf("%s  %s", res.Hash, res.ROM)
+	if res.Error != "" {
+		line += "  FAIL: " + res.Error
+	}
+
+	_, err := fmt.Fprintln(w, line)
+
+	return err
+}
+
+// outputPath returns where to write the output with extension ext for res:
+// path itself for a single ROM, or a file named after the ROM in the
+// directory path.
+func outputPath(path string, many bool, res runResult, ext string) string {
+	if !many {
+		return path
+	}
+
+	base := strings.TrimSuffix(filepath.Base(res.ROM), filepath.Ext(res.ROM))
+
+	return filepath.Join(path, base+ext)
+}
+
+func writeOutputs(results []runResult, many bool, pngPath, statePath string) error {
+	for _, dir := range []string{pngPath, statePath} {
+		if many && dir != "" {
+			if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:gosec
+				return fmt.Errorf("could not create output directory: %w", err)
+			}
+		}
+	}
+
+	for _, res := range results {
+		if res.Frame.Current == nil {
+			continue
+		}
+
+		if pngPath != "" {
+			buf := &bytes.Buffer{}
+			if err := capture.WritePNG(buf, res.Frame.Current, capture.DefaultOptions()); err != nil {
+				return err
+			}
+
+			if err := os.WriteFile(outputPath(pngPath, many, res, ".png"), buf.Bytes(), 0o644); err != nil { //nolint:gosec
+				return fmt.Errorf("could not write PNG: %w", err)
+			}
+		}
+
+		if statePath != "" {
+			data, err := json.MarshalIndent(res.State, "", "  ")
+			if err != nil {
+				return fmt.Errorf("could not encode state: %w", err)
+			}
+
+			if err := os.WriteFile(outputPath(statePath, many, res, ".json"), data, 0o644); err != nil { //nolint:gosec
+				return fmt.Errorf("could not write state: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkHashes compares the results with the text output of a previous run,
+// matching ROMs by file name.
+func checkHashes(path string, results []runResult) error {
+	fd, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open hashes: %w", err)
+	}
+	defer fd.Close()
+
+	want := make(map[string]string)
+	scanner := bufio.NewScanner(fd)
+
+	// lines are "hash  path", maybe followed by "  FAIL: error", and paths
+	// may have spaces.
+	for scanner.Scan() {
+		hash, rom, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok {
+			continue
+		}
+
+		rom, _, _ = strings.Cut(rom, "  FAIL: ")
+		want[filepath.Base(rom)] = hash
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read hashes: %w", err)
+	}
+
+	mismatches := 0
+
+	for _, res := range results {
+		hash, ok := want[filepath.Base(res.ROM)]
+
+		switch {
+		case !ok:
+			fmt.Fprintf(os.Stderr, "%s: no hash to check against\n", res.ROM)
+			mismatches++
+		case hash != res.Hash:
+			fmt.Fprintf(os.Stderr, "%s: hash %s, want %s\n", res.ROM, res.Hash, hash)
+			mismatches++
+		}
+	}
+
+	if mismatches > 0 {
+		return fmt.Errorf("%d of %d ROMs do not match '%s'", mismatches, len(results), path)
+	}
+
+	return nil
+}
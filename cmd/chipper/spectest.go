@@ -0,0 +1,598 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/internal/cli"
+	"github.com/aalbacetef/chipper/keyscript"
+	"github.com/aalbacetef/chipper/romdb"
+)
+
+var errTestsFailed = errors.New("some tests failed")
+
+// testSpec is a ROM test, read from JSON:
+//
+//	{
+//	  "name": "IBM logo",
+//	  "rom": "../set-1/ibm-logo.ch8",
+//	  "platform": "modernChip8",
+//	  "font": "vip",
+//	  "quirks": {"shift": true},
+//	  "ipf": 10,
+//	  "seed": 1,
+//	  "keys": ["frame 30 hold 5 10"],
+//	  "checkpoints": [
+//	    {
+//	      "frame": 60,
+//	      "screen": "<sha256 of the display, as printed by chipper run>",
+//	      "registers": {"V0": "0x2A", "I": 554, "PC": "0x228"},
+//	      "memory": [{"addr": "0x300", "bytes": "00 04 02"}],
+//	      "sound": false
+//	    }
+//	  ]
+//	}
+//
+// A relative ROM path is relative to the spec. The platform is a chipper
+// preset or a ROM database platform, see cli.PlatformConfig, the -platform
+// flag of chipper test if empty. Without either, the ROM is looked up in the
+// ROM database, and quirks override all of them. The font is a built-in font
+// or a JSON font file, relative to the spec, the -font flag of chipper test if
+// empty, or the platform's. Keys are lines of a keyscript.
+// Checkpoints are checked at the start of their frame, so frame 0 is before
+// the first instruction.
+type testSpec struct {
+	Name        string           `json:"name"`
+	ROM         string           `json:"rom"`
+	Platform    string           `json:"platform,omitempty"`
+	Font        string           `json:"font,omitempty"`
+	Quirks      romdb.QuirkFlags `json:"quirks"`
+	IPF         int              `json:"ipf,omitempty"`
+	Seed        int64            `json:"seed,omitempty"`
+	Keys        []string         `json:"keys,omitempty"`
+	Checkpoints []checkpoint     `json:"checkpoints"`
+
+	path   string
+	script *keyscript.Script
+
+	// machine holds the platform and font of the spec, once resolved.
+	machine cli.Machine
+}
+
+type checkpoint struct {
+	Frame     uint64                `json:"frame"`
+	Name      string                `json:"name,omitempty"`
+	Screen    string                `json:"screen,omitempty"`
+	Registers map[string]specNumber `json:"registers,omitempty"`
+	Memory    []memoryCheck         `json:"memory,omitempty"`
+	Sound     *bool                 `json:"sound,omitempty"`
+}
+
+func (c checkpoint) String() string {
+	if c.Name != "" {
+		return c.Name
+	}
+
+	return fmt.Sprintf("frame %d", c.Frame)
+}
+
+type memoryCheck struct {
+	Addr  specNumber `json:"addr"`
+	Bytes string     `json:"bytes"` // hex, spaces are ignored.
+}
+
+// specNumber is a JSON number, or a string holding a decimal number or a hex
+// one prefixed with 0x or #.
+type specNumber int
+
+func (n *specNumber) UnmarshalJSON(data []byte) error {
+	var v int
+	if err := json.Unmarshal(data, &v); err == nil {
+		*n = specNumber(v)
+
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("want a number, got %s", data)
+	}
+
+	if rest, ok := strings.CutPrefix(s, "#"); ok {
+		s = "0x" + rest
+	}
+
+	v64, err := strconv.ParseInt(s, 0, 32)
+	if err != nil {
+		return fmt.Errorf("invalid number '%s'", s)
+	}
+
+	*n = specNumber(v64)
+
+	return nil
+}
+
+// testCase is the outcome of a checkpoint.
+type testCase struct {
+	Spec     string
+	Name     string
+	Failures []string
+	Duration time.Duration
+}
+
+func runTest(args []string) error {
+	format := "tap"
+	outPath := ""
+	machine := cli.Machine{}
+
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	fs.StringVar(&format, "format", format, "output format, tap or junit")
+	fs.StringVar(&outPath, "o", outPath, "write the report to this path instead of stdout")
+	machine.AddFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: chipper test [flags] spec.json|directory...")
+		fmt.Fprintln(fs.Output(), "\nspecs are JSON files, see cmd/chipper/spectest.go for the format.")
+		fmt.Fprintln(fs.Output(), "the platform and font of a spec win over -platform and -font.")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("could not parse flags: %w", err)
+	}
+
+	var write func(io.Writer, []testCase) error
+
+	switch format {
+	case "tap":
+		write = writeTAP
+	case "junit":
+		write = writeJUnit
+	default:
+		return fmt.Errorf("unknown format '%s', want tap or junit", format)
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+
+		return errors.New("expected at least one spec")
+	}
+
+	paths, err := specPaths(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	cases := make([]testCase, 0, len(paths))
+	for _, path := range paths {
+		cases = append(cases, runSpecFile(path, machine)...)
+	}
+
+	w := io.Writer(os.Stdout)
+
+	if outPath != "" {
+		fd, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("could not create report: %w", err)
+		}
+		defer fd.Close()
+
+		w = fd
+	}
+
+	if err := write(w, cases); err != nil {
+		return fmt.Errorf("could not write report: %w", err)
+	}
+
+	for _, c := range cases {
+		if len(c.Failures) > 0 {
+			return errTestsFailed
+		}
+	}
+
+	return nil
+}
+
+// specPaths expands directories in args to the .json files in them.
+func specPaths(args []string) ([]string, error) {
+	paths := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, fmt.Errorf("could not read spec: %w", err)
+		}
+
+		if !info.IsDir() {
+			paths = append(paths, arg)
+
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(arg, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("could not list specs: %w", err)
+		}
+
+		sort.Strings(matches)
+		paths = append(paths, matches...)
+	}
+
+	if len(paths) == 0 {
+		return nil, errors.New("no specs found")
+	}
+
+	return paths, nil
+}
+
+// runSpecFile runs the spec at path, on the machine of the flags where the
+// spec has no platform or font, returning a failed case if it cannot be
+// loaded.
+func runSpecFile(path string, machine cli.Machine) []testCase {
+	spec, err := loadSpec(path)
+	if err != nil {
+		return []testCase{{Spec: path, Name: "load", Failures: []string{err.Error()}}}
+	}
+
+	if spec.machine.Platform == nil {
+		spec.machine.Platform = machine.Platform
+	}
+
+	if spec.machine.Font == nil {
+		spec.machine.Font = machine.Font
+	}
+
+	spec.machine.OutOfRange = machine.OutOfRange
+
+	return runSpec(spec)
+}
+
+func loadSpec(path string) (*testSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read spec: %w", err)
+	}
+
+	spec := &testSpec{path: path}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(spec); err != nil {
+		return nil, fmt.Errorf("could not decode spec: %w", err)
+	}
+
+	if spec.Name == "" {
+		spec.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+func (s *testSpec) validate() error {
+	if s.ROM == "" {
+		return errors.New("spec has no rom")
+	}
+
+	if len(s.Checkpoints) == 0 {
+		return errors.New("spec has no checkpoints")
+	}
+
+	if s.Platform != "" {
+		cfg, err := cli.PlatformConfig(s.Platform)
+		if err != nil {
+			return err
+		}
+
+		s.machine.Platform = &cfg
+	}
+
+	script, err := keyscript.Parse(strings.NewReader(strings.Join(s.Keys, "\n")))
+	if err != nil {
+		return fmt.Errorf("invalid keys: %w", err)
+	}
+
+	s.script = script
+
+	if fontName := s.Font; fontName != "" {
+		if _, ok := chipper.FontByName(fontName); !ok && !filepath.IsAbs(fontName) {
+			fontName = filepath.Join(filepath.Dir(s.path), fontName)
+		}
+
+		if s.machine.Font, err = cli.LoadFont(fontName); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range s.Checkpoints {
+		for name := range c.Registers {
+			if _, ok := registerValue(&chipper.Emulator{}, name); !ok {
+				return fmt.Errorf("%s: unknown register '%s'", c, name)
+			}
+		}
+
+		for _, m := range c.Memory {
+			if _, err := hex.DecodeString(strings.ReplaceAll(m.Bytes, " ", "")); err != nil {
+				return fmt.Errorf("%s: invalid bytes at %#03x: %w", c, int(m.Addr), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// runSpec runs the spec's ROM up to each checkpoint in turn. Once the
+// emulator faults, the checkpoints left fail.
+func runSpec(spec *testSpec) []testCase {
+	checkpoints := append([]checkpoint(nil), spec.Checkpoints...)
+	sort.SliceStable(checkpoints, func(i, j int) bool { return checkpoints[i].Frame < checkpoints[j].Frame })
+
+	cases := make([]testCase, len(checkpoints))
+	for k, c := range checkpoints {
+		cases[k] = testCase{Spec: spec.Name, Name: c.String()}
+	}
+
+	failAll := func(err error) []testCase {
+		for k := range cases {
+			cases[k].Failures = append(cases[k].Failures, err.Error())
+		}
+
+		return cases
+	}
+
+	romPath := spec.ROM
+	if !filepath.IsAbs(romPath) {
+		romPath = filepath.Join(filepath.Dir(spec.path), romPath)
+	}
+
+	rom, err := os.ReadFile(romPath)
+	if err != nil {
+		return failAll(fmt.Errorf("could not read ROM: %w", err))
+	}
+
+	cfg := chipper.DefaultConfig()
+
+	if entry, ok := romdb.Lookup(rom); ok && spec.machine.Platform == nil {
+		cfg = entry.Config()
+	}
+
+	cfg = spec.machine.Config(cfg)
+	cfg.Quirks = spec.Quirks.Apply(cfg.Quirks)
+
+	emu, err := headlessEmulator(cfg, spec.script, spec.Seed)
+	if err != nil {
+		return failAll(err)
+	}
+
+	ipf := firstPositive(spec.IPF, cfg.TickRate, defaultIPF)
+
+	if err := emu.Load(bytes.NewReader(rom)); err != nil {
+		return failAll(err)
+	}
+
+	frame := uint64(0)
+
+	for k, c := range checkpoints {
+		start := time.Now()
+
+		for ; frame < c.Frame; frame++ {
+			if err := emu.RunFrame(ipf); err != nil {
+				for n := k; n < len(cases); n++ {
+					cases[n].Failures = append(cases[n].Failures, fmt.Sprintf("frame %d: %v", frame, err))
+				}
+
+				return cases
+			}
+		}
+
+		cases[k].Failures = check(emu, c)
+		cases[k].Duration = time.Since(start)
+	}
+
+	return cases
+}
+
+// firstPositive returns the first of values that is positive, or 0.
+func firstPositive(values ...int) int {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+
+	return 0
+}
+
+// check returns how the emulator differs from the checkpoint.
+func check(emu *chipper.Emulator, c checkpoint) []string {
+	var failures []string
+
+	if c.Screen != "" {
+		if got := screenHash(emu); !strings.EqualFold(got, c.Screen) {
+			failures = append(failures, fmt.Sprintf("screen: want %s, got %s", c.Screen, got))
+		}
+	}
+
+	names := make([]string, 0, len(c.Registers))
+	for name := range c.Registers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		want := int(c.Registers[name])
+		if got, _ := registerValue(emu, name); got != want {
+			failures = append(failures, fmt.Sprintf("%s: want %#02x, got %#02x", strings.ToUpper(name), want, got))
+		}
+	}
+
+	for _, m := range c.Memory {
+		want, _ := hex.DecodeString(strings.ReplaceAll(m.Bytes, " ", ""))
+		addr := int(m.Addr)
+
+		if addr < 0 || addr+len(want) > len(emu.RAM) {
+			failures = append(failures, fmt.Sprintf("RAM: %d bytes at %#03x are out of range", len(want), addr))
+
+			continue
+		}
+
+		if got := emu.RAM[addr : addr+len(want)]; !bytes.Equal(got, want) {
+			failures = append(failures, fmt.Sprintf("RAM at %#03x: want % x, got % x", addr, want, got))
+		}
+	}
+
+	if c.Sound != nil && emu.Sounding() != *c.Sound {
+		failures = append(failures, fmt.Sprintf("sound: want %t, got %t", *c.Sound, emu.Sounding()))
+	}
+
+	return failures
+}
+
+// registerValue returns the register named V0 to VF, I, PC, DT or ST,
+// in any case.
+func registerValue(emu *chipper.Emulator, name string) (int, bool) {
+	name = strings.ToUpper(name)
+
+	switch name {
+	case "I":
+		return int(emu.Index), true
+	case "PC":
+		return int(emu.PC), true
+	case "DT":
+		return int(emu.DelayTimer), true
+	case "ST":
+		return int(emu.SoundTimer), true
+	}
+
+	if len(name) != 2 || name[0] != 'V' { //nolint:mnd
+		return 0, false
+	}
+
+	x, err := strconv.ParseUint(name[1:], 16, 4)
+	if err != nil {
+		return 0, false
+	}
+
+	return int(emu.V[x]), true
+}
+
+// writeTAP writes the cases in the Test Anything Protocol, version 13, with
+// failures in a YAML block after each failed test.
+func writeTAP(w io.Writer, cases []testCase) error {
+	b := &strings.Builder{}
+
+	fmt.Fprintln(b, "TAP version 13")
+	fmt.Fprintf(b, "1..%d\n", len(cases))
+
+	for k, c := range cases {
+		status := "ok"
+		if len(c.Failures) > 0 {
+			status = "not ok"
+		}
+
+		fmt.Fprintf(b, "%s %d - %s: %s\n", status, k+1, c.Spec, c.Name)
+
+		if len(c.Failures) == 0 {
+			continue
+		}
+
+		fmt.Fprintln(b, "  ---")
+		fmt.Fprintln(b, "  failures:")
+
+		for _, f := range c.Failures {
+			fmt.Fprintf(b, "    - %s\n", strconv.Quote(f))
+		}
+
+		fmt.Fprintln(b, "  ...")
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes the cases as JUnit XML, with a suite per spec.
+func writeJUnit(w io.Writer, cases []testCase) error {
+	report := junitSuites{}
+	seconds := func(d time.Duration) string { return strconv.FormatFloat(d.Seconds(), 'f', 3, 64) } //nolint:mnd
+
+	var (
+		suite    *junitSuite
+		duration time.Duration
+	)
+
+	for _, c := range cases {
+		if suite == nil || suite.Name != c.Spec {
+			report.Suites = append(report.Suites, junitSuite{Name: c.Spec})
+			suite = &report.Suites[len(report.Suites)-1]
+			duration = 0
+		}
+
+		jc := junitCase{Name: c.Name, ClassName: c.Spec, Time: seconds(c.Duration)}
+
+		if len(c.Failures) > 0 {
+			jc.Failure = &junitFailure{Message: c.Failures[0], Text: strings.Join(c.Failures, "\n")}
+			suite.Failures++
+			report.Failures++
+		}
+
+		duration += c.Duration
+		suite.Time = seconds(duration)
+		suite.Tests++
+		report.Tests++
+		suite.Cases = append(suite.Cases, jc)
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+
+	return err
+}
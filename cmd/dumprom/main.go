@@ -15,14 +15,20 @@ func main() {
 	name := ""
 	dump := false
 	text := false
+	sprites := ""
+	steps := 10000
+	scale := 4
 
 	flag.StringVar(&name, "name", name, "filepath to read")
 	flag.BoolVar(&dump, "dump", dump, "dump instructions")
 	flag.BoolVar(&text, "text", text, "to human readable text")
+	flag.StringVar(&sprites, "sprites", sprites, "extract sprites into a PNG sprite sheet at this path")
+	flag.IntVar(&steps, "steps", steps, "instructions to run when looking for sprites (0 for static only)")
+	flag.IntVar(&scale, "scale", scale, "pixel scale of the sprite sheet")
 
 	flag.Parse()
 
-	if !(dump || text) {
+	if !(dump || text || sprites != "") {
 		flag.Usage()
 
 		return
@@ -46,6 +52,16 @@ func main() {
 
 		return
 	}
+
+	if sprites != "" {
+		fmt.Print(printHeader("sprt"))
+
+		if err := dumpSprites(data, sprites, steps, max(1, scale)); err != nil {
+			log.Println("error: ", err)
+
+			return
+		}
+	}
 }
 
 func run(data []byte, dump bool, text bool) error {
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/analysis"
+)
+
+const (
+	spriteStepsPerFrame = 10
+	sheetColumns        = 8
+	sheetPadding        = 4
+	glyphWidth          = 4
+	glyphHeight         = 5
+)
+
+//nolint:gochecknoglobals
+var (
+	sheetBackground = color.RGBA{0x30, 0x30, 0x30, 0xFF}
+	sheetLabel      = color.RGBA{0xC0, 0xC0, 0xC0, 0xFF}
+)
+
+// dumpSprites finds the sprites of the ROM, statically and by running it for
+// the given number of steps, and writes them to a PNG sprite sheet.
+func dumpSprites(data []byte, out string, steps, scale int) error {
+	cfg := analysis.BuildCFG(data, chipper.StartAddress)
+	static := analysis.StaticSprites(cfg)
+
+	dynamic, err := runForSprites(data, steps)
+	if err != nil {
+		return err
+	}
+
+	sprites := analysis.MergeSprites(static, dynamic)
+	printSprites(sprites)
+
+	if len(sprites) == 0 {
+		return fmt.Errorf("no sprites found")
+	}
+
+	fd, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("could not create sprite sheet: %w", err)
+	}
+	defer fd.Close()
+
+	if err := png.Encode(fd, spriteSheet(sprites, scale)); err != nil {
+		return fmt.Errorf("could not encode sprite sheet: %w", err)
+	}
+
+	return nil
+}
+
+// runForSprites runs the ROM headless, recording the address and height of
+// every sprite drawn. It stops early if the ROM faults.
+func runForSprites(data []byte, steps int) ([]analysis.Sprite, error) {
+	const (
+		stackSize = 16
+		ramSize   = 4096
+		w         = 64
+		h         = 32
+	)
+
+	if steps <= 0 {
+		return nil, nil
+	}
+
+	display, err := chipper.NewDebugDisplay(w, h)
+	if err != nil {
+		return nil, fmt.Errorf("could not create display: %w", err)
+	}
+
+	emu, err := chipper.NewEmulator(stackSize, ramSize, display, &chipper.StubKeyInputSource{})
+	if err != nil {
+		return nil, fmt.Errorf("could not create emulator: %w", err)
+	}
+
+	if err := emu.Load(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("could not load ROM: %w", err)
+	}
+
+	rec := analysis.NewSpriteRecorder()
+	emu.AddTraceFunc(rec.Trace)
+
+	for k := 0; k < steps; k++ {
+		if k%spriteStepsPerFrame == 0 {
+			emu.TickTimers()
+		}
+
+		err := emu.Step()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "stopped after %d steps: %v\n", k, err)
+
+			break
+		}
+	}
+
+	return rec.Sprites(), nil
+}
+
+func printSprites(sprites []analysis.Sprite) {
+	b := &strings.Builder{}
+	tw := tabwriter.NewWriter(b, 0, 0, 1, ' ', tabwriter.TabIndent)
+
+	for k, s := range sprites {
+		source := "static"
+
+		switch {
+		case s.Static && s.Dynamic:
+			source = "static+dynamic"
+		case s.Dynamic:
+			source = "dynamic"
+		}
+
+		fmt.Fprintf(tw, "%2d) %0#4x \t8x%d \t%s\n", k, s.Addr, s.Height, source)
+	}
+
+	tw.Flush()
+	fmt.Println(b.String())
+}
+
+// spriteSheet lays the sprites out in a grid, each one labelled with its
+// address and height in hex, drawn with the CHIP-8 font.
+func spriteSheet(sprites []analysis.Sprite, scale int) *image.RGBA {
+	maxHeight := 0
+	for _, s := range sprites {
+		maxHeight = max(maxHeight, s.Height)
+	}
+
+	labelHeight := glyphHeight + sheetPadding
+	cellW := max(8*scale, labelWidth(sprites[0])) + sheetPadding //nolint:mnd
+	cellH := labelHeight + maxHeight*scale + sheetPadding
+
+	cols := min(sheetColumns, len(sprites))
+	rows := (len(sprites) + cols - 1) / cols
+
+	img := image.NewRGBA(image.Rect(0, 0, cols*cellW+sheetPadding, rows*cellH+sheetPadding))
+	fill(img, img.Bounds(), sheetBackground)
+
+	for k, s := range sprites {
+		x0 := sheetPadding + (k%cols)*cellW
+		y0 := sheetPadding + (k/cols)*cellH
+
+		drawText(img, x0, y0, label(s))
+		drawSprite(img, x0, y0+labelHeight, s, scale)
+	}
+
+	return img
+}
+
+func label(s analysis.Sprite) string {
+	return fmt.Sprintf("%03X %X", s.Addr, s.Height)
+}
+
+func labelWidth(s analysis.Sprite) int {
+	return len(label(s)) * (glyphWidth + 1)
+}
+
+func drawSprite(img *image.RGBA, x0, y0 int, s analysis.Sprite, scale int) {
+	const rowWidth = 8
+
+	for row, bits := range s.Data {
+		for col := 0; col < rowWidth; col++ {
+			c := color.Black
+			if bits&(0x80>>col) != 0 {
+				c = color.White
+			}
+
+			r := image.Rect(x0+col*scale, y0+row*scale, x0+(col+1)*scale, y0+(row+1)*scale)
+			fill(img, r, c)
+		}
+	}
+}
+
+func drawText(img *image.RGBA, x0, y0 int, text string) {
+	font := chipper.HexFont()
+
+	for k, ch := range text {
+		var digit int
+
+		switch {
+		case ch >= '0' && ch <= '9':
+			digit = int(ch - '0')
+		case ch >= 'A' && ch <= 'F':
+			digit = int(ch-'A') + 10 //nolint:mnd
+		default:
+			continue
+		}
+
+		glyph := font[digit*glyphHeight : (digit+1)*glyphHeight]
+		x := x0 + k*(glyphWidth+1)
+
+		for row, bits := range glyph {
+			for col := 0; col < glyphWidth; col++ {
+				if bits&(0x80>>col) != 0 {
+					img.Set(x+col, y0+row, sheetLabel)
+				}
+			}
+		}
+	}
+}
+
+func fill(img *image.RGBA, r image.Rectangle, c color.Color) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.Set(x, y, c)
+		}
+	}
+}
@@ -57,19 +57,14 @@ func readUntilError(r io.Reader, w io.Writer) error {
 			continue
 		}
 
-		addr, err := chipper.ToAddr3(instr.Operands)
-		if err != nil {
-			return fmt.Errorf("could not parse addr3: %w", err)
-		}
-
 		const offset = 0x200
 
 		fmt.Fprintf(
 			w,
-			"%0#4x) %s \t=> %0#3x \t| %+v\n",
-			cnt+offset, instr.Op,
-			addr,
-			instr.Operands,
+			"%0#4x) %s \t| %s\n",
+			cnt+offset,
+			chipper.Disassemble(uint16(p[0])<<8|uint16(p[1])),
+			instr.Op,
 		)
 
 		cnt += bytesRead
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/keymap"
+	"github.com/aalbacetef/chipper/terminal"
+)
+
+// frontend is how the emulator is shown and played.
+type frontend struct {
+	keys      chipper.KeyInputSource
+	stop      <-chan struct{}
+	afterTick func(emu *chipper.Emulator)
+	frame     chipper.FrameFunc // may be nil.
+	close     func()
+}
+
+// debugFrontend dumps the emulator state after every instruction, with no
+// keyboard input.
+func debugFrontend(stop <-chan struct{}) *frontend {
+	return &frontend{
+		keys:      &chipper.StubKeyInputSource{},
+		stop:      stop,
+		afterTick: chipper.DumpEmu,
+		close:     func() {},
+	}
+}
+
+// terminalFrontend reads the keypad from stdin in raw mode and draws every
+// presented frame to stdout. Ctrl-C stops it.
+func terminalFrontend(
+	stop <-chan struct{},
+	km keymap.Keymap,
+	mode terminal.Mode,
+	persist chipper.Persistence,
+	keyTimeout time.Duration,
+) (*frontend, error) {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return nil, errors.New("stdin is not a terminal")
+	}
+
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := terminal.NewKeys(os.Stdin, km)
+	keys.Timeout = keyTimeout
+
+	renderer := terminal.NewRenderer(os.Stdout, mode)
+
+	merged := make(chan struct{})
+
+	go func() {
+		select {
+		case <-stop:
+		case <-keys.Quit():
+		}
+
+		close(merged)
+	}()
+
+	return &frontend{
+		keys:      keys,
+		stop:      merged,
+		afterTick: func(_ *chipper.Emulator) {},
+		frame: func(emu *chipper.Emulator) {
+			_ = renderer.Render(emu.Frame().Bitmap(persist))
+		},
+		close: func() {
+			_ = renderer.Close()
+			_ = terminal.Restore(fd, state)
+		},
+	}, nil
+}
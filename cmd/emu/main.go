@@ -2,30 +2,75 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"time"
 
 	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/audio"
+	"github.com/aalbacetef/chipper/capture"
+	"github.com/aalbacetef/chipper/internal/cli"
+	"github.com/aalbacetef/chipper/keymap"
+	"github.com/aalbacetef/chipper/keyscript"
+	"github.com/aalbacetef/chipper/romdb"
+	"github.com/aalbacetef/chipper/terminal"
 )
 
 const (
-	ramSize = 4096
-	w       = 64
-	h       = 32
+	// termDelay runs the terminal frontend at 10 instructions per frame.
+	termDelay = time.Second / 60 / 10
 )
 
 func main() {
 	fname := ""
 	delayms := 500
 	stackSize := 16
+	useDB := true
+	machine := cli.Machine{}
+	maxFrames := 0
+	screenshot := ""
+	gifPath := ""
+	y4mPath := ""
+	aviPath := ""
+	wavPath := ""
+	palette := ""
+	filters := ""
+	capOpts := capture.DefaultOptions()
+	useTerm := false
+	renderMode := "halfblock"
+	persistence := "none"
+	keyTimeout := terminal.DefaultKeyTimeout
+	keymapPath := ""
+	scriptPath := ""
+	layout := ""
 
 	flag.StringVar(&fname, "name", fname, "name of rom (path)")
 	flag.IntVar(&delayms, "delay", delayms, "delay in ms")
 	flag.IntVar(&stackSize, "stack", stackSize, "stack size")
+	flag.BoolVar(&useDB, "romdb", useDB, "look the ROM up in the ROM database and apply its settings")
+	machine.AddFlags(flag.CommandLine)
+	flag.IntVar(&maxFrames, "frames", maxFrames, "stop after this many frames, 0 runs until the ROM ends or is interrupted")
+	flag.StringVar(&screenshot, "screenshot", screenshot, "write a PNG of the display to this path on exit")
+	flag.StringVar(&gifPath, "record-gif", gifPath, "record the display as an animated GIF to this path")
+	flag.StringVar(&y4mPath, "record-y4m", y4mPath, "record the display as an uncompressed YUV4MPEG2 video to this path")
+	flag.StringVar(&aviPath, "record-avi", aviPath, "record the display as a Motion JPEG AVI video to this path")
+	flag.StringVar(&wavPath, "record-wav", wavPath, "record the buzzer as a WAV file to this path")
+	flag.StringVar(&palette, "palette", palette, "colors from clear to set pixels in recordings, e.g. 000000,ffffff, or one of: amber, gameboy, green, paper")
+	flag.StringVar(&filters, "filters", filters, "post-processing of recordings, e.g. phosphor=0.7,hq2x,scale=2,scanlines")
+	flag.IntVar(&capOpts.Scale, "scale", capOpts.Scale, "pixel scale of screenshots and recordings")
+	flag.IntVar(&capOpts.FrameSkip, "frame-skip", capOpts.FrameSkip, "frames to drop after each recorded frame")
+	flag.BoolVar(&useTerm, "term", useTerm, "play in the terminal, Ctrl-C quits")
+	flag.StringVar(&renderMode, "render", renderMode, "terminal rendering: halfblock or braille")
+	flag.DurationVar(&keyTimeout, "key-timeout", keyTimeout, "how long a key stays pressed in the terminal")
+	flag.StringVar(&scriptPath, "script", scriptPath, "play the keypad from this script instead of the keyboard, see the keyscript package")
+	flag.StringVar(&keymapPath, "keymap", keymapPath, "keymap file for the terminal, see the keymap package")
+	flag.StringVar(&layout, "layout", layout, "keyboard layout for the terminal: azerty, dvorak, qwerty or qwertz")
+	flag.StringVar(&persistence, "persistence", persistence, "combine the last two frames in the terminal to hide flicker: none, or or blend")
 
 	flag.Parse()
 
@@ -35,7 +80,39 @@ func main() {
 		return
 	}
 
-	delay := time.Duration(delayms) * time.Millisecond
+	if palette != "" {
+		p, err := capture.ParsePalette(palette)
+		if err != nil {
+			fmt.Println(err)
+
+			return
+		}
+
+		capOpts.Palette = p
+	}
+
+	f, err := capture.ParseFilters(filters)
+	if err != nil {
+		fmt.Println(err)
+
+		return
+	}
+
+	capOpts.Filters = f
+
+	mode, err := terminal.ParseMode(renderMode)
+	if err != nil {
+		fmt.Println(err)
+
+		return
+	}
+
+	persist, err := chipper.ParsePersistence(persistence)
+	if err != nil {
+		fmt.Println(err)
+
+		return
+	}
 
 	data, err := os.ReadFile(fname)
 	if err != nil {
@@ -46,30 +123,319 @@ func main() {
 
 	r := bytes.NewReader(data)
 
-	emu, err := mkEmu(stackSize, ramSize, w, h)
+	// the ROM database picks the machine unless -platform does, and the
+	// keypad controls either way.
+	cfg := chipper.DefaultConfig()
+	fromDB := false
+
+	var controls map[string]int
+
+	if useDB {
+		if entry, ok := lookupROM(data); ok {
+			controls = entry.ROM.Keys
+
+			if machine.Platform == nil {
+				cfg = entry.Config()
+				fromDB = true
+			}
+		}
+	}
+
+	cfg = machine.Config(cfg)
+	if isFlagSet("stack") {
+		cfg.StackSize = stackSize
+	}
+
+	delay := time.Duration(delayms) * time.Millisecond
+
+	switch {
+	case isFlagSet("delay"):
+	case machine.Platform != nil || fromDB:
+		delay = tickRateDelay(cfg.TickRate)
+	case useTerm:
+		delay = termDelay
+	}
+
+	km, err := loadKeymap(keymapPath, layout, data, controls)
+	if err != nil {
+		fmt.Println(err)
+
+		return
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	fe := debugFrontend(ctx.Done())
+	if useTerm {
+		fe, err = terminalFrontend(ctx.Done(), km, mode, persist, keyTimeout)
+		if err != nil {
+			fmt.Println("could not start terminal frontend: ", err)
+
+			return
+		}
+	}
+
+	var player *keyscript.Player
+	if scriptPath != "" {
+		script, err := keyscript.ParseFile(scriptPath)
+		if err != nil {
+			fe.close()
+			fmt.Println(err)
+
+			return
+		}
+
+		player = keyscript.NewPlayer(script)
+		fe.keys = player
+	}
+
+	emu, err := mkEmu(cfg, fe.keys)
+	if err != nil {
+		fe.close()
+		fmt.Println(err)
+
+		return
+	}
+
+	if player != nil {
+		player.Attach(emu)
+	}
+
+	if fe.frame != nil {
+		emu.AddFrameFunc(fe.frame)
+	}
+
+	var rec *capture.Recorder
+	if gifPath != "" {
+		rec = capture.NewRecorder(emu.Display, capOpts)
+		emu.AddFrameFunc(rec.Frame)
+	}
+
+	streams, err := openStreams(emu, streamPaths{y4m: y4mPath, avi: aviPath, wav: wavPath}, capOpts, filters)
 	if err != nil {
+		fe.close()
 		fmt.Println(err)
 
 		return
 	}
 
-	if err := runUntilError(r, emu, delay); err != nil {
+	err = runUntilError(r, emu, delay, maxFrames, fe)
+	fe.close()
+
+	if err != nil {
+		fmt.Println("error: ", err)
+	}
+
+	for _, s := range streams {
+		if err := s.close(); err != nil {
+			fmt.Println("error: ", err)
+		}
+	}
+
+	if err := saveCaptures(emu, rec, screenshot, gifPath, capOpts); err != nil {
 		fmt.Println("error: ", err)
 	}
 }
 
-func mkEmu(stackSize, ramSize, w, h int) (*chipper.Emulator, error) {
-	display, err := chipper.NewDebugDisplay(w, h)
+func saveCaptures(emu *chipper.Emulator, rec *capture.Recorder, screenshot, gifPath string, opts capture.Options) error {
+	if screenshot != "" {
+		if err := writeFile(screenshot, func(w io.Writer) error {
+			return capture.WritePNG(w, emu.Display, opts)
+		}); err != nil {
+			return fmt.Errorf("could not save screenshot: %w", err)
+		}
+	}
+
+	if rec != nil {
+		if err := writeFile(gifPath, rec.WriteGIF); err != nil {
+			return fmt.Errorf("could not save recording: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// stream is a recording written to a file while the emulator runs.
+type stream struct {
+	path   string
+	fd     *os.File
+	finish func() error // completes the recording, before the file is closed.
+}
+
+func (s stream) close() error {
+	if err := s.finish(); err != nil {
+		s.fd.Close()
+
+		return fmt.Errorf("could not save recording '%s': %w", s.path, err)
+	}
+
+	return s.fd.Close()
+}
+
+// streamPaths are the files to record videos and sound to, if not empty.
+type streamPaths struct {
+	y4m string
+	avi string
+	wav string
+}
+
+// openStreams creates the video and sound recordings asked for, registering
+// them with the emulator.
+func openStreams(emu *chipper.Emulator, paths streamPaths, opts capture.Options, filters string) ([]stream, error) {
+	type recorder interface {
+		Frame(emu *chipper.Emulator)
+		Close() error
+	}
+
+	newVideo := func(mk func(fd *os.File, opts capture.Options) recorder) func(fd *os.File) recorder {
+		return func(fd *os.File) recorder {
+			// filters may keep state, so each video gets its own. They were
+			// already checked by main.
+			opts := opts
+			opts.Filters, _ = capture.ParseFilters(filters)
+
+			return mk(fd, opts)
+		}
+	}
+
+	kinds := []struct {
+		path string
+		mk   func(fd *os.File) recorder
+	}{
+		{paths.y4m, newVideo(func(fd *os.File, opts capture.Options) recorder {
+			return capture.NewY4MWriter(fd, emu.Display, opts)
+		})},
+		{paths.avi, newVideo(func(fd *os.File, opts capture.Options) recorder {
+			return capture.NewAVIWriter(fd, emu.Display, opts, capture.DefaultJPEGQuality)
+		})},
+		{paths.wav, func(fd *os.File) recorder {
+			return newSoundRecorder(fd)
+		}},
+	}
+
+	streams := make([]stream, 0, len(kinds))
+
+	for _, kind := range kinds {
+		if kind.path == "" {
+			continue
+		}
+
+		fd, err := os.Create(kind.path)
+		if err != nil {
+			for _, s := range streams {
+				s.fd.Close()
+			}
+
+			return nil, fmt.Errorf("could not create recording: %w", err)
+		}
+
+		rec := kind.mk(fd)
+		emu.AddFrameFunc(rec.Frame)
+		streams = append(streams, stream{path: kind.path, fd: fd, finish: rec.Close})
+	}
+
+	return streams, nil
+}
+
+// soundRecorder writes the buzzer to a WAV file.
+type soundRecorder struct {
+	*audio.Beeper
+	wav *audio.WAVWriter
+}
+
+func newSoundRecorder(w io.WriteSeeker) soundRecorder {
+	wav := audio.NewWAVWriter(w, audio.DefaultSampleRate)
+
+	return soundRecorder{Beeper: audio.NewBeeper(wav), wav: wav}
+}
+
+func (s soundRecorder) Close() error {
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	return s.wav.Close()
+}
+
+func writeFile(path string, write func(w io.Writer) error) error {
+	fd, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(fd); err != nil {
+		fd.Close()
+
+		return err
+	}
+
+	return fd.Close()
+}
+
+// loadKeymap returns the keymap for rom from the keymap file at path, if any,
+// with its layout replaced by layout if not empty and the ROM database's
+// controls.
+func loadKeymap(path, layout string, rom []byte, controls map[string]int) (keymap.Keymap, error) {
+	f := &keymap.File{}
+
+	if path != "" {
+		var err error
+
+		f, err = keymap.LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if layout != "" {
+		f.Layout = layout
+	}
+
+	return f.KeymapWithControls(rom, controls)
+}
+
+// lookupROM looks the ROM up in the database, saying what it found.
+func lookupROM(data []byte) (romdb.Entry, bool) {
+	entry, ok := romdb.Lookup(data)
+	if !ok {
+		fmt.Println("ROM not found in database, using defaults")
+
+		return romdb.Entry{}, false
+	}
+
+	fmt.Println("identified ROM: ", entry)
+
+	return entry, true
+}
+
+// tickRateDelay returns the delay between instructions that runs tickRate
+// instructions per 60Hz frame.
+func tickRateDelay(tickRate int) time.Duration {
+	const frame = time.Second / 60
+
+	return frame / time.Duration(max(tickRate, 1))
+}
+
+func isFlagSet(name string) bool {
+	found := false
+
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+
+	return found
+}
+
+func mkEmu(cfg chipper.Config, keys chipper.KeyInputSource) (*chipper.Emulator, error) {
+	display, err := chipper.NewDebugDisplay(cfg.Width, cfg.Height)
 	if err != nil {
 		return nil, fmt.Errorf("error: %w", err)
 	}
 
-	emu, err := chipper.NewEmulator(
-		stackSize,
-		ramSize,
-		display,
-		&chipper.StubKeyInputSource{},
-	)
+	emu, err := chipper.NewEmulatorWithConfig(cfg, display, keys)
 	if err != nil {
 		return nil, fmt.Errorf("error creating emulator: %w", err)
 	}
@@ -77,12 +443,27 @@ func mkEmu(stackSize, ramSize, w, h int) (*chipper.Emulator, error) {
 	return emu, nil
 }
 
-func runUntilError(r io.Reader, emu *chipper.Emulator, delay time.Duration) error {
+// runUntilError runs the ROM until it ends, fails, maxFrames frames have
+// passed (if positive) or the frontend stops it.
+func runUntilError(r io.Reader, emu *chipper.Emulator, delay time.Duration, maxFrames int, fe *frontend) error {
 	if err := emu.Load(r); err != nil {
 		return fmt.Errorf("could not load ROM: %w", err)
 	}
 
+	frames := 0
+	emu.AddFrameFunc(func(_ *chipper.Emulator) { frames++ })
+
 	for {
+		select {
+		case <-fe.stop:
+			return nil
+		default:
+		}
+
+		if maxFrames > 0 && frames >= maxFrames {
+			return nil
+		}
+
 		err := emu.Tick()
 		if errors.Is(err, io.EOF) {
 			return nil
@@ -93,6 +474,6 @@ func runUntilError(r io.Reader, emu *chipper.Emulator, delay time.Duration) erro
 		}
 
 		time.Sleep(delay)
-		chipper.DumpEmu(emu)
+		fe.afterTick(emu)
 	}
 }
@@ -7,25 +7,55 @@ import (
 	"sync"
 
 	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/keymap"
 )
 
+// WebKeyInputSource is the keypad of the web UI. Keys are set from the
+// JavaScript callbacks while the Controller reads them, so mu guards it all.
 type WebKeyInputSource struct {
-	keys     [16]bool
+	keys     [chipper.NumKeys]bool
 	mu       sync.Mutex
 	listener chan int
+	keymap   keymap.Keymap
+	latch    chipper.KeyLatch // answers FX0A.
 }
 
 func NewWebKeyInputSource() *WebKeyInputSource {
-	return &WebKeyInputSource{}
+	return &WebKeyInputSource{keymap: keymap.Default()}
+}
+
+// SetKeymap sets the keymap used by SetHost.
+func (ksrc *WebKeyInputSource) SetKeymap(km keymap.Keymap) {
+	ksrc.mu.Lock()
+	defer ksrc.mu.Unlock()
+
+	ksrc.keymap = km
+}
+
+// SetHost presses or releases the keypad key mapped to the host key name, as
+// given by KeyboardEvent.key. It returns the keypad key, or -1 if the host key
+// is not mapped.
+func (ksrc *WebKeyInputSource) SetHost(name string, isPressed bool) int {
+	ksrc.mu.Lock()
+	key, ok := ksrc.keymap.Lookup(name)
+	ksrc.mu.Unlock()
+
+	if !ok {
+		return -1
+	}
+
+	ksrc.Set(key, isPressed)
+
+	return key
 }
 
 func (ksrc *WebKeyInputSource) Set(key int, isPressed bool) {
 	ksrc.mu.Lock()
 	defer ksrc.mu.Unlock()
 
-	if key < 0 || key > chipper.NumKeys {
+	if key < 0 || key >= chipper.NumKeys {
 		fmt.Printf(
-			"key %d out of bounds [%d, %d]\n",
+			"key %d out of bounds [%d, %d)\n",
 			key,
 			0, chipper.NumKeys,
 		)
@@ -33,10 +63,13 @@ func (ksrc *WebKeyInputSource) Set(key int, isPressed bool) {
 		return
 	}
 
-	if isPressed && ksrc.listener != nil {
-		fmt.Println("set key listener")
-		ksrc.listener <- key
-		ksrc.listener = nil
+	if isPressed && !ksrc.keys[key] {
+		ksrc.latch.Press(key)
+
+		if ksrc.listener != nil {
+			ksrc.listener <- key
+			ksrc.listener = nil
+		}
 	}
 
 	ksrc.keys[key] = isPressed
@@ -46,9 +79,9 @@ func (ksrc *WebKeyInputSource) Get(key int) bool {
 	ksrc.mu.Lock()
 	defer ksrc.mu.Unlock()
 
-	if key < 0 || key > chipper.NumKeys {
+	if key < 0 || key >= chipper.NumKeys {
 		fmt.Printf(
-			"key %d out of bounds [%d, %d]\n",
+			"key %d out of bounds [%d, %d)\n",
 			key,
 			0, chipper.NumKeys,
 		)
@@ -60,6 +93,24 @@ func (ksrc *WebKeyInputSource) Get(key int) bool {
 	return v
 }
 
+// PollKeypress makes WebKeyInputSource a chipper.KeyPoller, so FX0A does not
+// block the Controller.
+func (ksrc *WebKeyInputSource) PollKeypress() (int, bool) {
+	ksrc.mu.Lock()
+	defer ksrc.mu.Unlock()
+
+	return ksrc.latch.Poll()
+}
+
+// ResetKeypress drops the key FX0A was waiting for, when the emulator is
+// reset.
+func (ksrc *WebKeyInputSource) ResetKeypress() {
+	ksrc.mu.Lock()
+	defer ksrc.mu.Unlock()
+
+	ksrc.latch.Reset()
+}
+
 func (ksrc *WebKeyInputSource) WaitUntilKeypress() <-chan int {
 	l := make(chan int, 1)
 
@@ -13,23 +13,15 @@ import (
 )
 
 func main() {
-	const (
-		stackSize         = 16
-		delay             = 16
-		RAMSize           = 4*1024 + 1
-		w                 = 64
-		h                 = 32
-		defaultTickPeriod = 2 * time.Millisecond
-	)
-
-	// NOTE: When the UI changes this, it should first STOP the emulator, update
-	// the tickPeriod, then Start the emulator.
-	tickPeriod := defaultTickPeriod
+	const defaultTickPeriod = 2 * time.Millisecond
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	wrapper, err := NewWrapper(w, h, stackSize, RAMSize)
+	wrapper, err := NewWrapper(ctx, chipper.ControllerOptions{
+		Config: chipper.DefaultConfig(),
+		IPF:    periodToIPF(defaultTickPeriod),
+	})
 	if err != nil {
 		fmt.Println("error: ", err)
 		return
@@ -78,13 +70,43 @@ func main() {
 		v := chipper.Direction(dir) == chipper.Down
 		fmt.Printf("[main.go] key: %#0x || %t\n", key, v)
 
-		wrapper.keySrc.Set(key, v)
+		wrapper.setKey(key, v)
+
+		return 0
+	})
+
+	handleHostKey := js.FuncOf(func(this js.Value, args []js.Value) any {
+		const wantLen = 3
+		n := len(args)
+
+		if n != wantLen {
+			fmt.Printf("want %d args, got %d", wantLen, n)
+			return -1
+		}
+
+		name := args[0].String()
+		dir := args[2].Int()
+
+		return wrapper.setHostKey(name, chipper.Direction(dir) == chipper.Down)
+	})
+
+	loadKeymapFn := js.FuncOf(func(this js.Value, args []js.Value) any {
+		m, n := 1, len(args)
+		if n != m {
+			fmt.Printf("expected args to have %d elements, got %d\n", m, n)
+			return 1
+		}
+
+		if err := wrapper.loadKeymap(args[0].String()); err != nil {
+			fmt.Println("error: ", err)
+			return 1
+		}
 
 		return 0
 	})
 
 	startFn := js.FuncOf(func(this js.Value, args []js.Value) any {
-		wrapper.start(ctx, tickPeriod)
+		wrapper.start()
 		return 0
 	})
 
@@ -107,20 +129,106 @@ func main() {
 
 		period := time.Millisecond * time.Duration(args[0].Int())
 
-		wrapper.stop()
-		tickPeriod = period
-		wrapper.start(ctx, tickPeriod)
+		wrapper.setSpeed(periodToIPF(period))
+
+		return 0
+	})
+
+	persistenceFn := js.FuncOf(func(this js.Value, args []js.Value) any {
+		m, n := 1, len(args)
+		if n != m {
+			fmt.Printf("expected args to have %d elements, got %d\n", m, n)
+			return 1
+		}
+
+		p, err := chipper.ParsePersistence(args[0].String())
+		if err != nil {
+			fmt.Println("error: ", err)
+			return 1
+		}
+
+		wrapper.setPersistence(p)
 
 		return 0
 	})
 
+	setPlatformFn := js.FuncOf(func(this js.Value, args []js.Value) any {
+		m, n := 1, len(args)
+		if n != m {
+			fmt.Printf("expected args to have %d elements, got %d\n", m, n)
+			return 1
+		}
+
+		if err := wrapper.setPlatform(args[0].String()); err != nil {
+			fmt.Println("error: ", err)
+			return 1
+		}
+
+		return 0
+	})
+
+	setFontFn := js.FuncOf(func(this js.Value, args []js.Value) any {
+		m, n := 1, len(args)
+		if n != m {
+			fmt.Printf("expected args to have %d elements, got %d\n", m, n)
+			return 1
+		}
+
+		if err := wrapper.setFont(args[0].String()); err != nil {
+			fmt.Println("error: ", err)
+			return 1
+		}
+
+		return 0
+	})
+
+	loadFontFn := js.FuncOf(func(this js.Value, args []js.Value) any {
+		m, n := 1, len(args)
+		if n != m {
+			fmt.Printf("expected args to have %d elements, got %d\n", m, n)
+			return 1
+		}
+
+		if err := wrapper.loadFont(args[0].String()); err != nil {
+			fmt.Println("error: ", err)
+			return 1
+		}
+
+		return 0
+	})
+
+	displaySizeFn := js.FuncOf(func(this js.Value, args []js.Value) any {
+		w, h := wrapper.displaySize()
+
+		return []any{w, h}
+	})
+
 	js.Global().Set("RestartEmu", restartFn)
 	js.Global().Set("StartEmu", startFn)
 	js.Global().Set("StopEmu", stopFn)
 	js.Global().Set("LoadROM", loadROMFn)
 	js.Global().Set("GetDisplay", sendDisplayToWASM)
 	js.Global().Set("SendKeyboardEvent", handleKeyPress)
+	js.Global().Set("SendHostKeyEvent", handleHostKey)
+	js.Global().Set("LoadKeymap", loadKeymapFn)
 	js.Global().Set("SetTickPeriod", tickerPeriodFn)
+	js.Global().Set("SetPersistence", persistenceFn)
+	js.Global().Set("SetPlatform", setPlatformFn)
+	js.Global().Set("GetDisplaySize", displaySizeFn)
+	js.Global().Set("SetFont", setFontFn)
+	js.Global().Set("LoadFont", loadFontFn)
 
 	select {}
 }
+
+// periodToIPF converts the time between instructions used by the UI to
+// instructions per 60Hz frame.
+func periodToIPF(period time.Duration) int {
+	const frame = time.Second / 60
+
+	if period <= 0 {
+		return 1
+	}
+
+	return max(int(frame/period), 1)
+}
@@ -3,150 +3,353 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"errors"
 	"fmt"
-	"io"
+	"strings"
 	"sync"
 	"syscall/js"
-	"time"
 
 	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/keymap"
+	"github.com/aalbacetef/chipper/romdb"
 )
 
-func NewWrapper(w, h, stackSize, RAMSize int) (*WASMWrapper, error) {
-	wrapper := &WASMWrapper{}
-	wrapper.settings = Settings{
-		stackSize: stackSize,
-		ramSize:   RAMSize,
-		w:         w,
-		h:         h,
+func NewWrapper(ctx context.Context, opts chipper.ControllerOptions) (*WASMWrapper, error) {
+	keys := NewWebKeyInputSource()
+	opts.Keys = keys
+
+	ctrl, err := chipper.NewController(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize: %w", err)
 	}
 
-	if err := wrapper.init(); err != nil {
+	cfg, err := ctrl.Config()
+	if err != nil {
 		return nil, fmt.Errorf("could not initialize: %w", err)
 	}
 
-	return wrapper, nil
-}
+	wrapper := &WASMWrapper{
+		ctrl: ctrl,
+		keys: keys,
+		w:    cfg.Width,
+		h:    cfg.Height,
+		ipf:  opts.IPF,
+	}
 
-type Settings struct {
-	stackSize int
-	ramSize   int
-	w         int
-	h         int
+	return wrapper, nil
 }
 
+// WASMWrapper exposes a chipper.Controller to JavaScript. The Controller
+// serialises everything touching the emulator, mu guards the rest.
 type WASMWrapper struct {
-	settings   Settings
-	emu        *chipper.Emulator
-	d          *Display
-	keySrc     chipper.KeyInputSource
-	cancelFunc context.CancelFunc
+	ctrl *chipper.Controller
+	keys *WebKeyInputSource
+
 	mu         sync.Mutex
+	keymapFile *keymap.File
+	rom        []byte
+	w, h       int
+
+	// controls are the keypad controls the ROM database lists for rom.
+	controls map[string]int
+
+	// ipf is the speed set from the UI, used for ROMs the database does not
+	// know.
+	ipf int
+
+	// platform is the machine picked with setPlatform. Until one is, ROMs
+	// run on the default machine with the ROM database's settings.
+	platform *chipper.Config
+
+	// font is the font picked with setFont or loadFont, which replaces the
+	// platform's if not nil.
+	font *chipper.Font
+
+	// data holds the last frame sent to JavaScript, one byte per pixel.
+	data        []byte
+	lastFrame   uint64
+	lastErr     error
+	persistence chipper.Persistence
 }
 
+// loadROM loads the ROM into the emulator, on the platform picked in the UI
+// if any. Otherwise, it runs on the machine the ROM database gives if the ROM
+// is known, or the default one at the speed set from the UI. The machine is
+// configured before the ROM is loaded, and the database's keypad controls
+// applied after.
 func (wrapper *WASMWrapper) loadROM(buf js.Value, lenBytes int) error {
 	romFile := make([]byte, lenBytes)
 	js.CopyBytesToGo(romFile, buf)
 
-	r := bytes.NewReader(romFile)
-	if err := wrapper.emu.Load(r); err != nil {
+	wrapper.mu.Lock()
+	ipf := wrapper.ipf
+	platform := wrapper.platform
+	font := wrapper.font
+	wrapper.mu.Unlock()
+
+	cfg := chipper.DefaultConfig()
+
+	entry, known := romdb.Lookup(romFile)
+	if known {
+		fmt.Println("identified ROM: ", entry)
+	}
+
+	switch {
+	case platform != nil:
+		cfg = *platform
+	case known:
+		cfg = entry.Config()
+		ipf = cfg.TickRate
+	}
+
+	if font != nil {
+		cfg.Font = font
+	}
+
+	if err := wrapper.ctrl.Configure(cfg, ipf); err != nil {
+		return fmt.Errorf("could not configure emulator: %w", err)
+	}
+
+	if err := wrapper.ctrl.Load(romFile); err != nil {
 		return fmt.Errorf("could not load rom: %w", err)
 	}
 
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+
+	wrapper.rom = romFile
+	wrapper.controls = entry.ROM.Keys
+	wrapper.w, wrapper.h = cfg.Width, cfg.Height
+	wrapper.lastFrame = 0
+
+	return wrapper.applyKeymap()
+}
+
+// setPlatform switches the emulator to the preset with the given ID, at its
+// tick rate, restarting the loaded ROM on it.
+func (wrapper *WASMWrapper) setPlatform(id string) error {
+	cfg, ok := chipper.PresetByName(id)
+	if !ok {
+		return fmt.Errorf("unknown platform '%s'", id)
+	}
+
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+
+	machine := cfg
+	if wrapper.font != nil {
+		machine.Font = wrapper.font
+	}
+
+	if err := wrapper.ctrl.Configure(machine, cfg.TickRate); err != nil {
+		return fmt.Errorf("could not set platform: %w", err)
+	}
+
+	wrapper.platform = &cfg
+	wrapper.ipf = cfg.TickRate
+	wrapper.w, wrapper.h = cfg.Width, cfg.Height
+	wrapper.lastFrame = 0
+
 	return nil
 }
 
-func (wrapper *WASMWrapper) sendDisplayToWASM(ptr js.Value) int {
-	wrapper.d.mu.Lock()
-	bytesCopied := js.CopyBytesToJS(ptr, wrapper.d.data)
-	wrapper.d.mu.Unlock()
+// setFont switches the emulator to the built-in font with the given ID,
+// restarting the loaded ROM with it.
+func (wrapper *WASMWrapper) setFont(id string) error {
+	font, ok := chipper.FontByName(id)
+	if !ok {
+		return fmt.Errorf("unknown font '%s'", id)
+	}
 
-	return bytesCopied
+	return wrapper.useFont(font)
 }
 
-func (wrapper *WASMWrapper) start(mainCtx context.Context, period time.Duration) {
-	wrapper.mu.Lock()
-	if wrapper.cancelFunc != nil {
-		wrapper.cancelFunc()
+// loadFont reads a font in the JSON format of chipper.LoadFont and switches
+// the emulator to it, restarting the loaded ROM with it.
+func (wrapper *WASMWrapper) loadFont(data string) error {
+	font, err := chipper.LoadFont(strings.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	return wrapper.useFont(font)
+}
+
+func (wrapper *WASMWrapper) useFont(font *chipper.Font) error {
+	cfg, err := wrapper.ctrl.Config()
+	if err != nil {
+		return fmt.Errorf("could not set font: %w", err)
+	}
+
+	state, err := wrapper.ctrl.State()
+	if err != nil {
+		return fmt.Errorf("could not set font: %w", err)
 	}
 
-	ctx, cancel := context.WithCancel(mainCtx)
-	wrapper.cancelFunc = cancel
+	cfg.Font = font
+
+	if err := wrapper.ctrl.Configure(cfg, state.IPF); err != nil {
+		return fmt.Errorf("could not set font: %w", err)
+	}
+
+	wrapper.mu.Lock()
+	wrapper.font = font
+	wrapper.lastFrame = 0
 	wrapper.mu.Unlock()
 
-	ticker := time.NewTicker(period)
+	return nil
+}
 
-	go func() {
-		defer ticker.Stop()
+// displaySize returns the size of the display GetDisplay copies, which
+// changes with the platform.
+func (wrapper *WASMWrapper) displaySize() (int, int) {
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
 
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				err := wrapper.emu.Tick()
-				if errors.Is(err, io.EOF) {
-					fmt.Println("end of file, exiting")
-					return
-				}
+	return wrapper.w, wrapper.h
+}
 
-				if err != nil {
-					pc := wrapper.emu.PC
-					indx := wrapper.emu.Index
-					last := wrapper.emu.LastInstruction
+// loadKeymap reads a keymap file, see the keymap package, and applies it to
+// the loaded ROM.
+func (wrapper *WASMWrapper) loadKeymap(data string) error {
+	f, err := keymap.Load(strings.NewReader(data))
+	if err != nil {
+		return err
+	}
 
-					fmt.Println("error: ", err)
-					fmt.Printf("PC: %#0x | (%d) \n", pc, pc)
-					fmt.Printf("Index: %#0x\n", indx)
-					fmt.Println("last instruction: ", last)
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+
+	wrapper.keymapFile = f
+
+	return wrapper.applyKeymap()
+}
+
+// applyKeymap must be called with mu held.
+func (wrapper *WASMWrapper) applyKeymap() error {
+	f := wrapper.keymapFile
+	if f == nil {
+		f = &keymap.File{}
+	}
+
+	km, err := f.KeymapWithControls(wrapper.rom, wrapper.controls)
+	if err != nil {
+		return fmt.Errorf("could not apply keymap: %w", err)
+	}
+
+	wrapper.keys.SetKeymap(km)
+
+	return nil
+}
+
+func (wrapper *WASMWrapper) setKey(key int, isPressed bool) {
+	wrapper.keys.Set(key, isPressed)
+}
+
+// setHostKey presses or releases the keypad key mapped to the host key name,
+// as given by KeyboardEvent.key. It returns the keypad key, or -1 if the host
+// key is not mapped.
+func (wrapper *WASMWrapper) setHostKey(name string, isPressed bool) int {
+	return wrapper.keys.SetHost(name, isPressed)
+}
+
+// sendDisplayToWASM copies the last presented frame into ptr, so JavaScript
+// never sees a sprite half erased. The bytes are only recomputed when a new
+// frame has been presented.
+func (wrapper *WASMWrapper) sendDisplayToWASM(ptr js.Value) int {
+	frame, err := wrapper.ctrl.Frame()
+	if err != nil {
+		fmt.Println("error: ", err)
+
+		return 0
+	}
 
-					return
-				}
+	wrapper.reportError()
 
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+
+	w, h := wrapper.w, wrapper.h
+	if n := w * h; len(wrapper.data) != n {
+		wrapper.data = make([]byte, n)
+		wrapper.lastFrame = 0
+	}
+
+	if frame.Number != wrapper.lastFrame || frame.Number == 0 {
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				wrapper.data[x+y*w] = frame.Index(x, y, wrapper.persistence)
 			}
 		}
-	}()
+
+		wrapper.lastFrame = frame.Number
+	}
+
+	return js.CopyBytesToJS(ptr, wrapper.data)
 }
 
-func (wrapper *WASMWrapper) stop() {
+// reportError prints the error that stopped the emulator, once.
+func (wrapper *WASMWrapper) reportError() {
+	state, err := wrapper.ctrl.State()
+	if err != nil || state.Err == nil {
+		return
+	}
+
 	wrapper.mu.Lock()
 	defer wrapper.mu.Unlock()
 
-	if wrapper.cancelFunc != nil {
-		wrapper.cancelFunc()
+	if state.Err == wrapper.lastErr { //nolint:errorlint
+		return
 	}
-}
 
-func (wrapper *WASMWrapper) restart() {
-	wrapper.stop()
+	wrapper.lastErr = state.Err
 
+	fmt.Println("error: ", state.Err)
+	fmt.Printf("PC: %#0x | (%d) \n", state.PC, state.PC)
+	fmt.Printf("Index: %#0x\n", state.Index)
+}
+
+func (wrapper *WASMWrapper) setPersistence(p chipper.Persistence) {
 	wrapper.mu.Lock()
-	wrapper.init()
-	wrapper.mu.Unlock()
+	defer wrapper.mu.Unlock()
 
-	return
+	wrapper.persistence = p
+	wrapper.lastFrame = 0
 }
 
-func (wrapper *WASMWrapper) init() error {
-	w := wrapper.settings.w
-	h := wrapper.settings.h
-	stackSize := wrapper.settings.stackSize
-	ramSize := wrapper.settings.ramSize
+func (wrapper *WASMWrapper) start() {
+	if err := wrapper.ctrl.Resume(); err != nil {
+		fmt.Println("error: ", err)
+	}
+}
 
-	d := NewDisplay(w, h)
-	keySrc := NewWebKeyInputSource()
+func (wrapper *WASMWrapper) stop() {
+	if err := wrapper.ctrl.Pause(); err != nil {
+		fmt.Println("error: ", err)
+	}
+}
 
-	emu, err := chipper.NewEmulator(stackSize, ramSize, d, keySrc)
-	if err != nil {
-		return fmt.Errorf("could not start emulator: %w", err)
+func (wrapper *WASMWrapper) setSpeed(ipf int) {
+	if err := wrapper.ctrl.SetSpeed(ipf); err != nil {
+		fmt.Println("error: ", err)
+
+		return
 	}
 
-	wrapper.emu = emu
-	wrapper.d = d
-	wrapper.keySrc = keySrc
+	wrapper.mu.Lock()
+	wrapper.ipf = ipf
+	wrapper.mu.Unlock()
+}
 
-	return nil
+// restart stops the emulator and restarts the loaded ROM.
+func (wrapper *WASMWrapper) restart() {
+	wrapper.stop()
+
+	if err := wrapper.ctrl.Reset(); err != nil {
+		fmt.Println("error: ", err)
+	}
+
+	wrapper.mu.Lock()
+	wrapper.lastFrame = 0
+	wrapper.mu.Unlock()
 }
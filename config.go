@@ -0,0 +1,304 @@
+package chipper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Config describes a machine: its memory, display and the behaviour of its
+// instructions, so they can be picked together for a platform rather than
+// one number at a time.
+type Config struct {
+	// ID names the config for PresetByName, e.g. "vip".
+	ID   string
+	Name string
+
+	RAMSize   int
+	StackSize int
+
+	// StartAddress is where ROMs are loaded and execution starts.
+	StartAddress uint16
+
+	// FontAddress is where the hex digit font is stored, for FX29.
+	FontAddress uint16
+
+	// Font is the font stored at FontAddress, DefaultFont if nil. Configs
+	// compare equal when they share the same *Font.
+	Font *Font
+
+	Width, Height int
+
+	Quirks Quirks
+
+	// OutOfRange is what memory accesses outside of RAM do.
+	OutOfRange OutOfRange
+
+	// TickRate is the number of instructions per 60Hz frame the platform
+	// ran at, roughly.
+	TickRate int
+}
+
+const (
+	defaultRAMSize   = 4096
+	defaultStackSize = 16
+	defaultWidth     = 64
+	defaultHeight    = 32
+	defaultTickRate  = 10
+
+	// maxRAMSize is the memory addressable by I.
+	maxRAMSize = 1 << 16
+)
+
+// DefaultConfig returns the machine NewEmulator has always built: 4K of RAM,
+// a 16 level stack, ROMs at 0x200 and the font at 0 on a 64x32 display.
+func DefaultConfig() Config {
+	return Config{
+		ID:           "default",
+		Name:         "chipper",
+		RAMSize:      defaultRAMSize,
+		StackSize:    defaultStackSize,
+		StartAddress: StartAddress,
+		FontAddress:  0,
+		Font:         DefaultFont(),
+		Width:        defaultWidth,
+		Height:       defaultHeight,
+		Quirks:       DefaultQuirks(),
+		TickRate:     defaultTickRate,
+	}
+}
+
+// Presets returns configs for the best known CHIP-8 platforms. Only the low
+// resolution mode of SCHIP and XO-CHIP is emulated, so their displays are
+// 64x32. The original interpreters kept the font in ROM, outside of the
+// CHIP-8 address space, so font addresses follow what emulators settled on;
+// the glyphs are those of each platform, see Fonts. The modern
+// preset is CHIP-8 as most emulators run it today, with every quirk off.
+func Presets() []Config {
+	const (
+		vipStackSize    = 12
+		vipTickRate     = 15
+		hpTickRate      = 30
+		xoTickRate      = 1000
+		modernTickRate  = 12
+		etiStartAddress = 0x600
+		etiHeight       = 48
+		hpFontAddress   = 0x50
+	)
+
+	return []Config{
+		{
+			ID:           "vip",
+			Name:         "COSMAC VIP",
+			RAMSize:      defaultRAMSize,
+			StackSize:    vipStackSize,
+			StartAddress: StartAddress,
+			FontAddress:  0,
+			Font:         fontByName("vip"),
+			Width:        defaultWidth,
+			Height:       defaultHeight,
+			Quirks:       Quirks{VBlank: true, Logic: true},
+			OutOfRange:   OutOfRangeWrap,
+			TickRate:     vipTickRate,
+		},
+		{
+			ID:           "eti660",
+			Name:         "ETI-660",
+			RAMSize:      defaultRAMSize,
+			StackSize:    vipStackSize,
+			StartAddress: etiStartAddress,
+			FontAddress:  0,
+			Font:         fontByName("eti660"),
+			Width:        defaultWidth,
+			Height:       etiHeight,
+			Quirks:       Quirks{VBlank: true, Logic: true},
+			OutOfRange:   OutOfRangeWrap,
+			TickRate:     vipTickRate,
+		},
+		{
+			ID:           "chip48",
+			Name:         "HP48 CHIP-48",
+			RAMSize:      defaultRAMSize,
+			StackSize:    defaultStackSize,
+			StartAddress: StartAddress,
+			FontAddress:  hpFontAddress,
+			Font:         DefaultFont(),
+			Width:        defaultWidth,
+			Height:       defaultHeight,
+			Quirks:       Quirks{Shift: true, MemoryIncrementByX: true, Jump: true},
+			TickRate:     hpTickRate,
+		},
+		{
+			ID:           "schip",
+			Name:         "SUPER-CHIP 1.1",
+			RAMSize:      defaultRAMSize,
+			StackSize:    defaultStackSize,
+			StartAddress: StartAddress,
+			FontAddress:  hpFontAddress,
+			Font:         fontByName("schip"),
+			Width:        defaultWidth,
+			Height:       defaultHeight,
+			Quirks:       Quirks{Shift: true, MemoryLeaveIUnchanged: true, Jump: true},
+			TickRate:     hpTickRate,
+		},
+		{
+			ID:           "xochip",
+			Name:         "XO-CHIP",
+			RAMSize:      maxRAMSize,
+			StackSize:    defaultStackSize,
+			StartAddress: StartAddress,
+			FontAddress:  hpFontAddress,
+			Font:         fontByName("schip"),
+			Width:        defaultWidth,
+			Height:       defaultHeight,
+			Quirks:       Quirks{Wrap: true},
+			TickRate:     xoTickRate,
+		},
+		{
+			ID:           "modern",
+			Name:         "Modern CHIP-8",
+			RAMSize:      defaultRAMSize,
+			StackSize:    defaultStackSize,
+			StartAddress: StartAddress,
+			FontAddress:  hpFontAddress,
+			Font:         DefaultFont(),
+			Width:        defaultWidth,
+			Height:       defaultHeight,
+			Quirks:       Quirks{},
+			TickRate:     modernTickRate,
+		},
+	}
+}
+
+// PresetByName returns the preset with the given ID, ignoring case.
+func PresetByName(id string) (Config, bool) {
+	for _, c := range Presets() {
+		if strings.EqualFold(c.ID, id) {
+			return c, true
+		}
+	}
+
+	return Config{}, false
+}
+
+// Validate checks the config describes a machine that can run: ROMs and the
+// font must fit in RAM without overlapping, and every address must be
+// reachable by I.
+func (c Config) Validate() error {
+	var errs []error
+
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.RAMSize > 0 && c.RAMSize <= maxRAMSize, "RAM size must be in (0, %d], got %d", maxRAMSize, c.RAMSize)
+	check(c.StackSize > 0, "stack size must be > 0, got %d", c.StackSize)
+	check(c.Width > 0 && c.Height > 0, "display size must be > 0, got %dx%d", c.Width, c.Height)
+	check(
+		c.OutOfRange >= OutOfRangeFault && c.OutOfRange <= OutOfRangeIgnore,
+		"unknown out of range policy %s", c.OutOfRange,
+	)
+	check(c.TickRate >= 0, "tick rate must be >= 0, got %d", c.TickRate)
+	check(
+		int(c.StartAddress)+InstructionSize <= c.RAMSize,
+		"start address %#03x leaves no room for a ROM in %d bytes of RAM", c.StartAddress, c.RAMSize,
+	)
+
+	font := c.font()
+	if err := font.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	fontSize := font.Size()
+
+	check(
+		int(c.FontAddress)+fontSize <= c.RAMSize,
+		"font at %#03x does not fit in %d bytes of RAM", c.FontAddress, c.RAMSize,
+	)
+	check(
+		int(c.FontAddress)+fontSize <= int(c.StartAddress),
+		"font at %#03x overlaps the ROM at %#03x", c.FontAddress, c.StartAddress,
+	)
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("invalid config '%s': %w", c.ID, errors.Join(errs...))
+}
+
+// font returns the font the config selects.
+func (c Config) font() *Font {
+	if c.Font == nil {
+		return DefaultFont()
+	}
+
+	return c.Font
+}
+
+// Names of the regions NewEmulatorWithConfig maps: the interpreter area below
+// the start address, which holds the font, and the program space above it.
+const (
+	RegionInterpreter = "interpreter"
+	RegionProgram     = "program"
+)
+
+// NewEmulatorWithConfig returns an emulator for the machine cfg describes,
+// drawing to display, which must be cfg.Width by cfg.Height. Its Memory is a
+// Bus over RAM with the interpreter and program regions mapped.
+func NewEmulatorWithConfig(cfg Config, display Display, keys KeyInputSource) (*Emulator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if b := display.Bounds(); b.Dx() != cfg.Width || b.Dy() != cfg.Height {
+		return nil, fmt.Errorf("display is %dx%d, config '%s' wants %dx%d", b.Dx(), b.Dy(), cfg.ID, cfg.Width, cfg.Height)
+	}
+
+	stack, err := NewStack(cfg.StackSize)
+	if err != nil {
+		return nil, fmt.Errorf("could not create stack: %w", err)
+	}
+
+	ram, err := NewRAM(cfg.RAMSize)
+	if err != nil {
+		return nil, fmt.Errorf("could not create ram: %w", err)
+	}
+
+	bus := NewBus(ram, cfg.OutOfRange)
+	start := int(cfg.StartAddress)
+
+	for _, r := range []Region{
+		{Name: RegionInterpreter, Start: 0, Size: start},
+		{Name: RegionProgram, Start: start, Size: cfg.RAMSize - start},
+	} {
+		if err := bus.Map(r); err != nil {
+			return nil, fmt.Errorf("could not map memory: %w", err)
+		}
+	}
+
+	emu := &Emulator{
+		PC:      cfg.StartAddress,
+		Stack:   stack,
+		RAM:     ram,
+		Memory:  bus,
+		Keys:    keys,
+		Display: display,
+		Quirks:  cfg.Quirks,
+		config:  cfg,
+	}
+
+	if err := loadSprites(emu); err != nil {
+		return nil, fmt.Errorf("could not load sprites into emulator: %w", err)
+	}
+
+	return emu, nil
+}
+
+// Config returns the config the emulator was built with. Its Quirks are
+// those at creation, see Emulator.Quirks for the current ones.
+func (emu *Emulator) Config() Config {
+	return emu.config
+}
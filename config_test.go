@@ -0,0 +1,120 @@
+package chipper_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/chippertest"
+)
+
+func newConfigEmulator(t *testing.T, cfg chipper.Config) *chipper.Emulator {
+	t.Helper()
+
+	display, err := chipper.NewBitmap(cfg.Width, cfg.Height)
+	if err != nil {
+		t.Fatalf("could not create display: %v", err)
+	}
+
+	emu, err := chipper.NewEmulatorWithConfig(cfg, display, &chipper.StubKeyInputSource{})
+	if err != nil {
+		t.Fatalf("could not create emulator: %v", err)
+	}
+
+	return emu
+}
+
+func TestPresets(t *testing.T) {
+	rom := chippertest.Assemble(t,
+		"LD V1, 1",
+		"LD F, V1",
+		"DRW V0, V0, 5",
+	)
+
+	for _, cfg := range chipper.Presets() {
+		t.Run(cfg.ID, func(t *testing.T) {
+			if got, ok := chipper.PresetByName(strings.ToUpper(cfg.ID)); !ok || got.Name != cfg.Name {
+				t.Fatalf("could not find preset '%s' by name", cfg.ID)
+			}
+
+			emu := newConfigEmulator(t, cfg)
+
+			if err := emu.Load(bytes.NewReader(rom)); err != nil {
+				t.Fatalf("could not load: %v", err)
+			}
+
+			if emu.PC != cfg.StartAddress || emu.RAM[cfg.StartAddress] != rom[0] {
+				t.Fatalf("want the ROM loaded and PC at %#03x, got PC at %#03x", cfg.StartAddress, emu.PC)
+			}
+
+			if emu.Quirks != cfg.Quirks || emu.Config() != cfg {
+				t.Fatalf("want the preset's config, got %+v", emu.Config())
+			}
+
+			emu.TickTimers() // for the VBlank quirk.
+
+			for k := 0; k < 3; k++ {
+				if err := emu.Step(); err != nil {
+					t.Fatalf("could not step: %v", err)
+				}
+			}
+
+			const digitSize = 5
+			if want := cfg.FontAddress + digitSize; emu.Index != want {
+				t.Fatalf("want FX29 to point I at %#03x, got %#03x", want, emu.Index)
+			}
+
+			// the 1 of the font is 0x20, 0x60, 0x20, 0x20, 0x70.
+			if !emu.Display.(*chipper.Bitmap).Pixel(2, 0) || emu.Display.(*chipper.Bitmap).Pixel(0, 0) {
+				t.Fatal("want the 1 of the font drawn")
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	cases := []struct {
+		label  string
+		modify func(c *chipper.Config)
+		want   string
+	}{
+		{"no RAM", func(c *chipper.Config) { c.RAMSize = 0 }, "RAM size"},
+		{"too much RAM", func(c *chipper.Config) { c.RAMSize = 1<<16 + 1 }, "RAM size"},
+		{"no stack", func(c *chipper.Config) { c.StackSize = 0 }, "stack size"},
+		{"no display", func(c *chipper.Config) { c.Height = 0 }, "display size"},
+		{"start past RAM", func(c *chipper.Config) { c.StartAddress = 0xFFF }, "no room for a ROM"},
+		{"font past RAM", func(c *chipper.Config) { c.RAMSize = 0x1000; c.StartAddress = 0x200; c.FontAddress = 0xFC0 }, "does not fit"},
+		{"font over ROM", func(c *chipper.Config) { c.FontAddress = 0x1E0 }, "overlaps the ROM"},
+		{"WASM RAM size", func(c *chipper.Config) { c.RAMSize = 4*1024 + 1 }, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.label, func(t *testing.T) {
+			cfg := chipper.DefaultConfig()
+			c.modify(&cfg)
+
+			err := cfg.Validate()
+
+			switch {
+			case c.want == "" && err != nil:
+				t.Fatalf("want no error, got %v", err)
+			case c.want != "" && (err == nil || !strings.Contains(err.Error(), c.want)):
+				t.Fatalf("want an error about '%s', got %v", c.want, err)
+			}
+		})
+	}
+
+	t.Run("display size", func(t *testing.T) {
+		cfg, _ := chipper.PresetByName("eti660")
+
+		display, err := chipper.NewBitmap(64, 32)
+		if err != nil {
+			t.Fatalf("could not create display: %v", err)
+		}
+
+		if _, err := chipper.NewEmulatorWithConfig(cfg, display, &chipper.StubKeyInputSource{}); err == nil {
+			t.Fatal("want an error for a 64x32 display with a 64x48 config")
+		}
+	})
+}
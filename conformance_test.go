@@ -0,0 +1,354 @@
+package chipper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// The conformance suite runs a tiny CHIP-8 program per opcode or flag edge
+// case. Each program leaves its results in V0 onwards, then ends with
+// done(n), which stores V0 to Vn at resultAddr and halts. The expected
+// results come from Cowgod's Chip-8 Technical Reference and the flags test
+// of Timendus' chip8-test-suite, which agree on all of them:
+//
+//   - 8XY4, 8XY5, 8XY6, 8XY7 and 8XYE write VF after VX, so with X=F the
+//     flag is what remains in VF.
+//   - 8XY5 and 8XY7 wrap around, with VF=1 when there is no borrow.
+//   - FX55 and FX65 include VX, and move I past them: by X+1 on the VIP, by
+//     X on the CHIP-48 and not at all on the SCHIP.
+//   - DXYN sets VF when it erases a pixel, and only then.
+const (
+	resultAddr = 0x300
+	maxSteps   = 1000
+)
+
+// done stores V0 to Vn at resultAddr and jumps to itself at addr, which is
+// where done's instructions start.
+func done(addr uint16, n int) []uint16 {
+	return []uint16{
+		0xA000 | resultAddr,
+		0xF055 | uint16(n)<<8,
+		0x1000 | (addr + 4), //nolint:mnd
+	}
+}
+
+// program assembles instructions followed by done(n), starting at
+// StartAddress.
+func program(n int, instrs ...uint16) []byte {
+	addr := uint16(StartAddress + len(instrs)*InstructionSize)
+	instrs = append(instrs, done(addr, n)...)
+
+	buf := &bytes.Buffer{}
+	_ = binary.Write(buf, binary.BigEndian, instrs)
+
+	return buf.Bytes()
+}
+
+// pressedKeys is a KeyInputSource holding down a fixed set of keys.
+type pressedKeys map[int]bool
+
+func (k pressedKeys) Get(key int) bool    { return k[key] }
+func (k pressedKeys) Set(key int, v bool) { k[key] = v }
+func (k pressedKeys) WaitUntilKeypress() <-chan int {
+	ch := make(chan int, 1)
+
+	for key := 0; key < NumKeys; key++ {
+		if k[key] {
+			ch <- key
+
+			break
+		}
+	}
+
+	return ch
+}
+
+// runConformance runs rom until it halts, returning the result area.
+func runConformance(t *testing.T, rom []byte, quirks Quirks, keys pressedKeys, n int) []byte {
+	t.Helper()
+
+	display, err := NewBitmap(64, 32)
+	if err != nil {
+		t.Fatalf("could not create display: %v", err)
+	}
+
+	emu, err := NewEmulator(16, 4096, display, keys)
+	if err != nil {
+		t.Fatalf("could not create emulator: %v", err)
+	}
+
+	emu.Quirks = quirks
+
+	if err := emu.Load(bytes.NewReader(rom)); err != nil {
+		t.Fatalf("could not load rom: %v", err)
+	}
+
+	for k := 0; k < maxSteps; k++ {
+		halt := 0x1000 | emu.PC
+		if uint16(emu.RAM[emu.PC])<<8|uint16(emu.RAM[emu.PC+1]) == halt {
+			return emu.RAM[resultAddr : resultAddr+n+1]
+		}
+
+		if err := emu.Step(); err != nil {
+			t.Fatalf("step %d (PC=%#03x): %v", k, emu.PC, err)
+		}
+	}
+
+	t.Fatalf("program did not halt after %d steps", maxSteps)
+
+	return nil
+}
+
+func TestConformance(t *testing.T) { //nolint:funlen,maintidx
+	cases := []struct {
+		label  string
+		quirks Quirks
+		keys   pressedKeys
+		n      int // results are V0 to Vn.
+		prog   []uint16
+		want   []byte
+	}{
+		{
+			"00E0 clears the screen", Quirks{}, nil, 0,
+			[]uint16{0xA000, 0xD005, 0x00E0, 0xD005, 0x80F0},
+			[]byte{0},
+		},
+		{
+			"2NNN and 00EE call and return", Quirks{}, nil, 1,
+			// call 0x206, set V1, then jump past the subroutine to done.
+			[]uint16{0x2206, 0x6102, 0x120A, 0x6001, 0x00EE},
+			[]byte{1, 2},
+		},
+		{
+			"1NNN jumps", Quirks{}, nil, 0,
+			[]uint16{0x6001, 0x1206, 0x60FF},
+			[]byte{1},
+		},
+		{
+			"3XNN skips if VX equals NN", Quirks{}, nil, 1,
+			[]uint16{0x6205, 0x3205, 0x7001, 0x3206, 0x7101},
+			[]byte{0, 1},
+		},
+		{
+			"4XNN skips if VX does not equal NN", Quirks{}, nil, 1,
+			[]uint16{0x6205, 0x4205, 0x7001, 0x4206, 0x7101},
+			[]byte{1, 0},
+		},
+		{
+			"5XY0 skips if VX equals VY", Quirks{}, nil, 1,
+			[]uint16{0x6205, 0x6305, 0x6406, 0x5230, 0x7001, 0x5240, 0x7101},
+			[]byte{0, 1},
+		},
+		{
+			"9XY0 skips if VX does not equal VY", Quirks{}, nil, 1,
+			[]uint16{0x6205, 0x6305, 0x6406, 0x9230, 0x7001, 0x9240, 0x7101},
+			[]byte{1, 0},
+		},
+		{
+			"6XNN and 7XNN wrap without touching VF", Quirks{}, nil, 0xF,
+			[]uint16{0x60FF, 0x6F55, 0x7002},
+			[]byte{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x55},
+		},
+		{
+			"8XY0 to 8XY3", Quirks{}, nil, 4,
+			[]uint16{
+				0x6A3C, 0x6B0F,
+				0x80B0,
+				0x81A0, 0x81B1,
+				0x82A0, 0x82B2,
+				0x83A0, 0x83B3,
+				0x84A0,
+			},
+			[]byte{0x0F, 0x3F, 0x0C, 0x33, 0x3C},
+		},
+		{
+			"8XY4 sets VF on carry", Quirks{}, nil, 0xF,
+			[]uint16{0x60FF, 0x6102, 0x8014, 0x82F0, 0x6301, 0x8314},
+			[]byte{1, 2, 1, 3, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			"8XY4 with X=F leaves the carry in VF", Quirks{}, nil, 1,
+			[]uint16{0x6FFF, 0x6101, 0x8F14, 0x80F0, 0x6F01, 0x8F14, 0x81F0},
+			[]byte{1, 0},
+		},
+		{
+			"8XY4 with Y=F reads VF before the carry", Quirks{}, nil, 0xF,
+			[]uint16{0x6F10, 0x6020, 0x80F4},
+			[]byte{0x30, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			"8XY5 wraps and clears VF on borrow", Quirks{}, nil, 5,
+			[]uint16{
+				0x600A, 0x6101, 0x8015, 0x82F0,
+				0x630F, 0x64FF, 0x8345, 0x84F0,
+				0x6505, 0x8555, 0x85F0,
+			},
+			// 10-1=9 and 5-5=0 do not borrow, 0x0F-0xFF does.
+			[]byte{9, 1, 1, 0x10, 0, 1},
+		},
+		{
+			"8XY5 with X=F leaves the flag in VF", Quirks{}, nil, 1,
+			[]uint16{0x6F0A, 0x6101, 0x8F15, 0x80F0, 0x6F01, 0x610A, 0x8F15, 0x81F0},
+			[]byte{1, 0},
+		},
+		{
+			"8XY7 wraps and clears VF on borrow", Quirks{}, nil, 5,
+			[]uint16{
+				0x6001, 0x610A, 0x8017, 0x82F0,
+				0x63FF, 0x640F, 0x8347, 0x84F0,
+				0x6505, 0x8557, 0x85F0,
+			},
+			[]byte{9, 0x0A, 1, 0x10, 0, 1},
+		},
+		{
+			"8XY7 with X=F leaves the flag in VF", Quirks{}, nil, 1,
+			[]uint16{0x6F01, 0x610A, 0x8F17, 0x80F0, 0x6F0A, 0x6101, 0x8F17, 0x81F0},
+			[]byte{1, 0},
+		},
+		{
+			"8XY6 shifts VY right into VX", Quirks{}, nil, 3,
+			[]uint16{0x6107, 0x8016, 0x82F0, 0x6308, 0x8336, 0x83F0},
+			[]byte{3, 7, 1, 0},
+		},
+		{
+			"8XY6 with X=F leaves the dropped bit in VF", Quirks{}, nil, 1,
+			[]uint16{0x6102, 0x8F16, 0x80F0, 0x6103, 0x8F16, 0x81F0},
+			[]byte{0, 1},
+		},
+		{
+			"8XY6 shifts VX with the Shift quirk", Quirks{Shift: true}, nil, 2,
+			[]uint16{0x6004, 0x6107, 0x8016, 0x82F0},
+			[]byte{2, 7, 0},
+		},
+		{
+			"8XYE shifts VY left into VX", Quirks{}, nil, 3,
+			[]uint16{0x6181, 0x801E, 0x82F0, 0x6340, 0x833E, 0x83F0},
+			[]byte{2, 0x81, 1, 0},
+		},
+		{
+			"8XYE with X=F leaves the dropped bit in VF", Quirks{}, nil, 1,
+			[]uint16{0x6140, 0x8F1E, 0x80F0, 0x61C0, 0x8F1E, 0x81F0},
+			[]byte{0, 1},
+		},
+		{
+			"ANNN and FX1E set I", Quirks{}, nil, 0,
+			// I=0+5 is the first row of the font's 1.
+			[]uint16{0xA000, 0x6005, 0xF01E, 0xF065},
+			[]byte{0x20},
+		},
+		{
+			"BNNN jumps to NNN+V0", Quirks{}, nil, 0,
+			[]uint16{0x6004, 0xB202, 0x60FF, 0x6001},
+			[]byte{1},
+		},
+		{
+			"BXNN jumps to XNN+VX with the Jump quirk", Quirks{Jump: true}, nil, 0,
+			[]uint16{0x6000, 0x6204, 0xB204, 0x60FF, 0x6001},
+			[]byte{1},
+		},
+		{
+			"DXYN sets VF on collision", Quirks{}, nil, 2,
+			[]uint16{0xA000, 0xD005, 0x80F0, 0xD005, 0x81F0, 0xD005, 0x82F0},
+			[]byte{0, 1, 0},
+		},
+		{
+			"EX9E and EXA1 skip on keys", Quirks{}, pressedKeys{5: true}, 3,
+			[]uint16{
+				0x6405, 0x6506,
+				0xE49E, 0x7001,
+				0xE59E, 0x7101,
+				0xE4A1, 0x7201,
+				0xE5A1, 0x7301,
+			},
+			[]byte{0, 1, 1, 0},
+		},
+		{
+			"FX07 reads FX15's delay timer", Quirks{}, nil, 0,
+			[]uint16{0x6120, 0xF115, 0xF007},
+			[]byte{0x20},
+		},
+		{
+			"FX0A waits for a key", Quirks{}, pressedKeys{0xB: true}, 0,
+			[]uint16{0xF00A},
+			[]byte{0xB},
+		},
+		{
+			"FX29 points I at the font sprite of VX", Quirks{}, nil, 0,
+			// the first row of A.
+			[]uint16{0x600A, 0xF029, 0xF065},
+			[]byte{0xF0},
+		},
+		{
+			"FX29 only looks at the low nibble of VX", Quirks{}, nil, 0,
+			// the first row of 1.
+			[]uint16{0x60F1, 0xF029, 0xF065},
+			[]byte{0x20},
+		},
+		{
+			"FX33 stores the BCD of VX", Quirks{}, nil, 2,
+			[]uint16{0x607B, 0xA400, 0xF033, 0xF265},
+			[]byte{1, 2, 3},
+		},
+		{
+			"FX55 stores V0 to VX inclusive", Quirks{MemoryLeaveIUnchanged: true}, nil, 3,
+			// store V0 to V2 at 0x400, clear them, then load V0 to V3 back.
+			[]uint16{
+				0x6001, 0x6102, 0x6203, 0x6304, 0xA400, 0xF255,
+				0x6000, 0x6100, 0x6200, 0x6300, 0xF365,
+			},
+			[]byte{1, 2, 3, 0},
+		},
+		{
+			"FX65 loads V0 to VX inclusive", Quirks{MemoryLeaveIUnchanged: true}, nil, 3,
+			// store V0 to V2 at 0x400, clear them, then load V0 to V1 back.
+			[]uint16{
+				0x6001, 0x6102, 0x6203, 0xA400, 0xF255,
+				0x6000, 0x6100, 0x6200, 0xF165,
+			},
+			[]byte{1, 2, 0, 0},
+		},
+		{
+			"FX55 increments I by X+1", Quirks{}, nil, 2,
+			[]uint16{0x6001, 0x6102, 0xA400, 0xF155, 0x6009, 0xF055, 0xA400, 0xF265},
+			[]byte{1, 2, 9},
+		},
+		{
+			"FX55 increments I by X with the CHIP-48 quirk", Quirks{MemoryIncrementByX: true}, nil, 2,
+			[]uint16{0x6001, 0x6102, 0xA400, 0xF155, 0x6009, 0xF055, 0xA400, 0xF265},
+			[]byte{1, 9, 0},
+		},
+		{
+			"FX65 increments I by X+1", Quirks{}, nil, 2,
+			// load 1 from 0x400 into V2, then 2 from 0x401 into V0.
+			[]uint16{0x6001, 0x6102, 0xA400, 0xF155, 0xA400, 0xF065, 0x8200, 0xF065},
+			[]byte{2, 2, 1},
+		},
+		{
+			"FX55 leaves I unchanged with the memory quirk", Quirks{MemoryLeaveIUnchanged: true}, nil, 2,
+			[]uint16{0x6001, 0x6102, 0x6203, 0xA400, 0xF155, 0x6009, 0xF055, 0xF265},
+			[]byte{9, 2, 0},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.label, func(t *testing.T) {
+			got := runConformance(t, program(c.n, c.prog...), c.quirks, c.keys, c.n)
+			if !bytes.Equal(got, c.want) {
+				t.Fatalf("want % x, got % x", c.want, got)
+			}
+		})
+	}
+}
+
+// TestConformanceRandom checks CXNN never sets bits outside of NN.
+func TestConformanceRandom(t *testing.T) {
+	for mask := 0; mask <= 0xFF; mask += 0x11 {
+		for k := 0; k < 10; k++ {
+			got := runConformance(t, program(0, 0xC000|uint16(mask)), Quirks{}, nil, 0)
+			if int(got[0])&^mask != 0 {
+				t.Fatalf("mask %#02x: got %#02x", mask, got[0])
+			}
+		}
+	}
+}
@@ -0,0 +1,448 @@
+package chipper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrControllerClosed is returned by the methods of a Controller whose
+// context is done or that has been closed.
+var ErrControllerClosed = errors.New("controller is closed")
+
+// ErrNoROM is returned when stepping a Controller that has no ROM loaded.
+var ErrNoROM = errors.New("no ROM loaded")
+
+const controllerFrameRate = 60
+
+// ControllerOptions configures the machines a Controller runs.
+type ControllerOptions struct {
+	// Config is the machine to run, DefaultConfig if zero.
+	Config Config
+
+	// IPF is the speed, in instructions per 60Hz frame. Zero uses the
+	// config's tick rate.
+	IPF int
+
+	// Keys is the keypad, a keypad of the Controller's own if nil. It must
+	// also be a KeyPoller, as FX0A cannot block the Controller's goroutine,
+	// and safe to use from other goroutines if the frontend sets keys on it
+	// directly rather than through SetKey.
+	Keys KeyInputSource
+}
+
+func (o ControllerOptions) withDefaults() ControllerOptions {
+	if o.Config == (Config{}) {
+		o.Config = DefaultConfig()
+	}
+
+	if o.IPF <= 0 {
+		o.IPF = o.Config.TickRate
+	}
+
+	if o.IPF <= 0 {
+		o.IPF = defaultTickRate
+	}
+
+	if o.Keys == nil {
+		o.Keys = &controllerKeys{}
+	}
+
+	return o
+}
+
+// ControllerState is a snapshot of a Controller's machine.
+type ControllerState struct {
+	PC         uint16
+	Index      uint16
+	V          [RegisterCount]byte
+	DelayTimer byte
+	SoundTimer byte
+	Stack      []uint16
+	Frames     uint64
+	Quirks     Quirks
+	IPF        int
+	Loaded     bool
+	Paused     bool
+
+	// Err is the error that stopped the machine, if any. Reset or Load
+	// clear it.
+	Err error
+}
+
+// Controller runs an emulator in a goroutine of its own, at 60 frames per
+// second, and serialises every command to it. The emulator is only ever
+// touched by that goroutine, so frontends calling the Controller from any
+// number of goroutines are free of data races.
+//
+// A Controller starts paused with no ROM. If the emulator faults, it pauses
+// and reports the error in State until the next Reset or Load.
+type Controller struct {
+	cmds   chan func()
+	done   chan struct{}
+	cancel context.CancelFunc
+
+	// owned by the run goroutine.
+	opts   ControllerOptions
+	emu    *Emulator
+	keys   KeyInputSource
+	rom    []byte
+	quirks Quirks
+	paused bool
+	err    error
+}
+
+// NewController starts a Controller, which runs until ctx is done or Close
+// is called.
+func NewController(ctx context.Context, opts ControllerOptions) (*Controller, error) {
+	opts = opts.withDefaults()
+
+	if _, ok := opts.Keys.(KeyPoller); !ok {
+		return nil, fmt.Errorf("keys %T do not implement KeyPoller", opts.Keys)
+	}
+
+	c := &Controller{
+		cmds:   make(chan func()),
+		done:   make(chan struct{}),
+		opts:   opts,
+		keys:   opts.Keys,
+		quirks: opts.Config.Quirks,
+		paused: true,
+	}
+
+	if err := c.reset(); err != nil {
+		return nil, err
+	}
+
+	ctx, c.cancel = context.WithCancel(ctx)
+
+	go c.run(ctx)
+
+	return c, nil
+}
+
+// Config returns the config of the machines the Controller runs.
+func (c *Controller) Config() (Config, error) {
+	var cfg Config
+
+	err := c.do(func() error {
+		cfg = c.opts.Config
+
+		return nil
+	})
+
+	return cfg, err
+}
+
+// Close stops the Controller and waits for its goroutine to return.
+func (c *Controller) Close() {
+	c.cancel()
+	<-c.done
+}
+
+func (c *Controller) run(ctx context.Context) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(time.Second / controllerFrameRate)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case fn := <-c.cmds:
+			fn()
+		case <-ticker.C:
+			if c.paused || c.rom == nil {
+				continue
+			}
+
+			if err := c.emu.RunFrame(c.opts.IPF); err != nil {
+				c.fault(err)
+			}
+		}
+	}
+}
+
+// do runs fn in the Controller's goroutine and returns its error.
+func (c *Controller) do(fn func() error) error {
+	errc := make(chan error, 1)
+
+	select {
+	case c.cmds <- func() { errc <- fn() }:
+	case <-c.done:
+		return ErrControllerClosed
+	}
+
+	return <-errc
+}
+
+func (c *Controller) fault(err error) {
+	c.err = fmt.Errorf("PC=%#03x: %w", c.emu.PC, err)
+	c.paused = true
+}
+
+// reset replaces the emulator with a new one, loading the ROM if there is
+// one.
+func (c *Controller) reset() error {
+	display, err := NewBitmap(c.opts.Config.Width, c.opts.Config.Height)
+	if err != nil {
+		return fmt.Errorf("could not create display: %w", err)
+	}
+
+	emu, err := NewEmulatorWithConfig(c.opts.Config, display, c.keys)
+	if err != nil {
+		return fmt.Errorf("could not create emulator: %w", err)
+	}
+
+	emu.Quirks = c.quirks
+
+	if c.rom != nil {
+		if err := emu.Load(bytes.NewReader(c.rom)); err != nil {
+			return fmt.Errorf("could not load rom: %w", err)
+		}
+	}
+
+	c.emu = emu
+	c.err = nil
+
+	c.keys.(KeyPoller).ResetKeypress()
+
+	return nil
+}
+
+// Load resets the machine with rom. It does not change whether the
+// Controller is paused.
+func (c *Controller) Load(rom []byte) error {
+	rom = bytes.Clone(rom)
+
+	return c.do(func() error {
+		if limit := c.opts.Config.RAMSize - int(c.opts.Config.StartAddress); len(rom) > limit {
+			return fmt.Errorf("rom is %d bytes, at most %d fit in RAM", len(rom), limit)
+		}
+
+		prev := c.rom
+		c.rom = rom
+
+		if err := c.reset(); err != nil {
+			c.rom = prev
+
+			return err
+		}
+
+		return nil
+	})
+}
+
+// Configure resets the machine as cfg, keeping the ROM, running ipf
+// instructions per frame, or the config's tick rate if ipf is zero. The
+// quirks set with SetQuirks are replaced by the config's.
+func (c *Controller) Configure(cfg Config, ipf int) error {
+	opts := ControllerOptions{Config: cfg, IPF: ipf}.withDefaults()
+
+	return c.do(func() error {
+		if limit := opts.Config.RAMSize - int(opts.Config.StartAddress); len(c.rom) > limit {
+			return fmt.Errorf("rom is %d bytes, at most %d fit in RAM", len(c.rom), limit)
+		}
+
+		prevConfig, prevIPF, prevQuirks := c.opts.Config, c.opts.IPF, c.quirks
+
+		c.opts.Config = opts.Config
+		c.opts.IPF = opts.IPF
+		c.quirks = opts.Config.Quirks
+
+		if err := c.reset(); err != nil {
+			c.opts.Config, c.opts.IPF, c.quirks = prevConfig, prevIPF, prevQuirks
+
+			return err
+		}
+
+		return nil
+	})
+}
+
+// Reset restarts the loaded ROM on a new machine.
+func (c *Controller) Reset() error {
+	return c.do(c.reset)
+}
+
+// Pause stops the machine after the current frame.
+func (c *Controller) Pause() error {
+	return c.do(func() error {
+		c.paused = true
+
+		return nil
+	})
+}
+
+// Resume runs the machine again. It returns the error that stopped it, if
+// any, in which case it stays paused.
+func (c *Controller) Resume() error {
+	return c.do(func() error {
+		if c.err != nil {
+			return c.err
+		}
+
+		c.paused = false
+
+		return nil
+	})
+}
+
+// Step executes a single instruction, typically while paused.
+func (c *Controller) Step() error {
+	return c.do(func() error {
+		if c.rom == nil {
+			return ErrNoROM
+		}
+
+		if c.err != nil {
+			return c.err
+		}
+
+		if err := c.emu.Step(); err != nil {
+			c.fault(err)
+
+			return c.err
+		}
+
+		return nil
+	})
+}
+
+// SetKey presses or releases a keypad key, from 0 to F.
+func (c *Controller) SetKey(key int, down bool) error {
+	if key < 0 || key >= NumKeys {
+		return fmt.Errorf("key %d out of range [0, %d)", key, NumKeys)
+	}
+
+	return c.do(func() error {
+		c.keys.Set(key, down)
+
+		return nil
+	})
+}
+
+// SetSpeed sets the number of instructions run per frame.
+func (c *Controller) SetSpeed(ipf int) error {
+	if ipf <= 0 {
+		return fmt.Errorf("instructions per frame must be > 0, got %d", ipf)
+	}
+
+	return c.do(func() error {
+		c.opts.IPF = ipf
+
+		return nil
+	})
+}
+
+// SetQuirks sets the quirks of the machine, which are kept across resets.
+func (c *Controller) SetQuirks(q Quirks) error {
+	return c.do(func() error {
+		c.quirks = q
+		c.emu.Quirks = q
+
+		return nil
+	})
+}
+
+// State returns a snapshot of the machine.
+func (c *Controller) State() (ControllerState, error) {
+	var s ControllerState
+
+	err := c.do(func() error {
+		emu := c.emu
+		s = ControllerState{
+			PC:         emu.PC,
+			Index:      emu.Index,
+			V:          emu.V,
+			DelayTimer: emu.DelayTimer,
+			SoundTimer: emu.SoundTimer,
+			Stack:      emu.Stack.Values(),
+			Frames:     emu.FrameCount(),
+			Quirks:     emu.Quirks,
+			IPF:        c.opts.IPF,
+			Loaded:     c.rom != nil,
+			Paused:     c.paused,
+			Err:        c.err,
+		}
+
+		return nil
+	})
+
+	return s, err
+}
+
+// Frame returns the last frame presented by the machine.
+func (c *Controller) Frame() (Frame, error) {
+	var f Frame
+
+	err := c.do(func() error {
+		f = c.emu.Frame()
+
+		return nil
+	})
+
+	return f, err
+}
+
+// Do runs fn with the emulator in the Controller's goroutine, for anything
+// the other methods do not cover. The emulator must not be kept after fn
+// returns.
+func (c *Controller) Do(fn func(emu *Emulator) error) error {
+	return c.do(func() error {
+		return fn(c.emu)
+	})
+}
+
+// controllerKeys is the keypad of a Controller. It is only used from the
+// Controller's goroutine. FX0A polls it, taking the first key pressed after
+// it started waiting.
+type controllerKeys struct {
+	keys     [NumKeys]bool
+	latch    KeyLatch
+	listener chan int
+}
+
+func (k *controllerKeys) Get(key int) bool {
+	if key < 0 || key >= NumKeys {
+		return false
+	}
+
+	return k.keys[key]
+}
+
+func (k *controllerKeys) Set(key int, v bool) {
+	if key < 0 || key >= NumKeys {
+		return
+	}
+
+	if v && !k.keys[key] {
+		k.latch.Press(key)
+
+		if k.listener != nil {
+			k.listener <- key
+			k.listener = nil
+		}
+	}
+
+	k.keys[key] = v
+}
+
+func (k *controllerKeys) PollKeypress() (int, bool) {
+	return k.latch.Poll()
+}
+
+func (k *controllerKeys) ResetKeypress() {
+	k.latch.Reset()
+}
+
+// WaitUntilKeypress is not used by FX0A, which polls, but is there to make
+// controllerKeys a KeyInputSource.
+func (k *controllerKeys) WaitUntilKeypress() <-chan int {
+	k.listener = make(chan int, 1)
+
+	return k.listener
+}
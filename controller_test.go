@@ -0,0 +1,348 @@
+package chipper_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/chippertest"
+)
+
+func newTestController(t *testing.T) *chipper.Controller {
+	t.Helper()
+
+	c, err := chipper.NewController(context.Background(), chipper.ControllerOptions{})
+	if err != nil {
+		t.Fatalf("could not create controller: %v", err)
+	}
+
+	t.Cleanup(c.Close)
+
+	return c
+}
+
+// waitForState polls the controller's state until cond holds.
+func waitForState(t *testing.T, c *chipper.Controller, cond func(s chipper.ControllerState) bool) chipper.ControllerState {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		s, err := c.State()
+		if err != nil {
+			t.Fatalf("could not get state: %v", err)
+		}
+
+		if cond(s) {
+			return s
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatal("timed out waiting for the controller")
+
+	return chipper.ControllerState{}
+}
+
+func TestController(t *testing.T) {
+	rom := chippertest.Assemble(t,
+		"LD V0, K",
+		"LD V1, 5",
+		"LD ST, V1",
+		"JP 0x206",
+	)
+
+	c := newTestController(t)
+
+	if err := c.Step(); !errors.Is(err, chipper.ErrNoROM) {
+		t.Fatalf("want ErrNoROM, got %v", err)
+	}
+
+	if err := c.Load(rom); err != nil {
+		t.Fatalf("could not load: %v", err)
+	}
+
+	t.Run("FX0A polls", func(t *testing.T) {
+		for k := 0; k < 3; k++ {
+			if err := c.Step(); err != nil {
+				t.Fatalf("could not step: %v", err)
+			}
+		}
+
+		if s, _ := c.State(); s.PC != 0x200 {
+			t.Fatalf("want PC to stay at 0x200 until a key is pressed, got %#03x", s.PC)
+		}
+
+		if err := c.SetKey(7, true); err != nil {
+			t.Fatalf("could not set key: %v", err)
+		}
+
+		if err := c.Step(); err != nil {
+			t.Fatalf("could not step: %v", err)
+		}
+
+		if s, _ := c.State(); s.PC != 0x202 || s.V[0] != 7 {
+			t.Fatalf("want PC=0x202 and V0=7, got PC=%#03x and V0=%d", s.PC, s.V[0])
+		}
+	})
+
+	t.Run("run", func(t *testing.T) {
+		if err := c.SetSpeed(100); err != nil {
+			t.Fatalf("could not set speed: %v", err)
+		}
+
+		if err := c.Resume(); err != nil {
+			t.Fatalf("could not resume: %v", err)
+		}
+
+		s := waitForState(t, c, func(s chipper.ControllerState) bool { return s.Frames >= 2 })
+		if s.PC != 0x206 || s.V[1] != 5 || s.IPF != 100 || s.Paused {
+			t.Fatalf("unexpected state: %+v", s)
+		}
+
+		if err := c.Pause(); err != nil {
+			t.Fatalf("could not pause: %v", err)
+		}
+
+		before, _ := c.State()
+		time.Sleep(50 * time.Millisecond)
+
+		if after, _ := c.State(); after.Frames != before.Frames || !after.Paused {
+			t.Fatalf("want no frames while paused, went from %d to %d", before.Frames, after.Frames)
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		if err := c.SetQuirks(chipper.Quirks{Shift: true}); err != nil {
+			t.Fatalf("could not set quirks: %v", err)
+		}
+
+		if err := c.Reset(); err != nil {
+			t.Fatalf("could not reset: %v", err)
+		}
+
+		s, _ := c.State()
+		if s.PC != chipper.StartAddress || s.V[0] != 0 || s.Frames != 0 || !s.Loaded {
+			t.Fatalf("unexpected state after reset: %+v", s)
+		}
+
+		if s.Quirks != (chipper.Quirks{Shift: true}) {
+			t.Fatalf("want quirks kept across resets, got %+v", s.Quirks)
+		}
+	})
+
+	t.Run("configure", func(t *testing.T) {
+		cfg, ok := chipper.PresetByName("vip")
+		if !ok {
+			t.Fatal("no vip preset")
+		}
+
+		if err := c.Configure(cfg, 0); err != nil {
+			t.Fatalf("could not configure: %v", err)
+		}
+
+		s, _ := c.State()
+		if s.Quirks != cfg.Quirks || s.IPF != cfg.TickRate || !s.Loaded {
+			t.Fatalf("want the preset's quirks and tick rate, got %+v", s)
+		}
+
+		if got, _ := c.Config(); got != cfg {
+			t.Fatalf("want the preset's config, got %+v", got)
+		}
+	})
+}
+
+func TestControllerFault(t *testing.T) {
+	c := newTestController(t)
+
+	if err := c.Load([]byte{0xFF, 0xFF}); err != nil {
+		t.Fatalf("could not load: %v", err)
+	}
+
+	if err := c.Resume(); err != nil {
+		t.Fatalf("could not resume: %v", err)
+	}
+
+	s := waitForState(t, c, func(s chipper.ControllerState) bool { return s.Err != nil })
+	if !s.Paused {
+		t.Fatal("want the controller paused after a fault")
+	}
+
+	if err := c.Resume(); err == nil {
+		t.Fatal("want Resume to fail after a fault")
+	}
+
+	if err := c.Reset(); err != nil {
+		t.Fatalf("could not reset: %v", err)
+	}
+
+	if s, _ := c.State(); s.Err != nil {
+		t.Fatalf("want the error cleared by Reset, got %v", s.Err)
+	}
+
+	if err := c.Load(make([]byte, 4096)); err == nil {
+		t.Fatal("want an error loading a ROM larger than RAM")
+	}
+}
+
+// TestControllerConcurrent drives a running controller from many goroutines,
+// for go test -race to check.
+func TestControllerConcurrent(t *testing.T) {
+	rom := chippertest.Assemble(t,
+		"CLS",
+		"LD V0, K",
+		"LD F, V0",
+		"DRW V0, V0, 5",
+		"JP 0x200",
+	)
+
+	c := newTestController(t)
+
+	if err := c.Load(rom); err != nil {
+		t.Fatalf("could not load: %v", err)
+	}
+
+	if err := c.Resume(); err != nil {
+		t.Fatalf("could not resume: %v", err)
+	}
+
+	const workers = 8
+
+	wg := sync.WaitGroup{}
+	deadline := time.Now().Add(200 * time.Millisecond)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for k := 0; time.Now().Before(deadline); k++ {
+				var err error
+
+				switch (w + k) % 8 {
+				case 0:
+					err = c.SetKey(k%chipper.NumKeys, k%3 == 0)
+				case 1:
+					_, err = c.State()
+				case 2:
+					_, err = c.Frame()
+				case 3:
+					err = c.SetSpeed(1 + k%20)
+				case 4:
+					err = c.Step()
+				case 5:
+					err = c.Reset()
+				case 6:
+					err = c.Load(rom)
+				default:
+					err = c.Do(func(emu *chipper.Emulator) error {
+						emu.V[0xE]++
+
+						return nil
+					})
+				}
+
+				if err != nil {
+					t.Errorf("worker %d: %v", w, err)
+
+					return
+				}
+			}
+		}(w)
+	}
+
+	wg.Wait()
+	c.Close()
+
+	if _, err := c.State(); !errors.Is(err, chipper.ErrControllerClosed) {
+		t.Fatalf("want ErrControllerClosed, got %v", err)
+	}
+}
+
+// resetCountingKeys is a keypad of a frontend, counting the resets of its
+// FX0A latch.
+type resetCountingKeys struct {
+	chipper.StubKeyInputSource
+	chipper.KeyLatch
+
+	resets int
+}
+
+func (k *resetCountingKeys) PollKeypress() (int, bool) { return k.Poll() }
+func (k *resetCountingKeys) ResetKeypress()            { k.resets++; k.Reset() }
+
+func TestControllerKeys(t *testing.T) {
+	keys := &resetCountingKeys{}
+
+	c, err := chipper.NewController(context.Background(), chipper.ControllerOptions{Keys: keys})
+	if err != nil {
+		t.Fatalf("could not create controller: %v", err)
+	}
+
+	t.Cleanup(c.Close)
+
+	if err := c.Load(chippertest.Assemble(t, "LD V0, K")); err != nil {
+		t.Fatalf("could not load: %v", err)
+	}
+
+	// FX0A starts waiting, then the key comes in after the reset.
+	if err := c.Step(); err != nil {
+		t.Fatalf("could not step: %v", err)
+	}
+
+	if err := c.Do(func(*chipper.Emulator) error {
+		keys.Press(3)
+
+		return nil
+	}); err != nil {
+		t.Fatalf("could not press key: %v", err)
+	}
+
+	if err := c.Reset(); err != nil {
+		t.Fatalf("could not reset: %v", err)
+	}
+
+	if err := c.Do(func(*chipper.Emulator) error {
+		if keys.resets != 3 {
+			t.Errorf("want the latch reset by NewController, Load and Reset, got %d resets", keys.resets)
+		}
+
+		if key, ok := keys.PollKeypress(); ok {
+			t.Errorf("want no key pending after a reset, got %X", key)
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatalf("could not inspect keys: %v", err)
+	}
+}
+
+func TestKeyLatch(t *testing.T) {
+	var l chipper.KeyLatch
+
+	l.Press(1)
+
+	if _, ok := l.Poll(); ok {
+		t.Fatal("want presses before FX0A waits ignored")
+	}
+
+	l.Press(2)
+	l.Press(3)
+
+	if key, ok := l.Poll(); !ok || key != 2 {
+		t.Fatalf("want the first key pressed while waiting, got %X, %t", key, ok)
+	}
+
+	l.Poll()
+	l.Press(4)
+	l.Reset()
+
+	if _, ok := l.Poll(); ok {
+		t.Fatal("want Reset to drop the latched key")
+	}
+}
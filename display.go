@@ -5,7 +5,6 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
-	"strings"
 )
 
 type Display interface {
@@ -34,90 +33,19 @@ const (
 	ColorSet
 )
 
+// DebugDisplay is a Bitmap whose Set panics on points out of bounds, to catch
+// drawing bugs early.
 type DebugDisplay struct {
-	width   int
-	height  int
-	data    []bool // storage is in row-major order.
-	palette color.Palette
-}
-
-func (d *DebugDisplay) ColorClear() color.Color {
-	return color.Black
-}
-
-func (d *DebugDisplay) ColorSet() color.Color {
-	return color.White
-}
-
-func (d *DebugDisplay) String() string {
-	b := &strings.Builder{}
-	rows := d.height
-	cols := d.width
-
-	top := &strings.Builder{}
-	top.WriteString("     ")
-
-	for k := 0; k < cols*2; k++ {
-		fmt.Fprintf(top, "-")
-	}
-
-	b.WriteString(top.String())
-	fmt.Fprintf(b, "\n")
-
-	for y := 0; y < rows; y++ {
-		fmt.Fprintf(b, " %2d |", y)
-
-		for x := 0; x < cols; x++ {
-			c := " ."
-			if ColorEq(d.At(x, y), d.ColorSet()) {
-				c = " o"
-			}
-
-			fmt.Fprintf(b, "%s", c)
-		}
-
-		fmt.Fprintf(b, "|\n")
-	}
-
-	b.WriteString(top.String())
-
-	return b.String()
-}
-
-func (d DebugDisplay) toIndex(x, y int) int {
-	return x + (y * d.width)
-}
-
-func (d *DebugDisplay) Bounds() image.Rectangle {
-	return image.Rect(0, 0, d.width, d.height)
-}
-
-func (d *DebugDisplay) At(x, y int) color.Color {
-	if d.data[d.toIndex(x, y)] {
-		return d.ColorSet()
-	}
-
-	return d.ColorClear()
-}
-
-func (d *DebugDisplay) ColorModel() color.Model {
-	return d.palette
+	*Bitmap
 }
 
 func NewDebugDisplay(w, h int) (*DebugDisplay, error) {
-	if w < 0 || h < 0 {
-		return nil, fmt.Errorf("width and height must be >= 0 (w=%d, h=%d)", w, h)
+	b, err := NewBitmap(w, h)
+	if err != nil {
+		return nil, err
 	}
 
-	return &DebugDisplay{
-		width:  w,
-		height: h,
-		data:   make([]bool, w*h),
-		palette: []color.Color{
-			color.Black,
-			color.White,
-		},
-	}, nil
+	return &DebugDisplay{Bitmap: b}, nil
 }
 
 func (d *DebugDisplay) Set(x, y int, c color.Color) {
@@ -131,8 +59,7 @@ func (d *DebugDisplay) Set(x, y int, c color.Color) {
 		))
 	}
 
-	is := ColorEq(c, d.ColorSet())
-	d.data[d.toIndex(x, y)] = is
+	d.Bitmap.Set(x, y, c)
 }
 
 func ColorEq(c1, c2 color.Color) bool {
@@ -161,8 +88,10 @@ func ColorEq(c1, c2 color.Color) bool {
 	return true
 }
 
-func loadSprites(emu *Emulator) error { //nolint: unparam
-	data := []byte{
+// HexFont returns the built-in 4x5 font for the hex digits 0-F, 5 bytes per
+// digit. It is the small font of DefaultFont.
+func HexFont() []byte {
+	return []byte{
 		0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
 		0x20, 0x60, 0x20, 0x20, 0x70, // 1
 		0xF0, 0x10, 0xF0, 0x80, 0xF0, // 2
@@ -180,8 +109,20 @@ func loadSprites(emu *Emulator) error { //nolint: unparam
 		0xF0, 0x80, 0xF0, 0x80, 0xF0, // E
 		0xF0, 0x80, 0xF0, 0x80, 0x80, // F
 	}
+}
+
+// loadSprites stores the config's font at its address, big digits right
+// after the small ones.
+func loadSprites(emu *Emulator) error {
+	font := emu.config.font()
+	addr := int(emu.config.FontAddress)
+	if addr+font.Size() > emu.Memory.Size() {
+		return fmt.Errorf("font '%s' at %#03x does not fit in %d bytes of RAM", font.id, addr, emu.Memory.Size())
+	}
 
-	copy(emu.RAM, data)
+	if err := emu.writeBytes(addr, font.small); err != nil {
+		return err
+	}
 
-	return nil
+	return emu.writeBytes(addr+len(font.small), font.big)
 }
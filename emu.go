@@ -5,13 +5,14 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"time"
 )
 
 const (
 	ProgramCounterSize = 2     // Size in bytes.
 	RegisterCount      = 16    // V0-VF.
-	StartAddress       = 0x200 // starting address of PC.
+	StartAddress       = 0x200 // starting address of PC, unless configured otherwise.
 	NumKeys            = 16
 	InstructionSize    = 2 // each instruction is 2 bytes wide.
 )
@@ -24,17 +25,64 @@ type Emulator struct {
 	Index           uint16
 	Keys            KeyInputSource
 	Stack           *Stack
-	RAM             []byte
+	RAM             []byte // the bytes behind Memory, to read; do not replace it.
+	Memory          Memory // what instructions, Load and the font access RAM through.
 	Display         Display
 	LastInstruction Instruction
+	Quirks          Quirks
+	config          Config
 	logger          *log.Logger
 	lastUpdate      time.Time
+	tracers         []TraceFunc
+	frameFuncs      []FrameFunc
+	screen          screen
+	sounding        bool
+	vblank          bool // set by every timer tick, consumed by DXYN.
+	rng             *rand.Rand
 }
 
+// TraceFunc is called with every decoded instruction, right before it is executed.
+type TraceFunc func(emu *Emulator, instr Instruction)
+
+// FrameFunc is called after every 60Hz timer tick, i.e. once per emulated frame.
+type FrameFunc func(emu *Emulator)
+
 func (emu *Emulator) SetLogger(l *log.Logger) {
 	emu.logger = l
 }
 
+// AddTraceFunc registers fn to be called before each instruction is executed.
+func (emu *Emulator) AddTraceFunc(fn TraceFunc) {
+	emu.tracers = append(emu.tracers, fn)
+}
+
+// AddFrameFunc registers fn to be called once per emulated frame.
+func (emu *Emulator) AddFrameFunc(fn FrameFunc) {
+	emu.frameFuncs = append(emu.frameFuncs, fn)
+}
+
+// SetRand sets the source used by CXNN. Passing a seeded source makes runs
+// reproducible; nil restores the global source.
+func (emu *Emulator) SetRand(r *rand.Rand) {
+	emu.rng = r
+}
+
+func (emu *Emulator) randomByte() byte {
+	if emu.rng == nil {
+		return randomNum()
+	}
+
+	return byte(emu.rng.Intn(max8BitVal + 1))
+}
+
+func (emu *Emulator) log() *log.Logger {
+	if emu.logger == nil {
+		emu.logger = log.New(io.Discard, "[emu] ", log.Ltime)
+	}
+
+	return emu.logger
+}
+
 func (emu *Emulator) Close() { /* noop for now */ }
 
 func NewRAM(size int) ([]byte, error) {
@@ -45,36 +93,22 @@ func NewRAM(size int) ([]byte, error) {
 	return make([]byte, size), nil
 }
 
+// NewEmulator returns an emulator with the DefaultConfig, but for its stack
+// and RAM sizes, and a display of any size.
 func NewEmulator(stackSize, ramSize int, display Display, keys KeyInputSource) (*Emulator, error) {
-	stack, err := NewStack(stackSize)
-	if err != nil {
-		return nil, fmt.Errorf("could not create stack: %w", err)
-	}
+	cfg := DefaultConfig()
+	cfg.StackSize = stackSize
+	cfg.RAMSize = ramSize
+	cfg.Width, cfg.Height = display.Bounds().Dx(), display.Bounds().Dy()
 
-	ram, err := NewRAM(ramSize)
-	if err != nil {
-		return nil, fmt.Errorf("could not create ram: %w", err)
-	}
-
-	emu := &Emulator{
-		PC:      StartAddress,
-		Stack:   stack,
-		RAM:     ram,
-		Keys:    keys,
-		Display: display,
-	}
-
-	if err := loadSprites(emu); err != nil {
-		return nil, fmt.Errorf("could not load sprites into emulator: %w", err)
-	}
-
-	return emu, nil
+	return NewEmulatorWithConfig(cfg, display, keys)
 }
 
-// Load will read the ROM from the passed in io.Reader and load the sprites.
+// Load will read the ROM from the passed in io.Reader and write it through
+// Memory at the start address.
 func (emu *Emulator) Load(r io.Reader) error {
-	ramSize := len(emu.RAM)
-	maxSize := ramSize - StartAddress
+	start := int(emu.config.StartAddress)
+	maxSize := emu.Memory.Size() - start
 	p := make([]byte, maxSize)
 
 	bytesRead, err := r.Read(p)
@@ -82,10 +116,8 @@ func (emu *Emulator) Load(r io.Reader) error {
 		return fmt.Errorf("error reading ROM: %w", err)
 	}
 
-	p = p[:bytesRead]
-
-	for k, b := range p {
-		emu.RAM[int(StartAddress)+k] = b
+	if err := emu.writeBytes(start, p[:bytesRead]); err != nil {
+		return fmt.Errorf("could not load ROM: %w", err)
 	}
 
 	return nil
@@ -107,7 +139,17 @@ func (emu *Emulator) subtractTimers() {
 	emu.lastUpdate = time.Now()
 
 	times := elapsed / timerPeriod
-	sub := int(times)
+	emu.decrementTimers(int(times))
+}
+
+// TickTimers decrements the delay and sound timers once, as a single 60Hz
+// timer tick would. It is meant for callers stepping the emulator themselves.
+func (emu *Emulator) TickTimers() {
+	emu.decrementTimers(1)
+}
+
+func (emu *Emulator) decrementTimers(sub int) {
+	emu.vblank = true
 
 	if emu.DelayTimer > 0 {
 		dt := int(emu.DelayTimer) - sub
@@ -118,6 +160,8 @@ func (emu *Emulator) subtractTimers() {
 		emu.DelayTimer = byte(dt)
 	}
 
+	soundBefore := int(emu.SoundTimer)
+
 	if emu.SoundTimer > 0 {
 		st := int(emu.SoundTimer) - sub
 		if st < 0 {
@@ -126,9 +170,28 @@ func (emu *Emulator) subtractTimers() {
 
 		emu.SoundTimer = byte(st)
 	}
+
+	emu.countFrames(sub)
+	emu.Present()
+
+	for k := 0; k < sub; k++ {
+		emu.sounding = soundBefore > k
+
+		for _, fn := range emu.frameFuncs {
+			fn(emu)
+		}
+	}
 }
 
-// Tick is the core Fetch-Decode-Execute loop of the emulator.
+// Sounding reports whether the buzzer was on during the frame that just ended,
+// that is whether SoundTimer was above zero before being decremented. Frame
+// funcs use it to generate sound, as they run after the decrement.
+func (emu *Emulator) Sounding() bool {
+	return emu.sounding
+}
+
+// Tick is the core Fetch-Decode-Execute loop of the emulator. It updates the
+// timers according to the wall clock before executing the next instruction.
 func (emu *Emulator) Tick() error {
 	if emu.lastUpdate.IsZero() {
 		emu.lastUpdate = time.Now()
@@ -136,10 +199,12 @@ func (emu *Emulator) Tick() error {
 
 	emu.subtractTimers()
 
-	logger := emu.logger
-	if logger == nil {
-		logger = log.New(io.Discard, "[emu] ", log.Ltime)
-	}
+	return emu.Step()
+}
+
+// Step executes a single instruction without touching the timers.
+func (emu *Emulator) Step() error {
+	logger := emu.log()
 
 	logger.Printf("fetch (PC=%#0x)\n", emu.PC)
 
@@ -168,6 +233,10 @@ func (emu *Emulator) Tick() error {
 
 	logger.Println("executing instruction: ", instr.String())
 
+	for _, fn := range emu.tracers {
+		fn(emu, instr)
+	}
+
 	// execute
 	execErr := emu.Execute(instr)
 	if execErr != nil {
@@ -177,10 +246,24 @@ func (emu *Emulator) Tick() error {
 	return nil
 }
 
+// RunFrame executes n instructions followed by a single timer tick, i.e. one
+// 60Hz frame at n instructions per frame. It is independent of the wall clock.
+func (emu *Emulator) RunFrame(n int) error {
+	for k := 0; k < n; k++ {
+		if err := emu.Step(); err != nil {
+			return err
+		}
+	}
+
+	emu.TickTimers()
+
+	return nil
+}
+
 // Fetch will read the instruction pointed at by the PC. It will do a bounds check.
 func (emu *Emulator) Fetch(numBytes int) ([]byte, error) {
 	pc := int(emu.PC)
-	ramSize := len(emu.RAM)
+	ramSize := emu.Memory.Size()
 
 	if pc+numBytes >= ramSize {
 		return nil, fmt.Errorf(
@@ -189,10 +272,5 @@ func (emu *Emulator) Fetch(numBytes int) ([]byte, error) {
 		)
 	}
 
-	read := make([]byte, numBytes)
-	for k := 0; k < numBytes; k++ {
-		read[k] = emu.RAM[pc+k]
-	}
-
-	return read, nil
+	return emu.readBytes(pc, numBytes)
 }
@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"errors"
 	"io"
+	"math/rand"
 	"strings"
 	"testing"
 )
@@ -108,6 +109,43 @@ func testROM(t *testing.T, rom []byte, golden []byte) {
 	checkSlicesMatch(t, data, golden)
 }
 
+func TestSetRand(t *testing.T) {
+	run := func(seed int64) string {
+		display, err := NewDebugDisplay(64, 32)
+		if err != nil {
+			t.Fatalf("could not make debug display: %v", err)
+		}
+
+		emu, err := NewEmulator(16, 4096, display, &StubKeyInputSource{})
+		if err != nil {
+			t.Fatalf("could not create emulator: %v", err)
+		}
+
+		emu.SetRand(rand.New(rand.NewSource(seed))) //nolint:gosec
+
+		if err := emu.Load(bytes.NewReader(testMaze)); err != nil {
+			t.Fatalf("could not load rom: %v", err)
+		}
+
+		const frames = 60
+		for k := 0; k < frames; k++ {
+			if err := emu.RunFrame(10); err != nil {
+				t.Fatalf("frame %d: %v", k, err)
+			}
+		}
+
+		return display.String()
+	}
+
+	if run(1) != run(1) {
+		t.Fatal("runs with the same seed should draw the same maze")
+	}
+
+	if run(1) == run(2) {
+		t.Fatal("runs with different seeds should draw different mazes")
+	}
+}
+
 func checkSlicesMatch(t *testing.T, data, golden []byte) {
 	t.Helper()
 
@@ -0,0 +1,349 @@
+package chipper
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// SmallGlyphSize is the size of a 4x5 glyph of the usual hex digit
+	// fonts FX29 points at.
+	SmallGlyphSize = 5
+
+	// BigGlyphSize is the size of an 8x10 glyph of SCHIP's big digits.
+	BigGlyphSize = 10
+
+	// maxGlyphSize is the tallest sprite DXYN draws.
+	maxGlyphSize = 15
+)
+
+const defaultFontID = "chip48"
+
+// Font is a set of glyphs for the hex digits, stored in the interpreter
+// area, big digits right after the small ones. Fonts are immutable, so
+// configs and emulators can share them: the accessors return copies.
+type Font struct {
+	id   string
+	name string
+
+	// small holds the glyphs of the digits 0 to F, of GlyphSize bytes each.
+	small []byte
+
+	// big optionally holds 8x10 glyphs, 10 bytes each, for the digits 0 to
+	// 9, as in SCHIP, or 0 to F.
+	big []byte
+}
+
+// NewFont returns a font with the given small glyphs, 16 of the same size,
+// and optionally 10 or 16 big ones of BigGlyphSize bytes. The glyphs are
+// copied.
+func NewFont(id, name string, small, big []byte) (*Font, error) {
+	f := &Font{id: id, name: name, small: bytes.Clone(small), big: bytes.Clone(big)}
+	if len(f.big) == 0 {
+		f.big = nil
+	}
+
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// ID names the font for FontByName, e.g. "vip".
+func (f *Font) ID() string {
+	return f.id
+}
+
+func (f *Font) Name() string {
+	return f.name
+}
+
+// Small returns a copy of the small glyphs.
+func (f *Font) Small() []byte {
+	return bytes.Clone(f.small)
+}
+
+// Big returns a copy of the big glyphs, nil if the font has none.
+func (f *Font) Big() []byte {
+	return bytes.Clone(f.big)
+}
+
+// GlyphSize is the size, and height, of each small glyph.
+func (f *Font) GlyphSize() int {
+	return len(f.small) / NumKeys
+}
+
+// Size is the number of bytes the font takes in memory.
+func (f *Font) Size() int {
+	return len(f.small) + len(f.big)
+}
+
+// validate checks the font has 16 small glyphs of the same size, and 10 or 16
+// big ones if any.
+func (f *Font) validate() error {
+	if f.id == "" {
+		return fmt.Errorf("font has no ID")
+	}
+
+	if n := len(f.small); n == 0 || n%NumKeys != 0 || n/NumKeys > maxGlyphSize {
+		return fmt.Errorf(
+			"font '%s': want 16 small glyphs of 1 to %d bytes, got %d bytes of small glyphs",
+			f.id, maxGlyphSize, n,
+		)
+	}
+
+	const decimalDigits = 10
+
+	switch len(f.big) {
+	case 0, decimalDigits * BigGlyphSize, NumKeys * BigGlyphSize:
+		return nil
+	default:
+		return fmt.Errorf(
+			"font '%s': want %d or %d bytes of big glyphs, got %d",
+			f.id, decimalDigits*BigGlyphSize, NumKeys*BigGlyphSize, len(f.big),
+		)
+	}
+}
+
+// builtinFonts are the fonts of the original interpreters, as collected by
+// Octo. The CHIP-48 font is the one most emulators use, and the one SCHIP
+// kept for its small digits.
+var builtinFonts = []*Font{
+	{
+		id:   "vip",
+		name: "COSMAC VIP",
+		small: []byte{
+			0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
+			0x60, 0x20, 0x20, 0x20, 0x70, // 1
+			0xF0, 0x10, 0xF0, 0x80, 0xF0, // 2
+			0xF0, 0x10, 0xF0, 0x10, 0xF0, // 3
+			0xA0, 0xA0, 0xF0, 0x20, 0x20, // 4
+			0xF0, 0x80, 0xF0, 0x10, 0xF0, // 5
+			0xF0, 0x80, 0xF0, 0x90, 0xF0, // 6
+			0xF0, 0x10, 0x10, 0x10, 0x10, // 7
+			0xF0, 0x90, 0xF0, 0x90, 0xF0, // 8
+			0xF0, 0x90, 0xF0, 0x10, 0xF0, // 9
+			0xF0, 0x90, 0xF0, 0x90, 0x90, // A
+			0xF0, 0x50, 0x70, 0x50, 0xF0, // B
+			0xF0, 0x80, 0x80, 0x80, 0xF0, // C
+			0xF0, 0x50, 0x50, 0x50, 0xF0, // D
+			0xF0, 0x80, 0xF0, 0x80, 0xF0, // E
+			0xF0, 0x80, 0xF0, 0x80, 0x80, // F
+		},
+	},
+	{
+		id:   "dream6800",
+		name: "DREAM 6800",
+		small: []byte{
+			0xE0, 0xA0, 0xA0, 0xA0, 0xE0, // 0
+			0x40, 0x40, 0x40, 0x40, 0x40, // 1
+			0xE0, 0x20, 0xE0, 0x80, 0xE0, // 2
+			0xE0, 0x20, 0xE0, 0x20, 0xE0, // 3
+			0x80, 0xA0, 0xA0, 0xE0, 0x20, // 4
+			0xE0, 0x80, 0xE0, 0x20, 0xE0, // 5
+			0xE0, 0x80, 0xE0, 0xA0, 0xE0, // 6
+			0xE0, 0x20, 0x20, 0x20, 0x20, // 7
+			0xE0, 0xA0, 0xE0, 0xA0, 0xE0, // 8
+			0xE0, 0xA0, 0xE0, 0x20, 0xE0, // 9
+			0xE0, 0xA0, 0xE0, 0xA0, 0xA0, // A
+			0xC0, 0xA0, 0xE0, 0xA0, 0xC0, // B
+			0xE0, 0x80, 0x80, 0x80, 0xE0, // C
+			0xC0, 0xA0, 0xA0, 0xA0, 0xC0, // D
+			0xE0, 0x80, 0xE0, 0x80, 0xE0, // E
+			0xE0, 0x80, 0xC0, 0x80, 0x80, // F
+		},
+	},
+	{
+		id:   "eti660",
+		name: "ETI-660",
+		small: []byte{
+			0xE0, 0xA0, 0xA0, 0xA0, 0xE0, // 0
+			0x20, 0x20, 0x20, 0x20, 0x20, // 1
+			0xE0, 0x20, 0xE0, 0x80, 0xE0, // 2
+			0xE0, 0x20, 0xE0, 0x20, 0xE0, // 3
+			0xA0, 0xA0, 0xE0, 0x20, 0x20, // 4
+			0xE0, 0x80, 0xE0, 0x20, 0xE0, // 5
+			0xE0, 0x80, 0xE0, 0xA0, 0xE0, // 6
+			0xE0, 0x20, 0x20, 0x20, 0x20, // 7
+			0xE0, 0xA0, 0xE0, 0xA0, 0xE0, // 8
+			0xE0, 0xA0, 0xE0, 0x20, 0xE0, // 9
+			0xE0, 0xA0, 0xE0, 0xA0, 0xA0, // A
+			0x80, 0x80, 0xE0, 0xA0, 0xE0, // B
+			0xE0, 0x80, 0x80, 0x80, 0xE0, // C
+			0x20, 0x20, 0xE0, 0xA0, 0xE0, // D
+			0xE0, 0x80, 0xE0, 0x80, 0xE0, // E
+			0xE0, 0x80, 0xE0, 0x80, 0x80, // F
+		},
+	},
+	{
+		id:    "chip48",
+		name:  "HP48 CHIP-48",
+		small: HexFont(),
+	},
+	{
+		id:    "schip",
+		name:  "SUPER-CHIP 1.1",
+		small: HexFont(),
+		big: []byte{
+			0x3C, 0x7E, 0xE7, 0xC3, 0xC3, 0xC3, 0xC3, 0xE7, 0x7E, 0x3C, // 0
+			0x18, 0x38, 0x58, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x3C, // 1
+			0x3E, 0x7F, 0xC3, 0x06, 0x0C, 0x18, 0x30, 0x60, 0xFF, 0xFF, // 2
+			0x3C, 0x7E, 0xC3, 0x03, 0x0E, 0x0E, 0x03, 0xC3, 0x7E, 0x3C, // 3
+			0x06, 0x0E, 0x1E, 0x36, 0x66, 0xC6, 0xFF, 0xFF, 0x06, 0x06, // 4
+			0xFF, 0xFF, 0xC0, 0xC0, 0xFC, 0xFE, 0x03, 0xC3, 0x7E, 0x3C, // 5
+			0x3E, 0x7C, 0xC0, 0xC0, 0xFC, 0xFE, 0xC3, 0xC3, 0x7E, 0x3C, // 6
+			0xFF, 0xFF, 0x03, 0x06, 0x0C, 0x18, 0x30, 0x60, 0x60, 0x60, // 7
+			0x3C, 0x7E, 0xC3, 0xC3, 0x7E, 0x7E, 0xC3, 0xC3, 0x7E, 0x3C, // 8
+			0x3C, 0x7E, 0xC3, 0xC3, 0x7F, 0x3F, 0x03, 0x03, 0x3E, 0x7C, // 9
+		},
+	},
+}
+
+// registeredFonts holds the fonts added with RegisterFont.
+var registeredFonts = struct {
+	mu    sync.RWMutex
+	fonts []*Font
+}{}
+
+// Fonts returns the built-in fonts followed by the registered ones.
+func Fonts() []*Font {
+	registeredFonts.mu.RLock()
+	defer registeredFonts.mu.RUnlock()
+
+	return append(append([]*Font(nil), builtinFonts...), registeredFonts.fonts...)
+}
+
+// FontByName returns the font with the given ID, ignoring case.
+func FontByName(id string) (*Font, bool) {
+	for _, f := range Fonts() {
+		if strings.EqualFold(f.id, id) {
+			return f, true
+		}
+	}
+
+	return nil, false
+}
+
+// RegisterFont makes f available by its ID, replacing any font registered
+// before with the same ID. Built-in fonts cannot be replaced.
+func RegisterFont(f *Font) error {
+	if err := f.validate(); err != nil {
+		return err
+	}
+
+	for _, b := range builtinFonts {
+		if strings.EqualFold(b.id, f.id) {
+			return fmt.Errorf("font '%s' is built in", f.id)
+		}
+	}
+
+	registeredFonts.mu.Lock()
+	defer registeredFonts.mu.Unlock()
+
+	for k, r := range registeredFonts.fonts {
+		if strings.EqualFold(r.id, f.id) {
+			registeredFonts.fonts[k] = f
+
+			return nil
+		}
+	}
+
+	registeredFonts.fonts = append(registeredFonts.fonts, f)
+
+	return nil
+}
+
+// DefaultFont returns the font of the DefaultConfig, CHIP-48's.
+func DefaultFont() *Font {
+	return fontByName(defaultFontID)
+}
+
+// fontByName returns the built-in font with the given ID, which must exist.
+func fontByName(id string) *Font {
+	f, _ := FontByName(id)
+
+	return f
+}
+
+// fontFile is the JSON format of LoadFont, glyphs in hex with any spacing:
+//
+//	{
+//	  "id": "mine",
+//	  "name": "My font",
+//	  "small": "F0 90 90 90 F0  20 60 20 20 70 ...",
+//	  "big": "3C 7E E7 C3 C3 C3 C3 E7 7E 3C ..."
+//	}
+type fontFile struct {
+	ID    string `json:"id"`
+	Name  string `json:"name,omitempty"`
+	Small string `json:"small"`
+	Big   string `json:"big,omitempty"`
+}
+
+// LoadFont reads a font in JSON, see fontFile.
+func LoadFont(r io.Reader) (*Font, error) {
+	return decodeFont(r, "")
+}
+
+// LoadFontFile reads a font from the JSON file at path. Without an ID, the
+// font is named after the file.
+func LoadFontFile(path string) (*Font, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open font: %w", err)
+	}
+	defer fd.Close()
+
+	f, err := decodeFont(fd, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return f, nil
+}
+
+func decodeFont(r io.Reader, defaultID string) (*Font, error) {
+	var file fontFile
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("could not decode font: %w", err)
+	}
+
+	id := file.ID
+	if id == "" {
+		id = defaultID
+	}
+
+	small, err := decodeGlyphs(file.Small, "small")
+	if err != nil {
+		return nil, err
+	}
+
+	big, err := decodeGlyphs(file.Big, "big")
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFont(id, file.Name, small, big)
+}
+
+// decodeGlyphs decodes hex glyph data, ignoring whitespace.
+func decodeGlyphs(src, name string) ([]byte, error) {
+	p, err := hex.DecodeString(strings.Join(strings.Fields(src), ""))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s glyphs: %w", name, err)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,211 @@
+package chipper_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/chippertest"
+)
+
+func TestFonts(t *testing.T) {
+	rom := chippertest.Assemble(t,
+		"LD V1, 1",
+		"LD F, V1",
+	)
+
+	for _, font := range chipper.Fonts() {
+		t.Run(font.ID(), func(t *testing.T) {
+			cfg := chipper.DefaultConfig()
+			cfg.Font = font
+			cfg.FontAddress = 0x50
+
+			emu := newConfigEmulator(t, cfg)
+			copy(emu.RAM[cfg.StartAddress:], rom)
+
+			for k := 0; k < 2; k++ {
+				if err := emu.Step(); err != nil {
+					t.Fatalf("could not step: %v", err)
+				}
+			}
+
+			got := emu.RAM[emu.Index : emu.Index+chipper.SmallGlyphSize]
+			if want := font.Small()[chipper.SmallGlyphSize : 2*chipper.SmallGlyphSize]; string(got) != string(want) {
+				t.Fatalf("want FX29 to point at the 1 of the font (% x), got % x", want, got)
+			}
+
+			big := emu.RAM[int(cfg.FontAddress)+len(font.Small()):][:len(font.Big())]
+			if string(big) != string(font.Big()) {
+				t.Fatal("want the big digits right after the small ones")
+			}
+		})
+	}
+
+	t.Run("schip big digits", func(t *testing.T) {
+		cfg, _ := chipper.PresetByName("schip")
+		if n := len(cfg.Font.Big()); n != 10*chipper.BigGlyphSize {
+			t.Fatalf("want 10 big digits in the SCHIP font, got %d bytes", n)
+		}
+	})
+
+	t.Run("immutable", func(t *testing.T) {
+		font := chipper.DefaultFont()
+
+		font.Small()[0] = 0
+		if font.Small()[0] != 0xF0 || chipper.DefaultFont().Small()[0] != 0xF0 {
+			t.Fatal("want Small to return a copy")
+		}
+
+		small := chipper.HexFont()
+
+		f, err := chipper.NewFont("copy", "", small, nil)
+		if err != nil {
+			t.Fatalf("could not create font: %v", err)
+		}
+
+		small[0] = 0
+		if f.Small()[0] != 0xF0 {
+			t.Fatal("want NewFont to copy the glyphs")
+		}
+	})
+
+	t.Run("tall glyphs", func(t *testing.T) {
+		small := make([]byte, 8*chipper.NumKeys)
+		for k := range small {
+			small[k] = byte(k / 8)
+		}
+
+		font, err := chipper.NewFont("tall", "", small, nil)
+		if err != nil {
+			t.Fatalf("could not create font: %v", err)
+		}
+
+		cfg := chipper.DefaultConfig()
+		cfg.Font = font
+
+		emu := newConfigEmulator(t, cfg)
+		copy(emu.RAM[cfg.StartAddress:], rom)
+
+		for k := 0; k < 2; k++ {
+			if err := emu.Step(); err != nil {
+				t.Fatalf("could not step: %v", err)
+			}
+		}
+
+		if want := int(cfg.FontAddress) + 8; int(emu.Index) != want || emu.RAM[emu.Index] != 1 {
+			t.Fatalf("want FX29 to step over 8-byte glyphs to %#03x, got %#03x", want, emu.Index)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := chipper.NewFont("short", "", []byte{0xF0}, nil); err == nil || !strings.Contains(err.Error(), "small glyphs") {
+			t.Fatalf("want an error about the small glyphs, got %v", err)
+		}
+
+		cfg := chipper.DefaultConfig()
+		cfg.Font = &chipper.Font{}
+
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "no ID") {
+			t.Fatalf("want an error about the missing ID, got %v", err)
+		}
+	})
+
+	t.Run("presets share fonts", func(t *testing.T) {
+		a, _ := chipper.PresetByName("vip")
+		b, _ := chipper.PresetByName("vip")
+
+		if a != b {
+			t.Fatal("want a preset to compare equal to itself")
+		}
+	})
+}
+
+func TestRegisterFont(t *testing.T) {
+	font, err := chipper.NewFont("test-boxes", "Boxes", bytes.Repeat([]byte{0xF0, 0x90, 0x90, 0x90, 0xF0}, chipper.NumKeys), nil)
+	if err != nil {
+		t.Fatalf("could not create font: %v", err)
+	}
+
+	if err := chipper.RegisterFont(font); err != nil {
+		t.Fatalf("could not register font: %v", err)
+	}
+
+	if got, ok := chipper.FontByName("TEST-BOXES"); !ok || got != font {
+		t.Fatalf("want the registered font, got %v", got)
+	}
+
+	if err := chipper.RegisterFont(font); err != nil {
+		t.Fatalf("want a font to replace one with the same ID, got %v", err)
+	}
+
+	n := 0
+
+	for _, f := range chipper.Fonts() {
+		if f.ID() == "test-boxes" {
+			n++
+		}
+	}
+
+	if n != 1 {
+		t.Fatalf("want the font listed once, got %d", n)
+	}
+
+	vip, _ := chipper.NewFont("vip", "", chipper.HexFont(), nil)
+	if err := chipper.RegisterFont(vip); err == nil || !strings.Contains(err.Error(), "built in") {
+		t.Fatalf("want built-in fonts kept, got %v", err)
+	}
+}
+
+func TestLoadFont(t *testing.T) {
+	small := strings.Repeat("F0 90 90 90 F0\n", chipper.NumKeys)
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "boxes.json")
+
+		data := `{"name": "Boxes", "small": "` + strings.ReplaceAll(small, "\n", " ") + `"}`
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatalf("could not write font: %v", err)
+		}
+
+		font, err := chipper.LoadFontFile(path)
+		if err != nil {
+			t.Fatalf("could not load font: %v", err)
+		}
+
+		if font.ID() != "boxes" || font.Name() != "Boxes" || font.Small()[5] != 0xF0 || font.Big() != nil {
+			t.Fatalf("unexpected font: %+v", font)
+		}
+
+		cfg := chipper.DefaultConfig()
+		cfg.Font = font
+
+		emu := newConfigEmulator(t, cfg)
+		if got := emu.RAM[cfg.FontAddress : int(cfg.FontAddress)+font.Size()]; string(got) != string(font.Small()) {
+			t.Fatalf("want the loaded font in RAM, got % x", got)
+		}
+	})
+
+	cases := []struct {
+		label string
+		data  string
+		want  string
+	}{
+		{"bad hex", `{"id": "x", "small": "F0 9"}`, "could not decode small glyphs"},
+		{"short", `{"id": "x", "small": "F0 90 90 90 F0"}`, "bytes of small glyphs"},
+		{"big", `{"id": "x", "small": "` + strings.ReplaceAll(small, "\n", "") + `", "big": "FF"}`, "bytes of big glyphs"},
+		{"no id", `{"small": "` + strings.ReplaceAll(small, "\n", "") + `"}`, "no ID"},
+		{"unknown field", `{"id": "x", "glyphs": ""}`, "unknown field"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.label, func(t *testing.T) {
+			_, err := chipper.LoadFont(strings.NewReader(c.data))
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Fatalf("want an error about '%s', got %v", c.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package chipper
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"sync"
+)
+
+// ColorHalf is the palette index of the half-lit pixels of PersistBlend.
+const ColorHalf = ColorSet + 1
+
+// Persistence selects how a Frame combines the last two presented frames, to
+// hide the flicker of games erasing and redrawing their sprites.
+type Persistence int
+
+const (
+	PersistNone  Persistence = iota
+	PersistOr                // a pixel is set if it is set in either frame.
+	PersistBlend             // pixels only set in the previous frame are half-lit.
+)
+
+// ParsePersistence returns the Persistence named by s.
+func ParsePersistence(s string) (Persistence, error) {
+	switch strings.ToLower(s) {
+	case "", "none":
+		return PersistNone, nil
+	case "or":
+		return PersistOr, nil
+	case "blend":
+		return PersistBlend, nil
+	default:
+		return 0, fmt.Errorf("unknown persistence '%s', want one of: none, or, blend", s)
+	}
+}
+
+// Frame is a complete picture of the display, published at a 60Hz timer
+// boundary rather than in the middle of a sequence of draws.
+type Frame struct {
+	Number   uint64  // timer ticks elapsed when it was presented.
+	Current  *Bitmap // owned by the caller.
+	Previous *Bitmap // the frame presented before, blank for the first one.
+}
+
+// Index returns the palette index of the pixel at (x, y): ColorClear,
+// ColorSet or, with PersistBlend, ColorHalf.
+func (f Frame) Index(x, y int, p Persistence) uint8 {
+	switch {
+	case f.Current.Pixel(x, y):
+		return ColorSet
+	case p == PersistNone || !f.Previous.Pixel(x, y):
+		return ColorClear
+	case p == PersistOr:
+		return ColorSet
+	default:
+		return ColorHalf
+	}
+}
+
+// Bitmap returns the frame as a Bitmap. Being monochrome, PersistBlend is
+// treated as PersistOr.
+func (f Frame) Bitmap(p Persistence) *Bitmap {
+	w, h := f.Current.Size()
+	b, _ := NewBitmap(w, h)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if f.Index(x, y, p) != ColorClear {
+				b.SetPixel(x, y, true)
+			}
+		}
+	}
+
+	b.ResetDirty()
+
+	return b
+}
+
+// Image returns the frame as a paletted image using the display colors, and
+// their average for half-lit pixels.
+func (f Frame) Image(p Persistence) *image.Paletted {
+	w, h := f.Current.Size()
+	palette := color.Palette{
+		f.Current.ColorClear(),
+		f.Current.ColorSet(),
+		average(f.Current.ColorClear(), f.Current.ColorSet()),
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, w, h), palette)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetColorIndex(x, y, f.Index(x, y, p))
+		}
+	}
+
+	return img
+}
+
+func average(a, b color.Color) color.Color {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+
+	return color.RGBA64{
+		R: uint16((r1 + r2) / 2), //nolint:gosec,mnd
+		G: uint16((g1 + g2) / 2), //nolint:gosec,mnd
+		B: uint16((b1 + b2) / 2), //nolint:gosec,mnd
+		A: uint16((a1 + a2) / 2), //nolint:gosec,mnd
+	}
+}
+
+// screen holds the last two presented frames.
+type screen struct {
+	mu    sync.Mutex
+	front *Bitmap
+	prev  *Bitmap
+	count uint64
+}
+
+// Present publishes the current contents of the display as a complete frame.
+// It is called on every timer tick, so callers stepping the emulator
+// themselves get it through TickTimers.
+func (emu *Emulator) Present() {
+	b := emu.Display.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	s := &emu.screen
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	back := s.prev
+	if back == nil || back.width != w || back.height != h {
+		back, _ = NewBitmap(w, h)
+	}
+
+	fb, isFramebuffer := emu.Display.(Framebuffer)
+	set := emu.Display.ColorSet()
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var lit bool
+			if isFramebuffer {
+				lit = fb.Pixel(x, y)
+			} else {
+				lit = ColorEq(emu.Display.At(x+b.Min.X, y+b.Min.Y), set)
+			}
+
+			back.SetPixel(x, y, lit)
+		}
+	}
+
+	back.palette = color.Palette{emu.Display.ColorClear(), set}
+
+	s.prev, s.front = s.front, back
+}
+
+func (emu *Emulator) countFrames(n int) {
+	emu.screen.mu.Lock()
+	defer emu.screen.mu.Unlock()
+
+	emu.screen.count += uint64(n) //nolint:gosec
+}
+
+// Frame returns the last presented frame. Unlike Display, it is safe to call
+// while the emulator runs in another goroutine.
+func (emu *Emulator) Frame() Frame {
+	s := &emu.screen
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.front == nil {
+		b := emu.Display.Bounds()
+		blank, _ := NewBitmap(b.Dx(), b.Dy())
+
+		return Frame{Current: blank, Previous: blank.clone()}
+	}
+
+	f := Frame{Number: s.count, Current: s.front.clone()}
+
+	if s.prev != nil && s.prev.width == s.front.width && s.prev.height == s.front.height {
+		f.Previous = s.prev.clone()
+	} else {
+		f.Previous, _ = NewBitmap(s.front.width, s.front.height)
+	}
+
+	return f
+}
+
+// FrameCount returns the number of frames elapsed, i.e. timer ticks.
+func (emu *Emulator) FrameCount() uint64 {
+	emu.screen.mu.Lock()
+	defer emu.screen.mu.Unlock()
+
+	return emu.screen.count
+}
+
+func (b *Bitmap) clone() *Bitmap {
+	c := *b
+	c.words = append([]uint64(nil), b.words...)
+	c.palette = append(color.Palette(nil), b.palette...)
+
+	return &c
+}
@@ -0,0 +1,110 @@
+package chipper
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPresent(t *testing.T) {
+	// draw the font's 0 sprite, then erase it, one instruction per frame.
+	rom := []byte{
+		0xA0, 0x00, // LD I, 0x000
+		0xD0, 0x15, // DRW V0, V1, 5
+		0xD0, 0x15, // DRW V0, V1, 5
+		0x12, 0x06, // JP 0x206
+	}
+
+	emu := newDrawTestEmulator(t, nil, false)
+	if err := emu.Load(bytes.NewReader(rom)); err != nil {
+		t.Fatalf("could not load rom: %v", err)
+	}
+
+	frame := emu.Frame()
+	if frame.Number != 0 || frame.Current.Pixel(0, 0) {
+		t.Fatal("want a blank frame before the first present")
+	}
+
+	if err := emu.Step(); err != nil {
+		t.Fatalf("could not step: %v", err)
+	}
+
+	if err := emu.Step(); err != nil {
+		t.Fatalf("could not step: %v", err)
+	}
+
+	if emu.Frame().Current.Pixel(0, 0) {
+		t.Fatal("drawing should not show before the frame is presented")
+	}
+
+	emu.TickTimers()
+
+	drawn := emu.Frame()
+	if drawn.Number != 1 || emu.FrameCount() != 1 {
+		t.Fatalf("want frame 1, got %d (count %d)", drawn.Number, emu.FrameCount())
+	}
+
+	if !drawn.Current.Pixel(0, 0) || drawn.Previous.Pixel(0, 0) {
+		t.Fatal("want the sprite in the current frame only")
+	}
+
+	if err := emu.RunFrame(1); err != nil {
+		t.Fatalf("could not run frame: %v", err)
+	}
+
+	erased := emu.Frame()
+	if erased.Number != 2 {
+		t.Fatalf("want frame 2, got %d", erased.Number)
+	}
+
+	tests := []struct {
+		p    Persistence
+		want uint8
+	}{
+		{PersistNone, ColorClear},
+		{PersistOr, ColorSet},
+		{PersistBlend, ColorHalf},
+	}
+
+	for _, tc := range tests {
+		if got := erased.Index(0, 0, tc.p); got != tc.want {
+			t.Fatalf("persistence %d: want index %d, got %d", tc.p, tc.want, got)
+		}
+
+		if got := erased.Image(tc.p).ColorIndexAt(0, 0); got != tc.want {
+			t.Fatalf("persistence %d: want image index %d, got %d", tc.p, tc.want, got)
+		}
+
+		if got := erased.Bitmap(tc.p).Pixel(0, 0); got != (tc.want != ColorClear) {
+			t.Fatalf("persistence %d: want bitmap pixel %v, got %v", tc.p, tc.want != ColorClear, got)
+		}
+	}
+
+	// the caller owns the frame.
+	erased.Previous.Clear()
+
+	if !emu.Frame().Previous.Pixel(0, 0) {
+		t.Fatal("changing a frame should not change the emulator's copy")
+	}
+}
+
+func TestParsePersistence(t *testing.T) {
+	for s, want := range map[string]Persistence{
+		"":      PersistNone,
+		"none":  PersistNone,
+		"OR":    PersistOr,
+		"blend": PersistBlend,
+	} {
+		got, err := ParsePersistence(s)
+		if err != nil {
+			t.Fatalf("could not parse '%s': %v", s, err)
+		}
+
+		if got != want {
+			t.Fatalf("'%s': want %d, got %d", s, want, got)
+		}
+	}
+
+	if _, err := ParsePersistence("fade"); err == nil {
+		t.Fatal("expected an error for an unknown persistence")
+	}
+}
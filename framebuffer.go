@@ -0,0 +1,254 @@
+package chipper
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/bits"
+	"strings"
+)
+
+// Framebuffer is a monochrome display that draws sprites itself instead of
+// going pixel by pixel through At and Set. The emulator uses it whenever the
+// Display implements it.
+type Framebuffer interface {
+	// Size returns the width and height in pixels.
+	Size() (int, int)
+
+	// Pixel reports whether the pixel at (x, y) is set.
+	Pixel(x, y int) bool
+
+	// DrawSprite XORs rows onto the screen with its top-left corner at (x, y).
+	// Each row is 8 pixels wide, most significant bit first. Pixels past the
+	// right and bottom edges are clipped, or wrap around if wrap is set. It
+	// reports whether any set pixel was cleared.
+	DrawSprite(x, y int, rows []byte, wrap bool) bool
+
+	// Clear clears every pixel.
+	Clear()
+
+	// Dirty returns the smallest rectangle holding every pixel changed since
+	// the last call to ResetDirty.
+	Dirty() image.Rectangle
+
+	// ResetDirty marks the whole screen as unchanged.
+	ResetDirty()
+}
+
+const wordBits = 64
+
+// Bitmap is a Framebuffer storing 64 pixels per word. It also implements
+// Display, so it can be used as an image.Image.
+type Bitmap struct {
+	width   int
+	height  int
+	stride  int      // words per row.
+	words   []uint64 // row-major, leftmost pixel in the most significant bit.
+	dirty   image.Rectangle
+	palette color.Palette
+}
+
+func NewBitmap(w, h int) (*Bitmap, error) {
+	if w < 0 || h < 0 {
+		return nil, fmt.Errorf("width and height must be >= 0 (w=%d, h=%d)", w, h)
+	}
+
+	stride := (w + wordBits - 1) / wordBits
+
+	return &Bitmap{
+		width:   w,
+		height:  h,
+		stride:  stride,
+		words:   make([]uint64, stride*h),
+		palette: color.Palette{color.Black, color.White},
+	}, nil
+}
+
+func (b *Bitmap) Size() (int, int) {
+	return b.width, b.height
+}
+
+func (b *Bitmap) inBounds(x, y int) bool {
+	return x >= 0 && y >= 0 && x < b.width && y < b.height
+}
+
+func (b *Bitmap) Pixel(x, y int) bool {
+	if !b.inBounds(x, y) {
+		return false
+	}
+
+	word := b.words[y*b.stride+x/wordBits]
+
+	return word>>(wordBits-1-x%wordBits)&1 == 1
+}
+
+// SetPixel sets or clears the pixel at (x, y), ignoring points out of bounds.
+func (b *Bitmap) SetPixel(x, y int, v bool) {
+	if !b.inBounds(x, y) || b.Pixel(x, y) == v {
+		return
+	}
+
+	b.words[y*b.stride+x/wordBits] ^= 1 << (wordBits - 1 - x%wordBits)
+	b.markDirty(image.Rect(x, y, x+1, y+1))
+}
+
+func (b *Bitmap) DrawSprite(x, y int, rows []byte, wrap bool) bool {
+	const spriteWidth = 8
+
+	if b.width == 0 || b.height == 0 {
+		return false
+	}
+
+	x, y = mod(x, b.width), mod(y, b.height)
+	fits := min(spriteWidth, b.width-x)
+	collided := false
+
+	for k, row := range rows {
+		ypos := y + k
+		if ypos >= b.height {
+			if !wrap {
+				break
+			}
+
+			ypos %= b.height
+		}
+
+		if b.xorBits(ypos, x, row&^(0xFF>>fits)) {
+			collided = true
+		}
+
+		if wrap && fits < spriteWidth && b.xorBits(ypos, 0, row<<fits) {
+			collided = true
+		}
+	}
+
+	return collided
+}
+
+// xorBits XORs the 8 pixels of row, which must fit in the screen, at (x, y).
+func (b *Bitmap) xorBits(y, x int, row byte) bool {
+	if row == 0 {
+		return false
+	}
+
+	const shift = wordBits - 8
+
+	line := b.words[y*b.stride : (y+1)*b.stride]
+	v := uint64(row) << shift
+	w, off := x/wordBits, x%wordBits
+
+	collided := false
+
+	if m := v >> off; m != 0 {
+		collided = line[w]&m != 0
+		line[w] ^= m
+	}
+
+	if off > shift {
+		if m := v << (wordBits - off); m != 0 {
+			collided = collided || line[w+1]&m != 0
+			line[w+1] ^= m
+		}
+	}
+
+	left := x + bits.LeadingZeros8(row)
+	right := x + 8 - bits.TrailingZeros8(row) //nolint:mnd
+	b.markDirty(image.Rect(left, y, right, y+1))
+
+	return collided
+}
+
+func (b *Bitmap) Clear() {
+	for k, w := range b.words {
+		if w != 0 {
+			b.words[k] = 0
+			b.markDirty(image.Rect(0, k/b.stride, b.width, k/b.stride+1))
+		}
+	}
+}
+
+func (b *Bitmap) markDirty(r image.Rectangle) {
+	b.dirty = b.dirty.Union(r)
+}
+
+func (b *Bitmap) Dirty() image.Rectangle {
+	return b.dirty
+}
+
+func (b *Bitmap) ResetDirty() {
+	b.dirty = image.Rectangle{}
+}
+
+func (b *Bitmap) ColorClear() color.Color {
+	return b.palette[ColorClear]
+}
+
+func (b *Bitmap) ColorSet() color.Color {
+	return b.palette[ColorSet]
+}
+
+func (b *Bitmap) ColorModel() color.Model {
+	return b.palette
+}
+
+func (b *Bitmap) Bounds() image.Rectangle {
+	return image.Rect(0, 0, b.width, b.height)
+}
+
+func (b *Bitmap) At(x, y int) color.Color {
+	if b.Pixel(x, y) {
+		return b.ColorSet()
+	}
+
+	return b.ColorClear()
+}
+
+func (b *Bitmap) Set(x, y int, c color.Color) {
+	b.SetPixel(x, y, ColorEq(c, b.ColorSet()))
+}
+
+// Bytes returns the pixels packed 8 to a byte, row by row, leftmost pixel in
+// the most significant bit. Rows are padded to whole bytes.
+func (b *Bitmap) Bytes() []byte {
+	rowBytes := (b.width + 7) / 8 //nolint:mnd
+	p := make([]byte, 0, rowBytes*b.height)
+
+	for y := 0; y < b.height; y++ {
+		for k := 0; k < rowBytes; k++ {
+			word := b.words[y*b.stride+k/8]
+			p = append(p, byte(word>>(wordBits-8-8*(k%8)))) //nolint:mnd
+		}
+	}
+
+	return p
+}
+
+// String draws the screen as a grid, 'o' for set pixels.
+func (b *Bitmap) String() string {
+	sb := &strings.Builder{}
+	border := "     " + strings.Repeat("-", b.width*2) //nolint:mnd
+
+	sb.WriteString(border + "\n")
+
+	for y := 0; y < b.height; y++ {
+		fmt.Fprintf(sb, " %2d |", y)
+
+		for x := 0; x < b.width; x++ {
+			if b.Pixel(x, y) {
+				sb.WriteString(" o")
+			} else {
+				sb.WriteString(" .")
+			}
+		}
+
+		sb.WriteString("|\n")
+	}
+
+	sb.WriteString(border)
+
+	return sb.String()
+}
+
+func mod(a, n int) int {
+	return (a%n + n) % n
+}
@@ -0,0 +1,176 @@
+package chipper
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+)
+
+func TestBitmap(t *testing.T) {
+	t.Run("draw and collide", func(tt *testing.T) {
+		b, err := NewBitmap(64, 32)
+		if err != nil {
+			tt.Fatalf("could not create bitmap: %v", err)
+		}
+
+		if b.DrawSprite(3, 4, []byte{0b1010_0000, 0b0100_0000}, false) {
+			tt.Fatal("drawing on a blank screen should not collide")
+		}
+
+		for _, p := range [][2]int{{3, 4}, {5, 4}, {4, 5}} {
+			if !b.Pixel(p[0], p[1]) {
+				tt.Fatalf("pixel %v should be set", p)
+			}
+		}
+
+		if got, want := b.Dirty(), image.Rect(3, 4, 6, 6); got != want {
+			tt.Fatalf("want dirty %v, got %v", want, got)
+		}
+
+		b.ResetDirty()
+
+		if !b.DrawSprite(5, 4, []byte{0b1000_0000}, false) {
+			tt.Fatal("erasing a pixel should collide")
+		}
+
+		if b.Pixel(5, 4) {
+			tt.Fatal("pixel (5, 4) should have been erased")
+		}
+
+		if got, want := b.Dirty(), image.Rect(5, 4, 6, 5); got != want {
+			tt.Fatalf("want dirty %v, got %v", want, got)
+		}
+
+		b.Clear()
+
+		if b.Pixel(3, 4) || b.Pixel(4, 5) {
+			tt.Fatal("clear should clear every pixel")
+		}
+	})
+
+	t.Run("clip and wrap", func(tt *testing.T) {
+		for _, wrap := range []bool{false, true} {
+			b, err := NewBitmap(64, 32)
+			if err != nil {
+				tt.Fatalf("could not create bitmap: %v", err)
+			}
+
+			b.DrawSprite(60, 31, []byte{0xFF, 0xFF}, wrap)
+
+			want := map[[2]int]bool{
+				{60, 31}: true, {63, 31}: true,
+				{0, 31}: wrap, {3, 31}: wrap, {4, 31}: false,
+				{60, 0}: wrap, {0, 0}: wrap,
+			}
+
+			for p, set := range want {
+				if b.Pixel(p[0], p[1]) != set {
+					tt.Fatalf("wrap=%v: pixel %v should be %v", wrap, p, set)
+				}
+			}
+		}
+	})
+
+	t.Run("across words", func(tt *testing.T) {
+		b, err := NewBitmap(128, 64)
+		if err != nil {
+			tt.Fatalf("could not create bitmap: %v", err)
+		}
+
+		b.DrawSprite(60, 0, []byte{0xFF}, false)
+
+		for x := 59; x <= 68; x++ {
+			if b.Pixel(x, 0) != (x >= 60 && x < 68) {
+				tt.Fatalf("pixel %d is wrong", x)
+			}
+		}
+
+		if !b.DrawSprite(67, 0, []byte{0x80}, false) {
+			tt.Fatal("expected a collision in the second word")
+		}
+	})
+
+	t.Run("bytes", func(tt *testing.T) {
+		b, err := NewBitmap(72, 2)
+		if err != nil {
+			tt.Fatalf("could not create bitmap: %v", err)
+		}
+
+		b.DrawSprite(0, 0, []byte{0xA5}, false)
+		b.DrawSprite(68, 1, []byte{0xF0}, false)
+
+		got := b.Bytes()
+		want := make([]byte, 18)
+		want[0] = 0xA5
+		want[17] = 0x0F
+
+		if string(got) != string(want) {
+			tt.Fatalf("want % x, got % x", want, got)
+		}
+	})
+}
+
+// plainDisplay hides the Framebuffer methods of the display it wraps, so the
+// emulator draws pixel by pixel.
+type plainDisplay struct {
+	Display
+}
+
+// TestFramebufferMatchesDisplay draws random sprites through both the
+// Framebuffer and the pixel by pixel paths, checking they agree.
+func TestFramebufferMatchesDisplay(t *testing.T) {
+	rng := rand.New(rand.NewSource(1)) //nolint:gosec
+
+	for _, wrap := range []bool{false, true} {
+		fast := newDrawTestEmulator(t, nil, wrap)
+		slow := newDrawTestEmulator(t, func(d Display) Display { return plainDisplay{d} }, wrap)
+
+		for k := 0; k < 500; k++ {
+			n := rng.Intn(16)
+			x, y := byte(rng.Intn(256)), byte(rng.Intn(256))
+			sprite := make([]byte, n)
+			rng.Read(sprite)
+
+			for _, emu := range []*Emulator{fast, slow} {
+				copy(emu.RAM[0x300:], sprite)
+				emu.Index = 0x300
+				emu.V[0], emu.V[1] = x, y
+
+				if err := emu.drawSpriteInXY(0, 1, n); err != nil {
+					t.Fatalf("could not draw: %v", err)
+				}
+			}
+
+			if fast.V[0xF] != slow.V[0xF] {
+				t.Fatalf("wrap=%v, sprite %d: VF differs, %d != %d", wrap, k, fast.V[0xF], slow.V[0xF])
+			}
+
+			if fast.Display.String() != slow.Display.String() {
+				t.Fatalf("wrap=%v, sprite %d: displays differ", wrap, k)
+			}
+		}
+	}
+}
+
+func newDrawTestEmulator(t *testing.T, wrapDisplay func(Display) Display, wrap bool) *Emulator {
+	t.Helper()
+
+	debug, err := NewDebugDisplay(64, 32)
+	if err != nil {
+		t.Fatalf("could not create display: %v", err)
+	}
+
+	var display Display = debug
+	if wrapDisplay != nil {
+		display = wrapDisplay(display)
+	}
+
+	emu, err := NewEmulator(16, 4096, display, &StubKeyInputSource{})
+	if err != nil {
+		t.Fatalf("could not create emulator: %v", err)
+	}
+
+	emu.Quirks.Wrap = wrap
+
+	return emu
+}
@@ -0,0 +1,228 @@
+package chipper
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// The fuzz targets check the emulator never panics, whatever it is fed, and
+// that PC and I keep their invariants, on every preset and on small machines
+// whose memory ends right after the program start. Run one with, for example:
+//
+//	go test -run '^$' -fuzz FuzzExecute
+//
+// Inputs that fail are saved in testdata/fuzz/<target> by the go tool, and
+// are run again as regular tests by go test from then on.
+
+// fuzzStateSize is the number of bytes a machine state is read from: the
+// registers, the timers, I, the PC, the quirks and the stack.
+const fuzzStateSize = RegisterCount + 2 + 2 + 2 + 1 + 4
+
+func FuzzDecode(f *testing.F) {
+	for _, seed := range [][]byte{{}, {0x00}, {0x00, 0xE0}, {0x8F, 0x1E}, {0xF3, 0x65}, {0x9A, 0xB1}, {0xFF, 0xFF, 0xFF}} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		instr, err := Decode(data)
+		_ = instr.String()
+
+		if len(data) < InstructionSize {
+			if err == nil {
+				t.Fatalf("decoding %d bytes should fail", len(data))
+			}
+
+			return
+		}
+
+		if len(instr.Operands) != 3 {
+			t.Fatalf("want 3 operands, got %d", len(instr.Operands))
+		}
+
+		if (err != nil) != (instr.Op == Unknown) {
+			t.Fatalf("op %s with error %v", instr.Op, err)
+		}
+
+		if err != nil && err.Error() == "" {
+			t.Fatal("error with no message")
+		}
+
+		digits := make([]int, 0, len(data))
+		for _, b := range data {
+			digits = append(digits, int(b))
+		}
+
+		_ = DetermineOpcode(digits)
+	})
+}
+
+func FuzzExecute(f *testing.F) {
+	state := make([]byte, fuzzStateSize)
+
+	for k, op := range []uint16{0x00EE, 0x2FFF, 0xBFFF, 0xDFFF, 0xF01E, 0xF033, 0xFF55, 0xFF65, 0x8FF6, 0xFF29, 0xAFFF} {
+		f.Add(op, uint16(k), state)
+	}
+
+	f.Fuzz(func(t *testing.T, op, machine uint16, state []byte) {
+		emu := fuzzEmulator(t, machine)
+		setFuzzState(emu, state)
+
+		emu.RAM[emu.PC] = byte(op >> 8) //nolint:mnd
+		emu.RAM[emu.PC+1] = byte(op)
+
+		index := emu.Index
+		err := emu.Step()
+
+		checkInvariants(t, emu, err)
+
+		if !setsIndex(emu.LastInstruction.Op) && emu.Index != index {
+			t.Fatalf("%s changed I from %#03x to %#03x", emu.LastInstruction.Op, index, emu.Index)
+		}
+	})
+}
+
+func FuzzROM(f *testing.F) {
+	f.Add(uint16(0), []byte{0x12, 0x00})
+	f.Add(uint16(0), testMaze)
+	f.Add(uint16(0), testParticle)
+	f.Add(uint16(len(Presets())), testMaze)
+
+	f.Fuzz(func(t *testing.T, machine uint16, rom []byte) {
+		const (
+			frames = 30
+			ipf    = 10
+		)
+
+		emu := fuzzEmulator(t, machine)
+		if err := emu.Load(bytes.NewReader(rom)); err != nil {
+			return
+		}
+
+		for k := 0; k < frames*ipf; k++ {
+			err := emu.Step()
+			checkInvariants(t, emu, err)
+
+			if err != nil {
+				return
+			}
+
+			if k%ipf == ipf-1 {
+				emu.TickTimers()
+			}
+		}
+	})
+}
+
+// fuzzEmulator returns an emulator for the machine fuzzConfig reads from m.
+func fuzzEmulator(t *testing.T, m uint16) *Emulator {
+	t.Helper()
+
+	cfg := fuzzConfig(m)
+
+	display, err := NewBitmap(cfg.Width, cfg.Height)
+	if err != nil {
+		t.Fatalf("could not create display: %v", err)
+	}
+
+	emu, err := NewEmulatorWithConfig(cfg, display, &StubKeyInputSource{})
+	if err != nil {
+		t.Fatalf("could not create emulator: %v", err)
+	}
+
+	emu.SetRand(rand.New(rand.NewSource(1))) //nolint:gosec
+
+	return emu
+}
+
+// fuzzConfig reads a machine from m: its low nibble picks a preset, or past
+// them a machine with just enough RAM to hold the program start plus the top
+// byte, the next bits the out of range policy and where the font goes.
+func fuzzConfig(m uint16) Config {
+	presets := Presets()
+
+	cfg := DefaultConfig()
+	if k := int(m&0xF) % (len(presets) + 1); k < len(presets) {
+		cfg = presets[k]
+	} else {
+		cfg.RAMSize = int(cfg.StartAddress) + InstructionSize + int(m>>8) //nolint:mnd
+	}
+
+	cfg.OutOfRange = OutOfRange(int(m>>4&0x3) % (int(OutOfRangeIgnore) + 1)) //nolint:mnd
+
+	// keep the preset's font address, or move the font around the
+	// interpreter area.
+	if addr := uint16(m>>6&0x3) * 0x50; addr != 0 { //nolint:mnd
+		cfg.FontAddress = addr
+	}
+
+	return cfg
+}
+
+// setFuzzState reads the machine state from p, zero-padded to fuzzStateSize.
+func setFuzzState(emu *Emulator, p []byte) {
+	state := make([]byte, fuzzStateSize)
+	copy(state, p)
+
+	copy(emu.V[:], state)
+	state = state[RegisterCount:]
+
+	emu.DelayTimer, emu.SoundTimer = state[0], state[1]
+	// I, as the PC, always points into memory.
+	index := int(state[2])<<8 | int(state[3])
+	emu.Index = uint16(index % emu.Memory.Size())
+
+	// keep the PC where the instruction can be fetched.
+	pc := int(state[4])<<8 | int(state[5])
+	emu.PC = uint16(pc % (len(emu.RAM) - InstructionSize))
+
+	quirks := state[6]
+	emu.Quirks = Quirks{
+		Shift:                 quirks&(1<<0) != 0,
+		MemoryIncrementByX:    quirks&(1<<1) != 0,
+		MemoryLeaveIUnchanged: quirks&(1<<2) != 0,
+		Wrap:                  quirks&(1<<3) != 0,
+		Jump:                  quirks&(1<<4) != 0,
+		VBlank:                quirks&(1<<5) != 0,
+		Logic:                 quirks&(1<<6) != 0,
+	}
+
+	for _, b := range state[7:] {
+		if b == 0 {
+			break
+		}
+
+		// return addresses, as pushed by CALL, are in memory.
+		_ = emu.Stack.Push(uint16(int(b) << 4 % emu.Memory.Size())) //nolint:mnd
+	}
+}
+
+// checkInvariants fails t if PC or I left memory, or err is not a proper
+// error.
+func checkInvariants(t *testing.T, emu *Emulator, err error) {
+	t.Helper()
+
+	// a skip over the last instruction is the one way past the end, which
+	// the next Fetch reports as io.EOF.
+	if int(emu.PC) >= emu.Memory.Size()+InstructionSize {
+		t.Fatalf("%s moved PC out of memory to %#03x", emu.LastInstruction, emu.PC)
+	}
+
+	if int(emu.Index) >= emu.Memory.Size() {
+		t.Fatalf("%s moved I out of memory to %#03x", emu.LastInstruction, emu.Index)
+	}
+
+	if err != nil && err.Error() == "" {
+		t.Fatalf("%s returned an error with no message", emu.LastInstruction)
+	}
+}
+
+// setsIndex reports whether op is allowed to change I.
+func setsIndex(op Opcode) bool {
+	switch op { //nolint:exhaustive
+	case StoreMemAddrNNNInRegI, AddXToI, SetIToMemAddrOfSpriteInX, Store0ToXInI, Fill0ToXWithValueInAddrI:
+		return true
+	default:
+		return false
+	}
+}
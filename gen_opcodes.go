@@ -0,0 +1,238 @@
+//go:build ignore
+
+// gen_opcodes reads the opcode table in opcodes.json and writes the Opcode
+// constants, the decoder and the specs used by the disassembler and the
+// assembler to opcode_table.go, and the reference table to docs/opcodes.md.
+//
+// Run it with go generate.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type entry struct {
+	Pattern     string `json:"pattern"`
+	Op          string `json:"op"`
+	Syntax      string `json:"syntax"`
+	Platform    string `json:"platform"`
+	Description string `json:"description"`
+
+	value, mask uint16
+	operands    []field
+}
+
+type field struct {
+	name  string
+	shift int
+	bits  int
+}
+
+func main() {
+	data, err := os.ReadFile("opcodes.json")
+	if err != nil {
+		log.Fatalf("could not read opcodes: %v", err)
+	}
+
+	var entries []entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.Fatalf("could not decode opcodes: %v", err)
+	}
+
+	seen := make(map[string]bool)
+
+	for k := range entries {
+		e := &entries[k]
+		if seen[e.Op] {
+			log.Fatalf("%s: listed twice", e.Op)
+		}
+
+		seen[e.Op] = true
+
+		if err := e.parse(); err != nil {
+			log.Fatalf("%s (%s): %v", e.Op, e.Pattern, err)
+		}
+	}
+
+	write("opcode_table.go", goSource(entries))
+	write(filepath.Join("docs", "opcodes.md"), markdown(entries))
+}
+
+// parse works out the value, mask and operand layout of e from its pattern,
+// checking the syntax names the same operands.
+func (e *entry) parse() error {
+	const digits = 4
+
+	if len(e.Pattern) != digits {
+		return fmt.Errorf("want %d digits in pattern", digits)
+	}
+
+	fields := make(map[string]field)
+
+	for k, c := range e.Pattern {
+		shift := (digits - 1 - k) * 4 //nolint:mnd
+
+		switch c {
+		case 'X', 'Y':
+			fields["V"+string(c)] = field{name: "V" + string(c), shift: shift, bits: 4} //nolint:mnd
+		case 'N':
+			f := fields["N"]
+			f.shift = shift
+			f.bits += 4
+			fields["N"] = f
+		default:
+			n, err := strconv.ParseUint(string(c), 16, 4)
+			if err != nil {
+				return fmt.Errorf("invalid digit '%c'", c)
+			}
+
+			e.value |= uint16(n) << shift
+			e.mask |= 0xF << shift
+		}
+	}
+
+	if f, ok := fields["N"]; ok {
+		delete(fields, "N")
+
+		f.name = strings.Repeat("N", f.bits/4) //nolint:mnd
+		fields[f.name] = f
+	}
+
+	for _, tok := range syntaxOperands(e.Syntax) {
+		if !isOperand(tok) {
+			continue
+		}
+
+		f, ok := fields[tok]
+		if !ok {
+			return fmt.Errorf("syntax has %s, which is not in the pattern", tok)
+		}
+
+		e.operands = append(e.operands, f)
+		delete(fields, tok)
+	}
+
+	for name := range fields {
+		return fmt.Errorf("pattern has %s, which is not in the syntax", name)
+	}
+
+	return nil
+}
+
+func syntaxOperands(syntax string) []string {
+	_, rest, _ := strings.Cut(syntax, " ")
+	if rest == "" {
+		return nil
+	}
+
+	ops := strings.Split(rest, ",")
+	for k := range ops {
+		ops[k] = strings.TrimSpace(ops[k])
+	}
+
+	return ops
+}
+
+func isOperand(tok string) bool {
+	switch tok {
+	case "VX", "VY", "N", "NN", "NNN":
+		return true
+	default:
+		return false
+	}
+}
+
+func goSource(entries []entry) []byte {
+	b := &bytes.Buffer{}
+
+	fmt.Fprintln(b, "// Code generated by gen_opcodes.go from opcodes.json; DO NOT EDIT.")
+	fmt.Fprintln(b)
+	fmt.Fprintln(b, "package chipper")
+	fmt.Fprintln(b)
+	fmt.Fprintln(b, "const (")
+
+	for _, e := range entries {
+		fmt.Fprintf(b, "\t// %s: %s\n", e.Pattern, e.Description)
+		fmt.Fprintf(b, "\t%s Opcode = %q\n", e.Op, e.Op)
+	}
+
+	fmt.Fprintln(b, ")")
+	fmt.Fprintln(b)
+	fmt.Fprintln(b, "var opcodeSpecs = []OpcodeSpec{")
+
+	for _, e := range entries {
+		fmt.Fprintf(b, "\t{\n\t\tOp: %s, Pattern: %q, Value: 0x%04X, Mask: 0x%04X,\n", e.Op, e.Pattern, e.value, e.mask)
+		fmt.Fprintf(b, "\t\tSyntax: %q, Platform: %q,\n", e.Syntax, e.Platform)
+		fmt.Fprintf(b, "\t\tDescription: %q,\n", e.Description)
+
+		if len(e.operands) > 0 {
+			fmt.Fprint(b, "\t\tOperands: []OperandField{")
+
+			for k, f := range e.operands {
+				if k > 0 {
+					fmt.Fprint(b, ", ")
+				}
+
+				fmt.Fprintf(b, "{Name: %q, Shift: %d, Bits: %d}", f.name, f.shift, f.bits)
+			}
+
+			fmt.Fprintln(b, "},")
+		}
+
+		fmt.Fprintln(b, "\t},")
+	}
+
+	fmt.Fprintln(b, "}")
+	fmt.Fprintln(b)
+	fmt.Fprintln(b, "// decodeOpcode returns the first opcode in the table matching raw.")
+	fmt.Fprintln(b, "func decodeOpcode(raw uint16) Opcode { //nolint:cyclop,gocyclo,funlen")
+	fmt.Fprintln(b, "\tswitch {")
+
+	for _, e := range entries {
+		fmt.Fprintf(b, "\tcase raw&0x%04X == 0x%04X:\n\t\treturn %s\n", e.mask, e.value, e.Op)
+	}
+
+	fmt.Fprintln(b, "\tdefault:\n\t\treturn Unknown\n\t}\n}")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatalf("could not format generated code: %v\n%s", err, b.String())
+	}
+
+	return src
+}
+
+func markdown(entries []entry) []byte {
+	b := &bytes.Buffer{}
+
+	fmt.Fprintln(b, "<!-- Code generated by gen_opcodes.go from opcodes.json; DO NOT EDIT. -->")
+	fmt.Fprintln(b)
+	fmt.Fprintln(b, "# Opcodes")
+	fmt.Fprintln(b)
+	fmt.Fprintln(b, "| Pattern | Syntax | Opcode | Platform | Description |")
+	fmt.Fprintln(b, "|---------|--------|--------|----------|-------------|")
+
+	for _, e := range entries {
+		fmt.Fprintf(b, "| `%s` | `%s` | %s | %s | %s |\n", e.Pattern, e.Syntax, e.Op, e.Platform, e.Description)
+	}
+
+	return b.Bytes()
+}
+
+func write(path string, data []byte) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:gosec
+		log.Fatalf("could not create %s: %v", filepath.Dir(path), err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil { //nolint:gosec
+		log.Fatalf("could not write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,172 @@
+package chipper
+
+import (
+	"bytes"
+	"flag"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+const (
+	goldenFrames = 180
+	goldenIPF    = 10
+	goldenSeed   = 1
+)
+
+// TestGolden runs every ROM in testdata for a fixed number of frames, with a
+// fixed RNG seed and no keys pressed, and compares the display with
+// testdata/golden/<rom>.txt.
+//
+// Run `go test -run TestGolden -update` to rewrite the golden files.
+func TestGolden(t *testing.T) {
+	roms, err := filepath.Glob("testdata/*.ch8")
+	if err != nil {
+		t.Fatalf("could not list roms: %v", err)
+	}
+
+	for _, path := range roms {
+		name := strings.TrimSuffix(filepath.Base(path), ".ch8")
+		golden := filepath.Join("testdata", "golden", name+".txt")
+
+		t.Run(name, func(t *testing.T) {
+			rom, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("could not read rom: %v", err)
+			}
+
+			got := runGolden(t, rom)
+
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil { //nolint:gosec
+					t.Fatalf("could not create golden directory: %v", err)
+				}
+
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil { //nolint:gosec
+					t.Fatalf("could not write golden: %v", err)
+				}
+
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("could not read golden, run with -update to create it: %v", err)
+			}
+
+			if got != string(want) {
+				t.Fatalf("display after %d frames does not match %s (+ extra, - missing):\n%s",
+					goldenFrames, golden, diffScreens(string(want), got))
+			}
+		})
+	}
+}
+
+func runGolden(t *testing.T, rom []byte) string {
+	t.Helper()
+
+	display, err := NewBitmap(64, 32)
+	if err != nil {
+		t.Fatalf("could not create display: %v", err)
+	}
+
+	emu, err := NewEmulator(16, 4096, display, &StubKeyInputSource{})
+	if err != nil {
+		t.Fatalf("could not create emulator: %v", err)
+	}
+
+	emu.SetRand(rand.New(rand.NewSource(goldenSeed))) //nolint:gosec
+
+	if err := emu.Load(bytes.NewReader(rom)); err != nil {
+		t.Fatalf("could not load rom: %v", err)
+	}
+
+	for k := 0; k < goldenFrames; k++ {
+		if err := emu.RunFrame(goldenIPF); err != nil {
+			t.Fatalf("frame %d: %v", k, err)
+		}
+	}
+
+	return screenText(emu.Frame().Current)
+}
+
+// screenText draws b one row per line, '#' for set pixels.
+func screenText(b *Bitmap) string {
+	sb := &strings.Builder{}
+
+	for y := 0; y < b.height; y++ {
+		for x := 0; x < b.width; x++ {
+			if b.Pixel(x, y) {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+// diffScreens draws got over want, marking pixels only set in got with '+'
+// and pixels only set in want with '-'. Rows that differ are flagged with '>'.
+func diffScreens(want, got string) string {
+	wantRows := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	gotRows := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	sb := &strings.Builder{}
+
+	for y := 0; y < max(len(wantRows), len(gotRows)); y++ {
+		w, g := "", ""
+		if y < len(wantRows) {
+			w = wantRows[y]
+		}
+
+		if y < len(gotRows) {
+			g = gotRows[y]
+		}
+
+		row := make([]byte, max(len(w), len(g)))
+		differs := false
+
+		for x := range row {
+			wantSet := x < len(w) && w[x] == '#'
+			gotSet := x < len(g) && g[x] == '#'
+
+			switch {
+			case wantSet == gotSet && gotSet:
+				row[x] = '#'
+			case wantSet == gotSet:
+				row[x] = '.'
+			case gotSet:
+				row[x] = '+'
+				differs = true
+			default:
+				row[x] = '-'
+				differs = true
+			}
+		}
+
+		marker := "  "
+		if differs {
+			marker = "> "
+		}
+
+		sb.WriteString(marker + string(row) + "\n")
+	}
+
+	return sb.String()
+}
+
+func TestDiffScreens(t *testing.T) {
+	got := diffScreens("#.\n..\n", "#.\n.#\n")
+	want := "  #.\n> .+\n"
+
+	if got != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -13,6 +13,56 @@ type KeyInputSource interface {
 	WaitUntilKeypress() <-chan int
 }
 
+// KeyPoller is implemented by a KeyInputSource that FX0A can poll instead of
+// blocking on WaitUntilKeypress. While PollKeypress reports no key, FX0A
+// leaves PC on itself, so the next Step executes it again and the caller
+// keeps control of the emulator in the meantime. ResetKeypress forgets any
+// press FX0A has not taken yet, for when the machine restarts.
+//
+// KeyLatch implements both methods.
+type KeyPoller interface {
+	PollKeypress() (int, bool)
+	ResetKeypress()
+}
+
+// KeyLatch is the FX0A state of a KeyPoller: it latches the first key pressed
+// after FX0A started waiting. The zero value is ready to use. It is not safe
+// for concurrent use, so KeyPollers guard it with their own lock.
+type KeyLatch struct {
+	waiting bool
+	pressed int
+}
+
+// Press records a key going down. Only the first press while FX0A waits is
+// kept.
+func (l *KeyLatch) Press(key int) {
+	if l.waiting && l.pressed < 0 {
+		l.pressed = key
+	}
+}
+
+// Poll starts waiting if FX0A was not already, and returns the latched key,
+// if any, which ends the wait.
+func (l *KeyLatch) Poll() (int, bool) {
+	if !l.waiting {
+		l.waiting = true
+		l.pressed = -1
+	}
+
+	if l.pressed < 0 {
+		return 0, false
+	}
+
+	l.waiting = false
+
+	return l.pressed, true
+}
+
+// Reset stops waiting, dropping any latched key.
+func (l *KeyLatch) Reset() {
+	l.waiting = false
+}
+
 type StubKeyInputSource struct{}
 
 func (stub *StubKeyInputSource) Get(_ int) bool {
@@ -2,6 +2,7 @@ package chipper
 
 import (
 	"fmt"
+	"strings"
 )
 
 type Instruction struct {
@@ -9,14 +10,17 @@ type Instruction struct {
 	Operands []int
 }
 
+// operandCount is the number of operands of every decoded instruction, the
+// three digits after the first.
+const operandCount = 3
+
 func (instr Instruction) String() string {
-	return fmt.Sprintf(
-		"{Op: %s, Operands: [%#0x, %#0x, %#0x]}",
-		instr.Op,
-		instr.Operands[0],
-		instr.Operands[1],
-		instr.Operands[2],
-	)
+	operands := make([]string, len(instr.Operands))
+	for k, v := range instr.Operands {
+		operands[k] = fmt.Sprintf("%#0x", v)
+	}
+
+	return fmt.Sprintf("{Op: %s, Operands: [%s]}", instr.Op, strings.Join(operands, ", "))
 }
 
 type InstructionNotImplementedError struct {
@@ -29,6 +33,9 @@ func (e InstructionNotImplementedError) Error() string {
 
 func (emu *Emulator) Execute(instr Instruction) error { //nolint: funlen,cyclop,gocyclo
 	args := instr.Operands
+	if len(args) != operandCount {
+		return ArgCountError{want: operandCount, got: len(args)}
+	}
 
 	switch instr.Op {
 	default:
@@ -146,6 +153,12 @@ func (emu *Emulator) execNNN(_ []int, instr Instruction) error {
 }
 
 func (emu *Emulator) clearScreen() error {
+	if fb, ok := emu.Display.(Framebuffer); ok {
+		fb.Clear()
+
+		return nil
+	}
+
 	b := emu.Display.Bounds()
 	dx, dy := b.Dx(), b.Dy()
 
@@ -177,9 +190,7 @@ func (emu *Emulator) jumpNNN(args []int) error {
 		return err
 	}
 
-	emu.PC = addr
-
-	return nil
+	return emu.jump(addr)
 }
 
 func (emu *Emulator) callSubNNN(args []int) error {
@@ -188,6 +199,10 @@ func (emu *Emulator) callSubNNN(args []int) error {
 		return err
 	}
 
+	if err := isInBounds(emu.Memory.Size(), int(addr)); err != nil {
+		return err
+	}
+
 	if err := emu.Stack.Push(emu.PC); err != nil {
 		return fmt.Errorf("could not push PC on stack: %w", err)
 	}
@@ -197,6 +212,18 @@ func (emu *Emulator) callSubNNN(args []int) error {
 	return nil
 }
 
+// jump moves the PC to addr, which must be in memory: machines with less
+// than 4K of RAM have nothing to run past their end.
+func (emu *Emulator) jump(addr uint16) error {
+	if err := isInBounds(emu.Memory.Size(), int(addr)); err != nil {
+		return err
+	}
+
+	emu.PC = addr
+
+	return nil
+}
+
 // skipIfXEqNN will skip the next instruction if VX == NN.
 func (emu *Emulator) skipIfXEqNN(x int, args []int) error {
 	if err := isInBounds(RegisterCount, x); err != nil {
@@ -315,6 +342,10 @@ func (emu *Emulator) setXToXORY(x, y int) error {
 
 	emu.V[x] = (emu.V[x] | emu.V[y])
 
+	if emu.Quirks.Logic {
+		emu.V[0xF] = 0
+	}
+
 	return nil
 }
 
@@ -330,6 +361,10 @@ func (emu *Emulator) setXToXANDY(x, y int) error {
 
 	emu.V[x] = (emu.V[x] & emu.V[y])
 
+	if emu.Quirks.Logic {
+		emu.V[0xF] = 0
+	}
+
 	return nil
 }
 
@@ -345,10 +380,15 @@ func (emu *Emulator) setXToXXORY(x, y int) error {
 
 	emu.V[x] = (emu.V[x] ^ emu.V[y])
 
+	if emu.Quirks.Logic {
+		emu.V[0xF] = 0
+	}
+
 	return nil
 }
 
 // addYToX will add VY to VX, setting VF if it overflows and keeping the lower 8 bits.
+// VF is written last, so with X=F it holds the carry.
 func (emu *Emulator) addYToX(x, y int) error {
 	if err := isInBounds(RegisterCount, x); err != nil {
 		return err
@@ -360,18 +400,19 @@ func (emu *Emulator) addYToX(x, y int) error {
 
 	val := int(emu.V[x]) + int(emu.V[y])
 
-	emu.V[0xF] = 0
+	carry := byte(0)
 	if val > max8BitVal {
-		emu.V[0xF] = 1
+		carry = 1
 	}
 
 	emu.V[x] = byte(val & max8BitVal)
+	emu.V[0xF] = carry
 
 	return nil
 }
 
-// subYFromX will subtract VY from VX, storing the value in VX. It
-// will clear VF if it borrows.
+// subYFromX will subtract VY from VX, storing the (wrapped) value in VX. It
+// will clear VF if it borrows, setting it otherwise. VF is written last.
 func (emu *Emulator) subYFromX(x, y int) error {
 	if err := isInBounds(RegisterCount, x); err != nil {
 		return err
@@ -381,22 +422,22 @@ func (emu *Emulator) subYFromX(x, y int) error {
 		return err
 	}
 
-	val := int(emu.V[x]) - int(emu.V[y])
+	vx, vy := emu.V[x], emu.V[y]
 
-	clearOnBorrow := 1
-	if val < 0 {
+	clearOnBorrow := byte(1)
+	if vy > vx {
 		clearOnBorrow = 0
-		val = val * -1
 	}
 
-	emu.V[x] = byte(val)
-	emu.V[0xF] = byte(clearOnBorrow)
+	emu.V[x] = vx - vy
+	emu.V[0xF] = clearOnBorrow
 
 	return nil
 }
 
 // storeYShiftedRightInX will shift VY right and store it in X.
-// It will place the dropped bit in VF.
+// It will place the dropped bit in VF, written after VX. With the Shift quirk,
+// VX is shifted instead.
 func (emu *Emulator) storeYShiftedRightInX(x, y int) error {
 	if err := isInBounds(RegisterCount, x); err != nil {
 		return err
@@ -406,19 +447,23 @@ func (emu *Emulator) storeYShiftedRightInX(x, y int) error {
 		return err
 	}
 
+	if emu.Quirks.Shift {
+		y = x
+	}
+
 	vy := emu.V[y]
 
 	const bitMask = 0x1
 	droppedBit := vy & bitMask
 
-	shifted := vy >> 1
+	emu.V[x] = vy >> 1
 	emu.V[0xF] = droppedBit
-	emu.V[x] = shifted
 
 	return nil
 }
 
-// SetXToYMinusX will set VX to VY - VX, clearing VF if a borrow occurs.
+// setXToYMinusX will set VX to the (wrapped) VY - VX, clearing VF if a borrow
+// occurs and setting it otherwise. VF is written last.
 func (emu *Emulator) setXToYMinusX(x, y int) error {
 	if err := isInBounds(RegisterCount, x); err != nil {
 		return err
@@ -429,22 +474,21 @@ func (emu *Emulator) setXToYMinusX(x, y int) error {
 	}
 
 	vx, vy := emu.V[x], emu.V[y]
-	val := int(vy) - int(vx)
 
-	clearOnBorrow := 1
-	if val < 0 {
+	clearOnBorrow := byte(1)
+	if vx > vy {
 		clearOnBorrow = 0
-		val = -1 * val
 	}
 
-	emu.V[0xF] = byte(clearOnBorrow)
-	emu.V[x] = byte(val)
+	emu.V[x] = vy - vx
+	emu.V[0xF] = clearOnBorrow
 
 	return nil
 }
 
 // storeYShiftedLeftInX will shift VY left and store it in VX.
-// It will place the dropped bit in VF.
+// It will place the dropped bit in VF, written after VX. With the Shift quirk,
+// VX is shifted instead.
 func (emu *Emulator) storeYShiftedLeftInX(x, y int) error {
 	if err := isInBounds(RegisterCount, x); err != nil {
 		return err
@@ -454,13 +498,17 @@ func (emu *Emulator) storeYShiftedLeftInX(x, y int) error {
 		return err
 	}
 
+	if emu.Quirks.Shift {
+		y = x
+	}
+
 	const shiftBits = 7
 
 	vy := emu.V[y]
 	droppedBit := vy >> shiftBits
 
-	emu.V[0xF] = droppedBit
 	emu.V[x] = vy << 1
+	emu.V[0xF] = droppedBit
 
 	return nil
 }
@@ -489,28 +537,29 @@ func (emu *Emulator) storeMemAddrNNNInRegI(args []int) error {
 		return err
 	}
 
-	emu.Index = addr
+	emu.setIndex(int(addr))
 
 	return nil
 }
 
-// jumpToAddrNNNPlusV0 will JUMP to the address NNN + V0.
+// jumpToAddrNNNPlusV0 will JUMP to the address NNN + V0. With the Jump quirk
+// it behaves as BXNN, jumping to XNN + VX.
 func (emu *Emulator) jumpToAddrNNNPlusV0(args []int) error {
 	addr, err := ToAddr3(args)
 	if err != nil {
 		return err
 	}
 
-	newAddr := addr + uint16(emu.V[0])
-	ramSize := len(emu.RAM)
+	reg := 0
+	if emu.Quirks.Jump {
+		reg = args[0]
+	}
 
-	if err := isInBounds(ramSize, int(newAddr)); err != nil {
+	if err := isInBounds(RegisterCount, reg); err != nil {
 		return err
 	}
 
-	emu.PC = newAddr
-
-	return nil
+	return emu.jump(addr + uint16(emu.V[reg]))
 }
 
 // setXToRandomNumWithMaskNN will set VX to (randInt(0, 255)  & NN).
@@ -524,14 +573,18 @@ func (emu *Emulator) setXToRandomNumWithMaskNN(x int, args []int) error {
 		return err
 	}
 
-	rn := randomNum()
+	rn := emu.randomByte()
 	emu.V[x] = rn & val
 
 	return nil
 }
 
-// drawSpriteInXY NEEDS TO BE DONE PROPERLY.
-func (emu *Emulator) drawSpriteInXY(x, y, n int) error { //nolint: gocognit
+// drawSpriteInXY XORs the N-byte sprite at I onto the display at (VX, VY),
+// setting VF if any pixel is erased. The starting position wraps around the
+// screen, the sprite itself wraps with the Wrap quirk, which DefaultQuirks
+// sets, and is clipped at the edges otherwise. With the VBlank quirk, it
+// waits for the next timer tick before drawing.
+func (emu *Emulator) drawSpriteInXY(x, y, n int) error { //nolint: gocognit,cyclop
 	if err := isInBounds(RegisterCount, x); err != nil {
 		return err
 	}
@@ -540,49 +593,80 @@ func (emu *Emulator) drawSpriteInXY(x, y, n int) error { //nolint: gocognit
 		return err
 	}
 
+	if emu.Quirks.VBlank && !emu.vblank {
+		emu.PC -= InstructionSize
+
+		return nil
+	}
+
+	emu.vblank = false
+
+	// I must point into memory even for DXY0, which draws nothing.
+	rows, err := emu.readBytes(int(emu.Index), max(n, 1))
+	if err != nil {
+		return fmt.Errorf("drawSpriteInXY: sprite data: %w", err)
+	}
+
+	rows = rows[:n]
+
+	if fb, ok := emu.Display.(Framebuffer); ok {
+		emu.V[0xF] = 0
+		if fb.DrawSprite(int(emu.V[x]), int(emu.V[y]), rows, emu.Quirks.Wrap) {
+			emu.V[0xF] = 1
+		}
+
+		return nil
+	}
+
 	b := emu.Display.Bounds()
 	displayWidth, displayHeight := b.Dx(), b.Dy()
 
-	posx, posy := int(emu.V[x]), int(emu.V[y])
+	posx, posy := int(emu.V[x])%displayWidth, int(emu.V[y])%displayHeight
 
 	emu.V[0xF] = 0
 	clearColor := emu.Display.ColorClear()
 	setColor := emu.Display.ColorSet()
 
-	const shiftMask = 7
+	const (
+		spriteWidth = 8
+		shiftMask   = 7
+	)
 
 	for yline := 0; yline < n; yline++ {
-		addr := int(emu.Index) + yline
-		pixels := emu.RAM[addr]
+		ypos := posy + yline
+		if ypos >= displayHeight {
+			if !emu.Quirks.Wrap {
+				break
+			}
 
-		for xline := 0; xline < 8; xline++ {
-			value := (pixels >> (shiftMask - xline)) & 1
+			ypos %= displayHeight
+		}
 
-			xpos := (posx + xline) % displayWidth
-			ypos := (posy + yline) % displayWidth
-			at := emu.Display.At(xpos, ypos)
-			bit := byte(1)
+		pixels := rows[yline]
 
-			if ColorEq(at, clearColor) {
-				bit = 0
+		for xline := 0; xline < spriteWidth; xline++ {
+			xpos := posx + xline
+			if xpos >= displayWidth {
+				if !emu.Quirks.Wrap {
+					break
+				}
+
+				xpos %= displayWidth
 			}
 
-			newPix := value ^ bit
+			value := (pixels >> (shiftMask - xline)) & 1
+			if value == 0 {
+				continue
+			}
 
-			if value == bit {
+			if !ColorEq(emu.Display.At(xpos, ypos), clearColor) {
 				emu.V[0xF] = 1
-			}
+				emu.Display.Set(xpos, ypos, clearColor)
 
-			c := clearColor
-			if newPix == 1 {
-				c = setColor
+				continue
 			}
 
-			emu.Display.Set(xpos, ypos, c)
-		}
-
-		if (posy + yline) >= displayHeight {
-			break
+			emu.Display.Set(xpos, ypos, setColor)
 		}
 	}
 
@@ -594,7 +678,7 @@ func (emu *Emulator) skipIfKeyInXIsPressed(x int) error {
 		return err
 	}
 
-	fmt.Println("skip if key pressed: ", emu.V[x])
+	emu.log().Println("skip if key pressed: ", emu.V[x])
 
 	v := emu.Keys.Get(int(emu.V[x]))
 	if v {
@@ -609,7 +693,7 @@ func (emu *Emulator) skipIfKeyInXNotPressed(x int) error {
 		return err
 	}
 
-	fmt.Println("skip if key not pressed: ", emu.V[x])
+	emu.log().Println("skip if key not pressed: ", emu.V[x])
 
 	v := emu.Keys.Get(int(emu.V[x]))
 	if !v {
@@ -634,11 +718,24 @@ func (emu *Emulator) waitForKeyAndStoreInX(x int) error {
 		return err
 	}
 
-	fmt.Println("[instruction.go] waiting for key")
+	if poller, ok := emu.Keys.(KeyPoller); ok {
+		key, pressed := poller.PollKeypress()
+		if !pressed {
+			emu.PC -= InstructionSize
+
+			return nil
+		}
+
+		emu.V[x] = byte(key)
+
+		return nil
+	}
+
+	emu.log().Println("waiting for key")
 
 	key := <-emu.Keys.WaitUntilKeypress()
 
-	fmt.Println("[instruction.go] got key: ", key)
+	emu.log().Println("got key: ", key)
 	emu.V[x] = byte(key)
 
 	return nil
@@ -669,19 +766,21 @@ func (emu *Emulator) addXToI(x int) error {
 		return err
 	}
 
-	emu.Index += uint16(emu.V[x])
+	emu.setIndex(int(emu.Index) + int(emu.V[x]))
 
 	return nil
 }
 
+// setIToMemAddrOfSpriteInX points I at the glyph of the low nibble of VX, as
+// the VIP only looked at those bits.
 func (emu *Emulator) setIToMemAddrOfSpriteInX(x int) error {
 	if err := isInBounds(RegisterCount, x); err != nil {
 		return err
 	}
 
-	const width = 5
+	size := emu.config.font().GlyphSize()
 
-	emu.Index = uint16(emu.V[x]) * width
+	emu.setIndex(int(emu.config.FontAddress) + int(emu.V[x]&0xF)*size)
 
 	return nil
 }
@@ -698,38 +797,60 @@ func (emu *Emulator) storeBCDOfXInI(x int) error {
 		return err
 	}
 
-	addr := int(emu.Index)
-
-	for k, p := range bcd {
-		emu.RAM[addr+k] = p
+	if err := emu.writeBytes(int(emu.Index), bcd); err != nil {
+		return fmt.Errorf("storeBCDOfXInI: %w", err)
 	}
 
 	return nil
 }
 
+// store0ToXInI stores V0 to VX, inclusive, in RAM starting at I.
 func (emu *Emulator) store0ToXInI(x int) error {
 	if err := isInBounds(RegisterCount, x); err != nil {
 		return err
 	}
 
-	addr := int(emu.Index)
-
-	for k, p := range emu.V[:x] {
-		emu.RAM[addr+k] = p
+	if err := emu.writeBytes(int(emu.Index), emu.V[:x+1]); err != nil {
+		return fmt.Errorf("store0ToXInI: %w", err)
 	}
 
+	emu.incrementIndexAfterMemOp(x)
+
 	return nil
 }
 
+// fill0ToXWithValueInAddrI loads V0 to VX, inclusive, from RAM starting at I.
 func (emu *Emulator) fill0ToXWithValueInAddrI(x int) error {
 	if err := isInBounds(RegisterCount, x); err != nil {
 		return err
 	}
 
-	addr := int(emu.Index)
-	for k := 0; k < x; k++ {
-		emu.V[k] = emu.RAM[addr+k]
+	values, err := emu.readBytes(int(emu.Index), x+1)
+	if err != nil {
+		return fmt.Errorf("fill0ToXWithValueInAddrI: %w", err)
 	}
 
+	copy(emu.V[:], values)
+
+	emu.incrementIndexAfterMemOp(x)
+
 	return nil
 }
+
+// incrementIndexAfterMemOp moves I past the registers saved or loaded by
+// FX55/FX65, as selected by the memory quirks.
+func (emu *Emulator) incrementIndexAfterMemOp(x int) {
+	switch {
+	case emu.Quirks.MemoryLeaveIUnchanged:
+	case emu.Quirks.MemoryIncrementByX:
+		emu.setIndex(int(emu.Index) + x)
+	default:
+		emu.setIndex(int(emu.Index) + x + 1)
+	}
+}
+
+// setIndex points I at addr, wrapped around memory, so I never addresses more
+// than the machine has, as the VIP ignored the address bits past its 4K.
+func (emu *Emulator) setIndex(addr int) {
+	emu.Index = uint16(addr % emu.Memory.Size())
+}
@@ -55,6 +55,8 @@ func TestInstruction(t *testing.T) {
 		{"storeYShiftedLeftInX", testStoreYShiftedLeftInX},
 		{"skipIfXNotEqY", testSkipIfXNotEqY},
 		{"set vx to random number mask with nn", testSetVXWithMask},
+		{"drawSpriteInXY", testDrawSpriteInXY},
+		{"quirks", testQuirks},
 	}
 
 	for _, c := range tests {
@@ -505,7 +507,7 @@ func testSubYFromX(t *testing.T) {
 		emu.V[x] = 0x0F
 		emu.V[y] = 0xFF
 
-		want := 0xF0
+		want := 0x10
 
 		if err := emu.subYFromX(x, y); err != nil {
 			t.Fatalf("error: %v", err)
@@ -602,7 +604,7 @@ func testSetXToYMinusX(t *testing.T) {
 		emu.V[x] = 0xFF
 		emu.V[y] = 0x0F
 
-		want := 0xF0
+		want := 0x10
 
 		if err := emu.setXToYMinusX(x, y); err != nil {
 			t.Fatalf("error: %v", err)
@@ -724,3 +726,197 @@ func testSetVXWithMask(t *testing.T) {
 
 	t.Fatalf("register V%d was never set", testV)
 }
+
+func testDrawSpriteInXY(t *testing.T) {
+	t.Helper()
+
+	const (
+		x          = 1
+		y          = 2
+		spriteAddr = 0x300
+	)
+
+	setup := func(t *testing.T, posx, posy byte) *Emulator {
+		t.Helper()
+
+		emu := mkEmu(t)
+		emu.RAM[spriteAddr] = 0xFF
+		emu.RAM[spriteAddr+1] = 0xFF
+		emu.Index = spriteAddr
+		emu.V[x] = posx
+		emu.V[y] = posy
+
+		return emu
+	}
+
+	isSet := func(emu *Emulator, px, py int) bool {
+		return ColorEq(emu.Display.At(px, py), emu.Display.ColorSet())
+	}
+
+	t.Run("it draws and detects collisions", func(t *testing.T) {
+		emu := setup(t, 4, 4)
+
+		if err := emu.drawSpriteInXY(x, y, 2); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+
+		if !isSet(emu, 4, 4) || !isSet(emu, 11, 5) || isSet(emu, 12, 4) {
+			t.Fatalf("sprite was not drawn as expected:\n%s", emu.Display)
+		}
+
+		if emu.V[0xF] != 0 {
+			t.Fatalf("VF set without a collision")
+		}
+
+		if err := emu.drawSpriteInXY(x, y, 2); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+
+		if isSet(emu, 4, 4) || emu.V[0xF] != 1 {
+			t.Fatalf("redrawing should erase the sprite and set VF")
+		}
+	})
+
+	t.Run("it clips at the edges without the Wrap quirk", func(t *testing.T) {
+		emu := setup(t, 60, 31)
+		emu.Quirks.Wrap = false
+
+		if err := emu.drawSpriteInXY(x, y, 2); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+
+		if !isSet(emu, 63, 31) || isSet(emu, 0, 31) || isSet(emu, 60, 0) {
+			t.Fatalf("sprite was not clipped:\n%s", emu.Display)
+		}
+	})
+
+	t.Run("it wraps by default", func(t *testing.T) {
+		emu := setup(t, 60, 31)
+
+		if err := emu.drawSpriteInXY(x, y, 2); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+
+		if !isSet(emu, 63, 31) || !isSet(emu, 0, 31) || !isSet(emu, 60, 0) {
+			t.Fatalf("sprite did not wrap:\n%s", emu.Display)
+		}
+	})
+
+	t.Run("it wraps the starting position", func(t *testing.T) {
+		emu := setup(t, 64+2, 32+3)
+
+		if err := emu.drawSpriteInXY(x, y, 1); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+
+		if !isSet(emu, 2, 3) {
+			t.Fatalf("starting position did not wrap:\n%s", emu.Display)
+		}
+	})
+}
+
+func testQuirks(t *testing.T) { //nolint:funlen
+	t.Helper()
+
+	const (
+		x = 1
+		y = 2
+	)
+
+	t.Run("shift", func(t *testing.T) {
+		emu := mkEmu(t)
+		emu.Quirks.Shift = true
+		emu.V[x] = 0x4
+		emu.V[y] = 0x10
+
+		if err := emu.storeYShiftedRightInX(x, y); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+
+		if emu.V[x] != 0x2 {
+			t.Fatalf("got %#0x, want %#0x", emu.V[x], 0x2)
+		}
+	})
+
+	t.Run("logic", func(t *testing.T) {
+		emu := mkEmu(t)
+		emu.Quirks.Logic = true
+		emu.V[0xF] = 1
+
+		if err := emu.setXToXORY(x, y); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+
+		if emu.V[0xF] != 0 {
+			t.Fatalf("VF was not reset")
+		}
+	})
+
+	t.Run("jump", func(t *testing.T) {
+		emu := mkEmu(t)
+		emu.Quirks.Jump = true
+		emu.V[0] = 0x10
+		emu.V[3] = 0x1
+
+		if err := emu.jumpToAddrNNNPlusV0([]int{3, 0, 0}); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+
+		if emu.PC != 0x301 {
+			t.Fatalf("got %#0x, want %#0x", emu.PC, 0x301)
+		}
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		cases := []struct {
+			label  string
+			quirks Quirks
+			want   uint16
+		}{
+			{"increment by x+1", Quirks{}, 0x304},
+			{"increment by x", Quirks{MemoryIncrementByX: true}, 0x303},
+			{"leave unchanged", Quirks{MemoryLeaveIUnchanged: true}, 0x300},
+		}
+
+		for _, c := range cases {
+			t.Run(c.label, func(t *testing.T) {
+				emu := mkEmu(t)
+				emu.Quirks = c.quirks
+				emu.Index = 0x300
+
+				if err := emu.store0ToXInI(3); err != nil {
+					t.Fatalf("error: %v", err)
+				}
+
+				if emu.Index != c.want {
+					t.Fatalf("got %#0x, want %#0x", emu.Index, c.want)
+				}
+			})
+		}
+	})
+
+	t.Run("vblank", func(t *testing.T) {
+		emu := mkEmu(t)
+		emu.Quirks.VBlank = true
+		emu.PC = 0x202
+
+		if err := emu.drawSpriteInXY(x, y, 1); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+
+		if emu.PC != 0x200 {
+			t.Fatalf("draw should wait for vblank, PC is %#0x", emu.PC)
+		}
+
+		emu.TickTimers()
+		emu.PC = 0x202
+
+		if err := emu.drawSpriteInXY(x, y, 1); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+
+		if emu.PC != 0x202 {
+			t.Fatalf("draw should not wait after a timer tick, PC is %#0x", emu.PC)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+// Package cli holds what the chipper commands share to pick the machine they
+// emulate: the -platform, -font and -out-of-range flags and how they build a
+// chipper.Config.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/romdb"
+)
+
+// Machine is what the machine flags picked. Each field is nil until its flag
+// is set.
+type Machine struct {
+	// Platform is the machine to emulate, see PlatformConfig.
+	Platform *chipper.Config
+
+	// Font replaces the platform's font, see LoadFont.
+	Font *chipper.Font
+
+	// OutOfRange replaces the platform's policy for addresses outside of
+	// memory.
+	OutOfRange *chipper.OutOfRange
+}
+
+// AddFlags adds -platform, -font and -out-of-range to fs, parsed into m.
+func (m *Machine) AddFlags(fs *flag.FlagSet) {
+	m.AddPlatformFlags(fs)
+
+	fs.Func(
+		"out-of-range",
+		"what accesses outside of memory do: fault, wrap or ignore, the platform's policy if not set",
+		func(s string) error {
+			o, err := chipper.ParseOutOfRange(s)
+			if err != nil {
+				return err
+			}
+
+			m.OutOfRange = &o
+
+			return nil
+		},
+	)
+}
+
+// AddPlatformFlags adds only -platform and -font to fs, for commands that do
+// not run the machine.
+func (m *Machine) AddPlatformFlags(fs *flag.FlagSet) {
+	fs.Func(
+		"platform",
+		"machine to emulate, a preset ("+PresetIDs()+") or a ROM database platform, instead of the ROM database's settings",
+		func(s string) error {
+			cfg, err := PlatformConfig(s)
+			if err != nil {
+				return err
+			}
+
+			m.Platform = &cfg
+
+			return nil
+		},
+	)
+
+	fs.Func(
+		"font",
+		"font for the hex digits, a built-in font ("+FontIDs()+") or a JSON font file, the platform's if not set",
+		func(s string) error {
+			font, err := LoadFont(s)
+			if err != nil {
+				return err
+			}
+
+			m.Font = font
+
+			return nil
+		},
+	)
+}
+
+// Config returns the platform picked, or base if none was, with the font and
+// out of range policy picked.
+func (m Machine) Config(base chipper.Config) chipper.Config {
+	cfg := base
+	if m.Platform != nil {
+		cfg = *m.Platform
+	}
+
+	if m.Font != nil {
+		cfg.Font = m.Font
+	}
+
+	if m.OutOfRange != nil {
+		cfg.OutOfRange = *m.OutOfRange
+	}
+
+	return cfg
+}
+
+// PlatformConfig returns the machine named by name: a chipper preset, or a
+// platform of the ROM database.
+func PlatformConfig(name string) (chipper.Config, error) {
+	if cfg, ok := chipper.PresetByName(name); ok {
+		return cfg, nil
+	}
+
+	if p, ok := romdb.PlatformByID(name); ok {
+		return p.Config, nil
+	}
+
+	return chipper.Config{}, fmt.Errorf("unknown platform '%s', want one of %s or a ROM database platform", name, PresetIDs())
+}
+
+// LoadFont returns the font with the given ID, or reads the JSON font file at
+// name, see chipper.LoadFont.
+func LoadFont(name string) (*chipper.Font, error) {
+	if font, ok := chipper.FontByName(name); ok {
+		return font, nil
+	}
+
+	font, err := chipper.LoadFontFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("could not load font: %w", err)
+	}
+
+	return font, nil
+}
+
+// PresetIDs lists the IDs of the chipper presets, for usage messages.
+func PresetIDs() string {
+	presets := chipper.Presets()
+	ids := make([]string, 0, len(presets))
+
+	for _, cfg := range presets {
+		ids = append(ids, cfg.ID)
+	}
+
+	return strings.Join(ids, ", ")
+}
+
+// FontIDs lists the IDs of the fonts, for usage messages.
+func FontIDs() string {
+	fonts := chipper.Fonts()
+	ids := make([]string, 0, len(fonts))
+
+	for _, f := range fonts {
+		ids = append(ids, f.ID())
+	}
+
+	return strings.Join(ids, ", ")
+}
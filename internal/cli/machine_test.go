@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aalbacetef/chipper"
+)
+
+func TestMachine(t *testing.T) {
+	parse := func(t *testing.T, args ...string) (Machine, error) {
+		t.Helper()
+
+		m := Machine{}
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		m.AddFlags(fs)
+
+		return m, fs.Parse(args)
+	}
+
+	t.Run("no flags", func(t *testing.T) {
+		m, err := parse(t)
+		if err != nil {
+			t.Fatalf("could not parse flags: %v", err)
+		}
+
+		base := chipper.DefaultConfig()
+		if cfg := m.Config(base); cfg != base {
+			t.Fatalf("want the base config, got %+v", cfg)
+		}
+	})
+
+	t.Run("flags", func(t *testing.T) {
+		m, err := parse(t, "-platform", "superchip", "-font", "vip", "-out-of-range", "wrap")
+		if err != nil {
+			t.Fatalf("could not parse flags: %v", err)
+		}
+
+		schip, _ := chipper.PresetByName("schip")
+		vip, _ := chipper.FontByName("vip")
+
+		cfg := m.Config(chipper.DefaultConfig())
+		if cfg.ID != schip.ID || cfg.Font != vip || cfg.OutOfRange != chipper.OutOfRangeWrap {
+			t.Fatalf("want the superchip platform with the VIP font wrapping around, got %+v", cfg)
+		}
+	})
+
+	cases := []struct {
+		flag string
+		want string
+	}{
+		{"-platform", "unknown platform"},
+		{"-font", "could not load font"},
+		{"-out-of-range", "unknown out of range policy"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.flag, func(t *testing.T) {
+			if _, err := parse(t, c.flag, "nope"); err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Fatalf("want an error about '%s', got %v", c.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package keymap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aalbacetef/chipper/romdb"
+)
+
+// File is a keymap file. Keypad keys are hex digits, given as strings or
+// numbers. For example, to play a ROM moving with 2, 4, 6 and 8 with the
+// arrow keys:
+//
+//	{
+//	  "layout": "azerty",
+//	  "keys": {"up": "2", "left": "4", "right": "6", "down": "8"},
+//	  "roms": {
+//	    "<sha1 of the rom>": {"space": "5"}
+//	  }
+//	}
+type File struct {
+	// Layout is the base layout, QWERTY if empty.
+	Layout string `json:"layout,omitempty"`
+
+	// Keys are added to the layout, replacing the mappings of the same keys.
+	Keys map[string]KeypadKey `json:"keys,omitempty"`
+
+	// ROMs holds more keys for specific ROMs, by hex-encoded SHA-1.
+	ROMs map[string]map[string]KeypadKey `json:"roms,omitempty"`
+}
+
+// KeypadKey is a keypad key, read from JSON as a hex digit string or a number.
+type KeypadKey int
+
+func (k *KeypadKey) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	var key int64
+
+	switch v := v.(type) {
+	case float64:
+		key = int64(v)
+		if float64(key) != v {
+			return fmt.Errorf("invalid keypad key %v", v)
+		}
+	case string:
+		n, err := strconv.ParseInt(strings.TrimPrefix(strings.ToLower(v), "0x"), 16, 0)
+		if err != nil {
+			return fmt.Errorf("invalid keypad key '%s'", v)
+		}
+
+		key = n
+	default:
+		return fmt.Errorf("invalid keypad key %s", string(data))
+	}
+
+	*k = KeypadKey(key)
+
+	return nil
+}
+
+func (k KeypadKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(k), 16))
+}
+
+// Load reads a keymap file, checking its layout and keys.
+func Load(r io.Reader) (*File, error) {
+	var f File
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("could not decode keymap: %w", err)
+	}
+
+	if _, err := f.Keymap(nil); err != nil {
+		return nil, err
+	}
+
+	roms := make(map[string]map[string]KeypadKey, len(f.ROMs))
+
+	for hash, keys := range f.ROMs {
+		if err := apply(Keymap{}, keys); err != nil {
+			return nil, fmt.Errorf("rom %s: %w", hash, err)
+		}
+
+		roms[strings.ToLower(hash)] = keys
+	}
+
+	f.ROMs = roms
+
+	return &f, nil
+}
+
+// LoadFile reads the keymap file at path.
+func LoadFile(path string) (*File, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open keymap: %w", err)
+	}
+	defer fd.Close()
+
+	return Load(fd)
+}
+
+// Keymap returns the keymap to play rom with: the layout, with the controls
+// the ROM database lists for rom, the file's keys and its keys for rom added
+// in that order. rom may be nil.
+func (f *File) Keymap(rom []byte) (Keymap, error) {
+	var controls map[string]int
+
+	if entry, ok := romdb.Lookup(rom); rom != nil && ok {
+		controls = entry.ROM.Keys
+	}
+
+	return f.KeymapWithControls(rom, controls)
+}
+
+// KeymapWithControls returns the keymap to play rom with, as Keymap does,
+// with the given controls in place of the ones the ROM database lists, e.g.
+// those of a romdb.Entry already looked up. controls may be nil.
+func (f *File) KeymapWithControls(rom []byte, controls map[string]int) (Keymap, error) {
+	layout := f.Layout
+	if layout == "" {
+		layout = "qwerty"
+	}
+
+	k, err := Layout(layout)
+	if err != nil {
+		return nil, err
+	}
+
+	k = k.WithControls(controls)
+
+	if err := apply(k, f.Keys); err != nil {
+		return nil, err
+	}
+
+	if rom == nil {
+		return k, nil
+	}
+
+	if err := apply(k, f.ROMs[romdb.Hash(rom)]); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+func apply(k Keymap, keys map[string]KeypadKey) error {
+	for name, key := range keys {
+		if err := k.Set(name, int(key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ForROM returns the keymap to play rom with, as File.Keymap does. If f is
+// nil, it is the QWERTY layout with the controls from the ROM database.
+func ForROM(f *File, rom []byte) (Keymap, error) {
+	if f == nil {
+		f = &File{}
+	}
+
+	return f.Keymap(rom)
+}
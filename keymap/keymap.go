@@ -0,0 +1,171 @@
+// Package keymap maps the keys of a host keyboard to the 16 keys of the
+// CHIP-8 keypad. Host keys are named by the character they type, such as "q"
+// or "1", or by a name for keys that type none, such as "up" or "space".
+package keymap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// Keymap maps normalized host key names to keypad keys.
+type Keymap map[string]int
+
+// Lookup returns the keypad key mapped to the host key name.
+func (k Keymap) Lookup(name string) (int, bool) {
+	key, ok := k[Normalize(name)]
+
+	return key, ok
+}
+
+// Set maps the host key name to the keypad key.
+func (k Keymap) Set(name string, key int) error {
+	if key < 0 || key >= chipper.NumKeys {
+		return fmt.Errorf("key '%s': keypad key %#x out of range", name, key)
+	}
+
+	k[Normalize(name)] = key
+
+	return nil
+}
+
+// Clone returns a copy of k.
+func (k Keymap) Clone() Keymap {
+	c := make(Keymap, len(k))
+	for name, key := range k {
+		c[name] = key
+	}
+
+	return c
+}
+
+// aliases are the alternative names of some keys. The names KeyboardEvent.key
+// gives in browsers are among them.
+var aliases = map[string]string{ //nolint:gochecknoglobals
+	" ":        "space",
+	"spacebar": "space",
+	"return":   "enter",
+	"esc":      "escape",
+	"del":      "delete",
+}
+
+// Normalize returns the canonical name of a host key. Single characters are
+// lowercased, so a key is found whether shift is held or not, and browser
+// names such as "ArrowUp" or "KeyQ" become "up" and "q".
+func Normalize(name string) string {
+	if utf8.RuneCountInString(name) == 1 {
+		name = strings.ToLower(name)
+		if alias, ok := aliases[name]; ok {
+			return alias
+		}
+
+		return name
+	}
+
+	name = strings.ToLower(name)
+
+	for _, prefix := range []string{"arrow", "key", "digit"} {
+		rest := strings.TrimPrefix(name, prefix)
+		if rest != name && (prefix == "arrow" || utf8.RuneCountInString(rest) == 1) {
+			return rest
+		}
+	}
+
+	if alias, ok := aliases[name]; ok {
+		return alias
+	}
+
+	return name
+}
+
+// keypad lists the COSMAC VIP keypad row by row.
+var keypad = [4][4]int{ //nolint:gochecknoglobals
+	{0x1, 0x2, 0x3, 0xC},
+	{0x4, 0x5, 0x6, 0xD},
+	{0x7, 0x8, 0x9, 0xE},
+	{0xA, 0x0, 0xB, 0xF},
+}
+
+// layouts holds, for each keyboard layout, the keys in the top left 4x4 block
+// of the keyboard, which are mapped onto the keypad. A row may list more than
+// one set of keys, such as AZERTY's number row with and without shift.
+var layouts = map[string][4][]string{ //nolint:gochecknoglobals
+	"qwerty": {{"1234"}, {"qwer"}, {"asdf"}, {"zxcv"}},
+	"qwertz": {{"1234"}, {"qwer"}, {"asdf"}, {"yxcv"}},
+	"azerty": {{"1234", "&é\"'"}, {"azer"}, {"qsdf"}, {"wxcv"}},
+	"dvorak": {{"1234"}, {"',.p"}, {"aoeu"}, {";qjk"}},
+}
+
+// Layouts returns the names of the built-in layouts.
+func Layouts() []string {
+	names := make([]string, 0, len(layouts))
+	for name := range layouts {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Layout returns the standard COSMAC VIP keypad on the named keyboard layout,
+// one of Layouts:
+//
+//	1 2 3 4      1 2 3 C
+//	q w e r  ->  4 5 6 D
+//	a s d f      7 8 9 E
+//	z x c v      A 0 B F
+func Layout(name string) (Keymap, error) {
+	rows, ok := layouts[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown layout '%s', want one of: %s", name, strings.Join(Layouts(), ", "))
+	}
+
+	k := make(Keymap)
+
+	for r, row := range rows {
+		for _, keys := range row {
+			for c, char := range []rune(keys) {
+				k[Normalize(string(char))] = keypad[r][c]
+			}
+		}
+	}
+
+	return k, nil
+}
+
+// Default returns the keypad on a QWERTY keyboard.
+func Default() Keymap {
+	k, _ := Layout("qwerty")
+
+	return k
+}
+
+// controls maps the named controls of the ROM database to host keys.
+var controls = map[string]string{ //nolint:gochecknoglobals
+	"up":    "up",
+	"down":  "down",
+	"left":  "left",
+	"right": "right",
+	"a":     "space",
+	"b":     "enter",
+}
+
+// WithControls returns a copy of k which also maps the arrow keys, space and
+// enter to the keypad keys a ROM uses for up, down, left, right, a and b, as
+// listed by romdb.ROM.Keys. Other controls are ignored.
+func (k Keymap) WithControls(keys map[string]int) Keymap {
+	c := k.Clone()
+
+	for control, key := range keys {
+		if name, ok := controls[control]; ok {
+			_ = c.Set(name, key)
+		}
+	}
+
+	return c
+}
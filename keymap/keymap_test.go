@@ -0,0 +1,121 @@
+package keymap
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		layout string
+		want   map[string]int
+	}{
+		{"qwerty", map[string]int{"1": 0x1, "4": 0xC, "q": 0x4, "R": 0xD, "x": 0x0, "v": 0xF}},
+		{"qwertz", map[string]int{"y": 0xA, "w": 0x5}},
+		{"azerty", map[string]int{"&": 0x1, "é": 0x2, "1": 0x1, "a": 0x4, "q": 0x7, "w": 0xA}},
+		{"dvorak", map[string]int{"'": 0x4, "p": 0xD, "o": 0x8, ";": 0xA, "k": 0xF}},
+	}
+
+	for _, tc := range tests {
+		k, err := Layout(tc.layout)
+		if err != nil {
+			t.Fatalf("could not get layout: %v", err)
+		}
+
+		for name, key := range tc.want {
+			if got, ok := k.Lookup(name); !ok || got != key {
+				t.Errorf("%s '%s': want %#x, got %#x", tc.layout, name, key, got)
+			}
+		}
+	}
+
+	if len(Default()) != 16 {
+		t.Fatalf("want 16 mappings, got %d", len(Default()))
+	}
+
+	if _, err := Layout("colemak"); err == nil {
+		t.Fatal("expected an error for an unknown layout")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for name, want := range map[string]string{
+		"Q":        "q",
+		"KeyQ":     "q",
+		"Digit4":   "4",
+		"ArrowUp":  "up",
+		" ":        "space",
+		"Enter":    "enter",
+		"Return":   "enter",
+		"keyboard": "keyboard",
+	} {
+		if got := Normalize(name); got != want {
+			t.Errorf("'%s': want '%s', got '%s'", name, want, got)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	// Pong in the ROM database moves player one with 1 and 4.
+	pong, err := os.ReadFile("../roms/set-1/Pong.ch8")
+	if err != nil {
+		t.Fatalf("could not read rom: %v", err)
+	}
+
+	// the hash of the empty ROM.
+	f, err := Load(strings.NewReader(`{
+		"layout": "azerty",
+		"keys": {"ArrowLeft": "4", "right": 6},
+		"roms": {"DA39A3EE5E6B4B0D3255BFEF95601890AFD80709": {"space": "0xa"}}
+	}`))
+	if err != nil {
+		t.Fatalf("could not load keymap: %v", err)
+	}
+
+	k, err := f.Keymap(pong)
+	if err != nil {
+		t.Fatalf("could not build keymap: %v", err)
+	}
+
+	want := map[string]int{"a": 0x4, "left": 0x4, "right": 0x6, "up": 0x1, "down": 0x4}
+	for name, key := range want {
+		if got, ok := k.Lookup(name); !ok || got != key {
+			t.Errorf("'%s': want %#x, got %#x", name, key, got)
+		}
+	}
+
+	if _, ok := k.Lookup("space"); ok {
+		t.Error("space should only be mapped for the empty rom")
+	}
+
+	noControls, err := f.KeymapWithControls(pong, nil)
+	if err != nil {
+		t.Fatalf("could not build keymap: %v", err)
+	}
+
+	if got, ok := noControls.Lookup("up"); ok && got == 0x1 {
+		t.Error("up should only be mapped from the ROM database's controls")
+	}
+
+	empty, err := f.Keymap([]byte{})
+	if err != nil {
+		t.Fatalf("could not build keymap: %v", err)
+	}
+
+	if got, ok := empty.Lookup("space"); !ok || got != 0xA {
+		t.Errorf("space: want 0xa, got %#x", got)
+	}
+
+	for _, s := range []string{
+		`{"layout": "colemak"}`,
+		`{"keys": {"up": "10"}}`,
+		`{"keys": {"up": "x"}}`,
+		`{"roms": {"abc": {"up": 1.5}}}`,
+		`{"unknown": true}`,
+	} {
+		if _, err := Load(strings.NewReader(s)); err == nil {
+			t.Errorf("expected error loading %s", s)
+		}
+	}
+}
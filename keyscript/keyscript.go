@@ -0,0 +1,276 @@
+// Package keyscript drives the CHIP-8 keypad from a script, to play ROMs
+// automatically in tests and headless runs.
+//
+// A script has one event per line. Each event is a trigger, an action and a
+// keypad key in hex:
+//
+//	# comments start with a hash.
+//	frame 120 press 5     # at the start of frame 120.
+//	frame 130 release 5
+//	frame 200 hold 4 3s   # press 4, release it 3 seconds (180 frames) later.
+//	pc 0x2F4 press A      # the first time the instruction at 0x2F4 runs.
+//	pc 0x2F4 hold A 10    # durations without a unit are in frames.
+//
+// Frames are counted in timer ticks from the start of the run, so frame 0 is
+// before the first instruction. Durations are either frames, with an
+// optional "f" suffix, or a time such as "500ms", rounded to whole frames.
+//
+// Scripts can also be written in JSON, as a list of events with the same
+// fields, see ParseJSON:
+//
+//	[
+//	  {"frame": 120, "action": "press", "key": "5"},
+//	  {"pc": "0x2F4", "action": "hold", "key": "A", "duration": "10"}
+//	]
+package keyscript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// FrameRate is the rate at which the emulator's timers tick.
+const FrameRate = 60
+
+type Action int
+
+const (
+	Press Action = iota
+	Release
+	Hold
+)
+
+func (a Action) String() string {
+	switch a {
+	case Press:
+		return "press"
+	case Release:
+		return "release"
+	case Hold:
+		return "hold"
+	default:
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+}
+
+// Event is a single line of a script.
+type Event struct {
+	Line   int    // 1-based, the position in the list for JSON scripts.
+	OnPC   bool   // triggered by PC rather than by frame.
+	Frame  uint64 // if !OnPC.
+	PC     uint16 // if OnPC.
+	Action Action
+	Key    int
+	Frames uint64 // how long a Hold lasts.
+}
+
+func (e Event) String() string {
+	trigger := fmt.Sprintf("frame %d", e.Frame)
+	if e.OnPC {
+		trigger = fmt.Sprintf("pc %#03x", e.PC)
+	}
+
+	s := fmt.Sprintf("%s %s %X", trigger, e.Action, e.Key)
+	if e.Action == Hold {
+		s += fmt.Sprintf(" %d", e.Frames)
+	}
+
+	return s
+}
+
+// Script is a parsed script.
+type Script struct {
+	Events []Event
+}
+
+// ParseFile reads the script at path, in JSON if its extension is .json.
+func ParseFile(path string) (*Script, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open script: %w", err)
+	}
+	defer fd.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ParseJSON(fd)
+	}
+
+	return Parse(fd)
+}
+
+// Parse reads a script.
+func Parse(r io.Reader) (*Script, error) {
+	script := &Script{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		event, err := parseEvent(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		event.Line = line
+		script.Events = append(script.Events, event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read script: %w", err)
+	}
+
+	return script, nil
+}
+
+// jsonEvent is an event of a JSON script. Exactly one of Frame and PC is
+// set, and Duration only for holds.
+type jsonEvent struct {
+	Frame    *uint64 `json:"frame,omitempty"`
+	PC       string  `json:"pc,omitempty"`
+	Action   string  `json:"action"`
+	Key      string  `json:"key"`
+	Duration string  `json:"duration,omitempty"`
+}
+
+// ParseJSON reads a script written as a JSON list of events.
+func ParseJSON(r io.Reader) (*Script, error) {
+	var events []jsonEvent
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&events); err != nil {
+		return nil, fmt.Errorf("could not decode script: %w", err)
+	}
+
+	script := &Script{}
+
+	for k, e := range events {
+		var fields []string
+
+		switch {
+		case e.Frame != nil && e.PC != "":
+			return nil, fmt.Errorf("event %d: want either a frame or a pc, got both", k+1)
+		case e.Frame != nil:
+			fields = []string{"frame", strconv.FormatUint(*e.Frame, 10)}
+		case e.PC != "":
+			fields = []string{"pc", e.PC}
+		default:
+			return nil, fmt.Errorf("event %d: want a frame or a pc", k+1)
+		}
+
+		fields = append(fields, e.Action, e.Key)
+		if e.Duration != "" {
+			fields = append(fields, e.Duration)
+		}
+
+		event, err := parseEvent(fields)
+		if err != nil {
+			return nil, fmt.Errorf("event %d: %w", k+1, err)
+		}
+
+		event.Line = k + 1
+		script.Events = append(script.Events, event)
+	}
+
+	return script, nil
+}
+
+func parseEvent(fields []string) (Event, error) {
+	const minFields = 4
+
+	var event Event
+
+	if len(fields) < minFields {
+		return event, fmt.Errorf("want '<frame N|pc ADDR> <action> <key>', got '%s'", strings.Join(fields, " "))
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "frame":
+		n, err := strconv.ParseUint(fields[1], 0, 64)
+		if err != nil {
+			return event, fmt.Errorf("invalid frame '%s'", fields[1])
+		}
+
+		event.Frame = n
+	case "pc":
+		n, err := strconv.ParseUint(fields[1], 0, 16)
+		if err != nil {
+			return event, fmt.Errorf("invalid address '%s'", fields[1])
+		}
+
+		event.OnPC = true
+		event.PC = uint16(n)
+	default:
+		return event, fmt.Errorf("unknown trigger '%s', want frame or pc", fields[0])
+	}
+
+	key, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(fields[3]), "0x"), 16, 8)
+	if err != nil || key >= chipper.NumKeys {
+		return event, fmt.Errorf("invalid key '%s', want a hex digit", fields[3])
+	}
+
+	event.Key = int(key)
+
+	action, rest := strings.ToLower(fields[2]), fields[4:]
+
+	switch action {
+	case "press", "release":
+		if len(rest) != 0 {
+			return event, fmt.Errorf("unexpected '%s' after %s", strings.Join(rest, " "), action)
+		}
+
+		event.Action = Press
+		if action == "release" {
+			event.Action = Release
+		}
+	case "hold":
+		if len(rest) != 1 {
+			return event, fmt.Errorf("want 'hold <key> <duration>'")
+		}
+
+		frames, err := parseDuration(rest[0])
+		if err != nil {
+			return event, err
+		}
+
+		event.Action = Hold
+		event.Frames = frames
+	default:
+		return event, fmt.Errorf("unknown action '%s', want press, release or hold", fields[2])
+	}
+
+	return event, nil
+}
+
+// parseDuration returns the number of frames in s, either frames or a time.
+func parseDuration(s string) (uint64, error) {
+	if n, err := strconv.ParseUint(strings.TrimSuffix(s, "f"), 10, 64); err == nil {
+		return n, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration '%s', want frames or a time such as 3s", s)
+	}
+
+	return uint64(math.Round(d.Seconds() * FrameRate)), nil
+}
@@ -0,0 +1,277 @@
+package keyscript
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aalbacetef/chipper"
+)
+
+func TestParse(t *testing.T) {
+	s, err := Parse(strings.NewReader(`
+# a comment
+frame 120 press 5
+frame 130 release 5   # trailing comment
+FRAME 200 hold 4 3s
+pc 0x2F4 press a
+pc 0x2F4 hold 0xA 10f
+frame 0 hold f 250ms
+`))
+	if err != nil {
+		t.Fatalf("could not parse: %v", err)
+	}
+
+	want := []string{
+		"frame 120 press 5",
+		"frame 130 release 5",
+		"frame 200 hold 4 180",
+		"pc 0x2f4 press A",
+		"pc 0x2f4 hold A 10",
+		"frame 0 hold F 15",
+	}
+
+	if len(s.Events) != len(want) {
+		t.Fatalf("want %d events, got %d", len(want), len(s.Events))
+	}
+
+	for k, e := range s.Events {
+		if e.String() != want[k] {
+			t.Errorf("event %d: want '%s', got '%s'", k, want[k], e)
+		}
+	}
+
+	if s.Events[2].Line != 5 {
+		t.Errorf("want line 5, got %d", s.Events[2].Line)
+	}
+
+	for _, bad := range []string{
+		"frame 1 press",
+		"frame x press 1",
+		"pc 0x10000 press 1",
+		"at 1 press 1",
+		"frame 1 push 1",
+		"frame 1 press 10",
+		"frame 1 press 1 2",
+		"frame 1 hold 1",
+		"frame 1 hold 1 soon",
+	} {
+		if _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected error parsing '%s'", bad)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	s, err := ParseJSON(strings.NewReader(`[
+		{"frame": 120, "action": "press", "key": "5"},
+		{"pc": "0x2F4", "action": "hold", "key": "a", "duration": "3s"}
+	]`))
+	if err != nil {
+		t.Fatalf("could not parse: %v", err)
+	}
+
+	want := []string{
+		"frame 120 press 5",
+		"pc 0x2f4 hold A 180",
+	}
+
+	if len(s.Events) != len(want) {
+		t.Fatalf("want %d events, got %d", len(want), len(s.Events))
+	}
+
+	for k, e := range s.Events {
+		if e.String() != want[k] || e.Line != k+1 {
+			t.Errorf("event %d: want '%s', got '%s' at %d", k, want[k], e, e.Line)
+		}
+	}
+
+	for _, bad := range []string{
+		`{"frame": 1}`,
+		`[{"action": "press", "key": "1"}]`,
+		`[{"frame": 1, "pc": "0x200", "action": "press", "key": "1"}]`,
+		`[{"frame": 1, "action": "press", "key": "1", "when": "now"}]`,
+		`[{"frame": 1, "action": "hold", "key": "1"}]`,
+	} {
+		if _, err := ParseJSON(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected error parsing '%s'", bad)
+		}
+	}
+}
+
+func newEmulator(t *testing.T, rom []byte, p *Player) *chipper.Emulator {
+	t.Helper()
+
+	display, err := chipper.NewDebugDisplay(64, 32)
+	if err != nil {
+		t.Fatalf("could not create display: %v", err)
+	}
+
+	emu, err := chipper.NewEmulator(16, 4096, display, p)
+	if err != nil {
+		t.Fatalf("could not create emulator: %v", err)
+	}
+
+	if err := emu.Load(bytes.NewReader(rom)); err != nil {
+		t.Fatalf("could not load rom: %v", err)
+	}
+
+	p.Attach(emu)
+
+	return emu
+}
+
+func TestPlayer(t *testing.T) {
+	s, err := Parse(strings.NewReader(`
+frame 2 press 5
+frame 4 release 5
+frame 3 hold 1 2
+pc 0x202 press 9
+frame 50 press 7
+`))
+	if err != nil {
+		t.Fatalf("could not parse: %v", err)
+	}
+
+	// a loop of JP 0x200, with a second instruction at 0x202 only reached
+	// after key 9 is pressed by hand.
+	p := NewPlayer(s)
+	emu := newEmulator(t, []byte{0x12, 0x00, 0x12, 0x02}, p)
+
+	want := []map[int]bool{
+		{5: false, 1: false},
+		{5: false, 1: false},
+		{5: true, 1: false},
+		{5: true, 1: true},
+		{5: false, 1: true},
+		{5: false, 1: false},
+	}
+
+	for frame, keys := range want {
+		for key, pressed := range keys {
+			if p.Get(key) != pressed {
+				t.Fatalf("frame %d: key %X should be %v", frame, key, pressed)
+			}
+		}
+
+		if err := emu.RunFrame(1); err != nil {
+			t.Fatalf("could not run frame: %v", err)
+		}
+	}
+
+	if p.Get(9) {
+		t.Fatal("key 9 should wait for PC to reach 0x202")
+	}
+
+	emu.PC = 0x202
+	if err := emu.Step(); err != nil {
+		t.Fatalf("could not step: %v", err)
+	}
+
+	if !p.Get(9) {
+		t.Fatal("key 9 should be pressed once PC reaches 0x202")
+	}
+
+	// waiting for a key only answers once the press is played, at frame 50.
+	emu.PC = 0x200
+	keys := p.WaitUntilKeypress()
+
+	for emu.FrameCount() < 50 {
+		select {
+		case key := <-keys:
+			t.Fatalf("frame %d: got key %X before its frame", emu.FrameCount(), key)
+		default:
+		}
+
+		if err := emu.RunFrame(1); err != nil {
+			t.Fatalf("could not run frame: %v", err)
+		}
+	}
+
+	if key := <-keys; key != 7 || !p.Get(7) {
+		t.Fatalf("want key 7 pressed, got %X", key)
+	}
+
+	if p.Remaining() != 0 {
+		t.Fatalf("want no events left, got %d", p.Remaining())
+	}
+}
+
+func TestPlayerFX0A(t *testing.T) {
+	s, err := Parse(strings.NewReader("frame 5 press 7"))
+	if err != nil {
+		t.Fatalf("could not parse: %v", err)
+	}
+
+	// LD V0, K then a loop of JP 0x202.
+	p := NewPlayer(s)
+	emu := newEmulator(t, []byte{0xF0, 0x0A, 0x12, 0x02}, p)
+
+	for emu.FrameCount() < 5 {
+		if err := emu.RunFrame(10); err != nil {
+			t.Fatalf("could not run frame: %v", err)
+		}
+
+		if emu.FrameCount() < 5 && emu.PC != 0x200 {
+			t.Fatalf("frame %d: want FX0A to wait for the press at frame 5, PC is %#03x", emu.FrameCount(), emu.PC)
+		}
+	}
+
+	if err := emu.RunFrame(10); err != nil {
+		t.Fatalf("could not run frame: %v", err)
+	}
+
+	if emu.PC != 0x202 || emu.V[0] != 7 {
+		t.Fatalf("want V0=7 and PC=0x202, got V0=%X and PC=%#03x", emu.V[0], emu.PC)
+	}
+
+	// with no presses left, FX0A waits rather than reading key 0.
+	emu.PC = 0x200
+
+	for k := 0; k < 10; k++ {
+		if err := emu.RunFrame(10); err != nil {
+			t.Fatalf("could not run frame: %v", err)
+		}
+	}
+
+	if emu.PC != 0x200 || emu.V[0] != 7 {
+		t.Fatalf("want FX0A still waiting, got V0=%X and PC=%#03x", emu.V[0], emu.PC)
+	}
+}
+
+// TestTetris plays the first piece of Tetris, which only moves with input.
+func TestTetris(t *testing.T) {
+	rom, err := os.ReadFile("../roms/set-2/tetris.ch8")
+	if err != nil {
+		t.Fatalf("could not read rom: %v", err)
+	}
+
+	run := func(script string) string {
+		s, err := Parse(strings.NewReader(script))
+		if err != nil {
+			t.Fatalf("could not parse: %v", err)
+		}
+
+		emu := newEmulator(t, rom, NewPlayer(s))
+		emu.SetRand(rand.New(rand.NewSource(1))) //nolint:gosec
+
+		for k := 0; k < 120; k++ {
+			if err := emu.RunFrame(10); err != nil {
+				t.Fatalf("could not run frame: %v", err)
+			}
+		}
+
+		return emu.Display.String()
+	}
+
+	idle := run("")
+	if run("") != idle {
+		t.Fatal("runs should be deterministic")
+	}
+
+	if run("frame 10 hold 5 1s") == idle {
+		t.Fatal("moving the piece left should change the screen")
+	}
+}
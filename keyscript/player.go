@@ -0,0 +1,213 @@
+package keyscript
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// Player is a KeyInputSource playing a script. Create the emulator with it,
+// then call Attach so it can follow the emulator's frames and PC.
+//
+// Player is a chipper.KeyPoller, so FX0A keeps running, and the frames keep
+// going, until the script presses a key at its trigger. If the script has no
+// presses left, FX0A waits forever, as it would for a player who walked
+// away. WaitUntilKeypress likewise only answers with the next press played.
+type Player struct {
+	mu       sync.Mutex
+	events   []Event
+	fired    []bool
+	byFrame  []int // indexes of frame events, by frame then script order.
+	next     int   // into byFrame.
+	keys     [chipper.NumKeys]bool
+	releases []release
+	frame    uint64
+
+	latch    chipper.KeyLatch
+	listener chan int
+}
+
+type release struct {
+	frame uint64
+	key   int
+}
+
+func NewPlayer(s *Script) *Player {
+	p := &Player{
+		events: append([]Event(nil), s.Events...),
+		fired:  make([]bool, len(s.Events)),
+	}
+
+	for k, e := range p.events {
+		if !e.OnPC {
+			p.byFrame = append(p.byFrame, k)
+		}
+	}
+
+	sort.SliceStable(p.byFrame, func(a, b int) bool {
+		return p.events[p.byFrame[a]].Frame < p.events[p.byFrame[b]].Frame
+	})
+
+	p.advance(0)
+
+	return p
+}
+
+// Attach registers the player with emu, which should be using it as its
+// KeyInputSource.
+func (p *Player) Attach(emu *chipper.Emulator) {
+	emu.AddFrameFunc(func(emu *chipper.Emulator) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		p.advance(emu.FrameCount())
+	})
+
+	emu.AddTraceFunc(func(emu *chipper.Emulator, _ chipper.Instruction) {
+		pc := emu.PC - chipper.InstructionSize
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		for k, e := range p.events {
+			if e.OnPC && e.PC == pc && !p.fired[k] {
+				p.fire(k)
+			}
+		}
+	})
+}
+
+// advance plays the events and releases due by frame. The caller must hold
+// the lock.
+func (p *Player) advance(frame uint64) {
+	p.frame = frame
+
+	kept := p.releases[:0]
+
+	for _, r := range p.releases {
+		if r.frame <= frame {
+			p.keys[r.key] = false
+		} else {
+			kept = append(kept, r)
+		}
+	}
+
+	p.releases = kept
+
+	for ; p.next < len(p.byFrame); p.next++ {
+		k := p.byFrame[p.next]
+		if p.events[k].Frame > frame {
+			break
+		}
+
+		if !p.fired[k] {
+			p.fire(k)
+		}
+	}
+}
+
+// fire plays the event at index k. The caller must hold the lock.
+func (p *Player) fire(k int) {
+	e := p.events[k]
+	p.fired[k] = true
+
+	switch e.Action {
+	case Press:
+		p.press(e.Key)
+	case Release:
+		p.keys[e.Key] = false
+	case Hold:
+		p.press(e.Key)
+		p.releases = append(p.releases, release{frame: p.frame + e.Frames, key: e.Key})
+	}
+}
+
+// press presses key, answering FX0A if it is waiting. The caller must hold
+// the lock.
+func (p *Player) press(key int) {
+	if !p.keys[key] {
+		p.latch.Press(key)
+
+		if p.listener != nil {
+			p.listener <- key
+			p.listener = nil
+		}
+	}
+
+	p.keys[key] = true
+}
+
+// Remaining returns the number of events not played yet.
+func (p *Player) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := 0
+
+	for _, fired := range p.fired {
+		if !fired {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (p *Player) Get(key int) bool {
+	if key < 0 || key >= chipper.NumKeys {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.keys[key]
+}
+
+// Set presses or releases a key, as if the script did.
+func (p *Player) Set(key int, v bool) {
+	if key < 0 || key >= chipper.NumKeys {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if v {
+		p.press(key)
+
+		return
+	}
+
+	p.keys[key] = false
+}
+
+// PollKeypress reports the first key the script pressed since FX0A started
+// waiting, if any.
+func (p *Player) PollKeypress() (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.latch.Poll()
+}
+
+// ResetKeypress drops the key FX0A was waiting for.
+func (p *Player) ResetKeypress() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.latch.Reset()
+}
+
+// WaitUntilKeypress returns a channel receiving the next key the script
+// presses. It only receives once the emulator runs the frames up to that
+// press, from another goroutine, and never if the script has none left.
+func (p *Player) WaitUntilKeypress() <-chan int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.listener = make(chan int, 1)
+
+	return p.listener
+}
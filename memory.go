@@ -0,0 +1,267 @@
+package chipper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Memory is what instructions read and write through. Tools can wrap the
+// Emulator's Memory to intercept every access, or use Bus.Watch.
+//
+// Addresses are ints, as I plus an offset can go past 0xFFFF.
+type Memory interface {
+	Read(addr int) (byte, error)
+	Write(addr int, v byte) error
+	Size() int
+}
+
+var (
+	// ErrOutOfRange is returned for accesses outside of memory when the
+	// policy is OutOfRangeFault.
+	ErrOutOfRange = errors.New("address out of range")
+
+	// ErrReadOnly is returned for writes to a read-only region.
+	ErrReadOnly = errors.New("region is read-only")
+)
+
+// OutOfRange selects what a Bus does with addresses outside of memory.
+type OutOfRange int
+
+const (
+	OutOfRangeFault  OutOfRange = iota // fail with ErrOutOfRange.
+	OutOfRangeWrap                     // wrap around, as the 4K VIP does.
+	OutOfRangeIgnore                   // read 0 and drop writes.
+)
+
+func (o OutOfRange) String() string {
+	switch o {
+	case OutOfRangeFault:
+		return "fault"
+	case OutOfRangeWrap:
+		return "wrap"
+	case OutOfRangeIgnore:
+		return "ignore"
+	default:
+		return fmt.Sprintf("OutOfRange(%d)", int(o))
+	}
+}
+
+// ParseOutOfRange returns the OutOfRange named by s.
+func ParseOutOfRange(s string) (OutOfRange, error) {
+	for _, o := range []OutOfRange{OutOfRangeFault, OutOfRangeWrap, OutOfRangeIgnore} {
+		if strings.EqualFold(s, o.String()) {
+			return o, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown out of range policy '%s', want one of: fault, wrap, ignore", s)
+}
+
+// Region is a named range of addresses of a Bus.
+type Region struct {
+	Name  string
+	Start int
+	Size  int
+
+	// ReadOnly regions fail writes with ErrReadOnly.
+	ReadOnly bool
+
+	// Mirror makes the region an alias of the Size bytes at Target.
+	Mirror bool
+	Target int
+}
+
+func (r Region) contains(addr int) bool {
+	return addr >= r.Start && addr < r.Start+r.Size
+}
+
+// AccessKind tells reads and writes apart.
+type AccessKind int
+
+const (
+	AccessRead AccessKind = iota
+	AccessWrite
+)
+
+// Access is a read or write seen by a Bus.
+type Access struct {
+	Kind AccessKind
+
+	// Addr is the address accessed, Resolved the byte of RAM it ended up
+	// at after wrapping and mirroring.
+	Addr     int
+	Resolved int
+
+	Value  byte
+	Region string // the name of the region accessed, if any.
+}
+
+// AccessFunc is called by a Bus after every successful access.
+type AccessFunc func(a Access)
+
+// Bus is the Memory of an Emulator: its RAM, divided into regions, with a
+// policy for addresses outside of it.
+type Bus struct {
+	ram      []byte
+	policy   OutOfRange
+	regions  []Region
+	watchers []AccessFunc
+}
+
+// NewBus returns a Bus over ram, without any region.
+func NewBus(ram []byte, policy OutOfRange) *Bus {
+	return &Bus{ram: ram, policy: policy}
+}
+
+func (b *Bus) Size() int {
+	return len(b.ram)
+}
+
+// Policy returns what the Bus does with addresses outside of memory.
+func (b *Bus) Policy() OutOfRange {
+	return b.policy
+}
+
+// Map adds a region. Regions must be within memory and must not overlap, and
+// mirrors must point within memory.
+func (b *Bus) Map(r Region) error {
+	if r.Size <= 0 || r.Start < 0 || r.Start+r.Size > len(b.ram) {
+		return fmt.Errorf("region '%s' [%#03x, %#03x) is not within %d bytes of memory", r.Name, r.Start, r.Start+r.Size, len(b.ram))
+	}
+
+	if r.Mirror && (r.Target < 0 || r.Target+r.Size > len(b.ram)) {
+		return fmt.Errorf("region '%s' mirrors [%#03x, %#03x), which is not within memory", r.Name, r.Target, r.Target+r.Size)
+	}
+
+	for _, other := range b.regions {
+		if r.Start < other.Start+other.Size && other.Start < r.Start+r.Size {
+			return fmt.Errorf("region '%s' overlaps region '%s'", r.Name, other.Name)
+		}
+	}
+
+	b.regions = append(b.regions, r)
+
+	return nil
+}
+
+// Regions returns the mapped regions, in the order they were mapped.
+func (b *Bus) Regions() []Region {
+	return append([]Region(nil), b.regions...)
+}
+
+// SetReadOnly protects the region named name from writes, or lifts the
+// protection.
+func (b *Bus) SetReadOnly(name string, readOnly bool) error {
+	for k := range b.regions {
+		if b.regions[k].Name == name {
+			b.regions[k].ReadOnly = readOnly
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no region '%s'", name)
+}
+
+// RegionAt returns the region addr is in.
+func (b *Bus) RegionAt(addr int) (Region, bool) {
+	for _, r := range b.regions {
+		if r.contains(addr) {
+			return r, true
+		}
+	}
+
+	return Region{}, false
+}
+
+// Watch registers fn to be called after every successful access.
+func (b *Bus) Watch(fn AccessFunc) {
+	b.watchers = append(b.watchers, fn)
+}
+
+// resolve returns the byte of RAM addr refers to, and false if the access
+// is to be ignored.
+func (b *Bus) resolve(addr int) (int, Region, bool, error) {
+	if addr < 0 || addr >= len(b.ram) {
+		switch b.policy {
+		case OutOfRangeWrap:
+			addr %= len(b.ram)
+			if addr < 0 {
+				addr += len(b.ram)
+			}
+		case OutOfRangeIgnore:
+			return 0, Region{}, false, nil
+		default:
+			return 0, Region{}, false, fmt.Errorf("%w: %#03x not in [0, %#03x)", ErrOutOfRange, addr, len(b.ram))
+		}
+	}
+
+	r, _ := b.RegionAt(addr)
+	if r.Mirror {
+		return r.Target + addr - r.Start, r, true, nil
+	}
+
+	return addr, r, true, nil
+}
+
+func (b *Bus) Read(addr int) (byte, error) {
+	resolved, r, ok, err := b.resolve(addr)
+	if !ok {
+		return 0, err
+	}
+
+	v := b.ram[resolved]
+	b.notify(Access{Kind: AccessRead, Addr: addr, Resolved: resolved, Value: v, Region: r.Name})
+
+	return v, nil
+}
+
+func (b *Bus) Write(addr int, v byte) error {
+	resolved, r, ok, err := b.resolve(addr)
+	if !ok {
+		return err
+	}
+
+	if r.ReadOnly {
+		return fmt.Errorf("%w: writing %#03x in '%s'", ErrReadOnly, addr, r.Name)
+	}
+
+	b.ram[resolved] = v
+	b.notify(Access{Kind: AccessWrite, Addr: addr, Resolved: resolved, Value: v, Region: r.Name})
+
+	return nil
+}
+
+func (b *Bus) notify(a Access) {
+	for _, fn := range b.watchers {
+		fn(a)
+	}
+}
+
+// readBytes reads n bytes starting at addr through the emulator's Memory.
+func (emu *Emulator) readBytes(addr, n int) ([]byte, error) {
+	p := make([]byte, n)
+
+	for k := range p {
+		v, err := emu.Memory.Read(addr + k)
+		if err != nil {
+			return nil, err
+		}
+
+		p[k] = v
+	}
+
+	return p, nil
+}
+
+// writeBytes writes p starting at addr through the emulator's Memory.
+func (emu *Emulator) writeBytes(addr int, p []byte) error {
+	for k, v := range p {
+		if err := emu.Memory.Write(addr+k, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package chipper_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/chippertest"
+)
+
+func TestBus(t *testing.T) {
+	t.Run("out of range", func(t *testing.T) {
+		cases := []struct {
+			policy   chipper.OutOfRange
+			wantErr  bool
+			wantRead byte
+			wantRAM  []byte
+		}{
+			{chipper.OutOfRangeFault, true, 0, []byte{1, 2, 3, 4}},
+			{chipper.OutOfRangeWrap, false, 2, []byte{1, 9, 3, 4}},
+			{chipper.OutOfRangeIgnore, false, 0, []byte{1, 2, 3, 4}},
+		}
+
+		for _, c := range cases {
+			t.Run(c.policy.String(), func(t *testing.T) {
+				ram := []byte{1, 2, 3, 4}
+				bus := chipper.NewBus(ram, c.policy)
+
+				v, err := bus.Read(5)
+				if c.wantErr != (err != nil) || v != c.wantRead {
+					t.Fatalf("read: want %d (error: %t), got %d (%v)", c.wantRead, c.wantErr, v, err)
+				}
+
+				err = bus.Write(5, 9)
+				if c.wantErr && !errors.Is(err, chipper.ErrOutOfRange) {
+					t.Fatalf("write: want ErrOutOfRange, got %v", err)
+				}
+
+				if string(ram) != string(c.wantRAM) {
+					t.Fatalf("want RAM % x, got % x", c.wantRAM, ram)
+				}
+			})
+		}
+	})
+
+	t.Run("regions", func(t *testing.T) {
+		ram := make([]byte, 16)
+		bus := chipper.NewBus(ram, chipper.OutOfRangeFault)
+
+		for _, r := range []chipper.Region{
+			{Name: "rom", Start: 0, Size: 4, ReadOnly: true},
+			{Name: "mirror", Start: 12, Size: 4, Mirror: true, Target: 4},
+		} {
+			if err := bus.Map(r); err != nil {
+				t.Fatalf("could not map: %v", err)
+			}
+		}
+
+		if err := bus.Map(chipper.Region{Name: "overlap", Start: 2, Size: 4}); err == nil {
+			t.Fatal("want an error mapping overlapping regions")
+		}
+
+		if err := bus.Map(chipper.Region{Name: "outside", Start: 14, Size: 4}); err == nil {
+			t.Fatal("want an error mapping a region past the end of memory")
+		}
+
+		if err := bus.Write(1, 7); !errors.Is(err, chipper.ErrReadOnly) {
+			t.Fatalf("want ErrReadOnly, got %v", err)
+		}
+
+		if err := bus.SetReadOnly("rom", false); err != nil {
+			t.Fatalf("could not unprotect: %v", err)
+		}
+
+		if err := bus.Write(1, 7); err != nil || ram[1] != 7 {
+			t.Fatalf("want the write to go through once unprotected, got %v", err)
+		}
+
+		var accesses []chipper.Access
+
+		bus.Watch(func(a chipper.Access) { accesses = append(accesses, a) })
+
+		if err := bus.Write(13, 5); err != nil {
+			t.Fatalf("could not write: %v", err)
+		}
+
+		if v, _ := bus.Read(5); v != 5 || ram[5] != 5 {
+			t.Fatal("want writes to the mirror to land at its target")
+		}
+
+		want := []chipper.Access{
+			{Kind: chipper.AccessWrite, Addr: 13, Resolved: 5, Value: 5, Region: "mirror"},
+			{Kind: chipper.AccessRead, Addr: 5, Resolved: 5, Value: 5},
+		}
+
+		if len(accesses) != len(want) || accesses[0] != want[0] || accesses[1] != want[1] {
+			t.Fatalf("want accesses %+v, got %+v", want, accesses)
+		}
+	})
+}
+
+func TestEmulatorMemory(t *testing.T) {
+	// FX55 with I at the last byte of RAM.
+	rom := chippertest.Assemble(t,
+		"LD V0, 1",
+		"LD V1, 2",
+		"LD I, 0xFFF",
+		"LD [I], V1",
+	)
+
+	run := func(cfg chipper.Config) (*chipper.Emulator, error) {
+		emu := newConfigEmulator(t, cfg)
+		copy(emu.RAM[cfg.StartAddress:], rom)
+
+		for k := 0; k < 4; k++ {
+			if err := emu.Step(); err != nil {
+				return emu, err
+			}
+		}
+
+		return emu, nil
+	}
+
+	t.Run("fault", func(t *testing.T) {
+		if _, err := run(chipper.DefaultConfig()); !errors.Is(err, chipper.ErrOutOfRange) {
+			t.Fatalf("want ErrOutOfRange, got %v", err)
+		}
+	})
+
+	t.Run("wrap", func(t *testing.T) {
+		cfg, _ := chipper.PresetByName("vip")
+
+		emu, err := run(cfg)
+		if err != nil {
+			t.Fatalf("could not run: %v", err)
+		}
+
+		if emu.RAM[0xFFF] != 1 || emu.RAM[0] != 2 {
+			t.Fatalf("want V1 wrapped around to 0, got % x and % x", emu.RAM[0xFFF], emu.RAM[0])
+		}
+	})
+
+	t.Run("read-only font", func(t *testing.T) {
+		emu := newConfigEmulator(t, chipper.DefaultConfig())
+		bus := emu.Memory.(*chipper.Bus)
+
+		if err := bus.SetReadOnly(chipper.RegionInterpreter, true); err != nil {
+			t.Fatalf("could not protect: %v", err)
+		}
+
+		copy(emu.RAM[chipper.StartAddress:], chippertest.Assemble(t, "LD I, 0x010", "LD B, V0"))
+
+		if err := emu.Step(); err != nil {
+			t.Fatalf("could not step: %v", err)
+		}
+
+		if err := emu.Step(); !errors.Is(err, chipper.ErrReadOnly) {
+			t.Fatalf("want ErrReadOnly writing over the font, got %v", err)
+		}
+	})
+
+	t.Run("load", func(t *testing.T) {
+		emu := newConfigEmulator(t, chipper.DefaultConfig())
+		bus := emu.Memory.(*chipper.Bus)
+
+		writes := 0
+
+		bus.Watch(func(a chipper.Access) {
+			if a.Kind == chipper.AccessWrite && a.Region == chipper.RegionProgram {
+				writes++
+			}
+		})
+
+		if err := emu.Load(bytes.NewReader([]byte{0x12, 0x00})); err != nil || writes != 2 {
+			t.Fatalf("want the ROM written through the bus, got %d writes (%v)", writes, err)
+		}
+
+		if err := bus.SetReadOnly(chipper.RegionProgram, true); err != nil {
+			t.Fatalf("could not protect: %v", err)
+		}
+
+		if err := emu.Load(bytes.NewReader([]byte{0x12, 0x00})); !errors.Is(err, chipper.ErrReadOnly) {
+			t.Fatalf("want ErrReadOnly loading over a read-only program, got %v", err)
+		}
+	})
+}
@@ -2,225 +2,105 @@ package chipper
 
 import "fmt"
 
-type Opcode string
-
-const (
-	Unknown                   Opcode = "Unknown"
-	Nop                       Opcode = "Nop"
-	ExecNNN                   Opcode = "ExecNNN"
-	Clear                     Opcode = "Clear"
-	ReturnFromSub             Opcode = "ReturnFromSub"
-	JumpNNN                   Opcode = "JumpNNN"
-	CallSub                   Opcode = "CallSub"
-	SkipIfXEqNN               Opcode = "SkipIfXEqNN"
-	SkipIfXNotEqNN            Opcode = "SkipIfXNotEqNN"
-	SkipIfXEqY                Opcode = "SkipIfXEqY"
-	StoreNNInX                Opcode = "StoreNNInX"
-	AddNNToX                  Opcode = "AddNNToX"
-	StoreYinX                 Opcode = "StoreYinX"
-	SetXToXORY                Opcode = "SetXToXORY"
-	SetXToXANDY               Opcode = "SetXToXANDY"
-	SetXToXXORY               Opcode = "SetXToXXORY"
-	AddYToX                   Opcode = "AddYToX"
-	SubYFromX                 Opcode = "SubYFromX"
-	StoreYShiftedRightInX     Opcode = "StoreYShiftedRightInX"
-	SetXToYMinusX             Opcode = "SetXToYMinusX"
-	StoreYShiftedLeftInX      Opcode = "StoreYShiftedLeftInX"
-	SkipIfXNotEqY             Opcode = "SkipIfXNotEqY"
-	StoreMemAddrNNNInRegI     Opcode = "StoreMemAddrNNNInRegI"
-	JumpToAddrNNNPlusV0       Opcode = "JumpToAddrNNNPlusV0"
-	SetXToRandomNumWithMaskNN Opcode = "SetXToRandomNumWithMaskNN"
-	DrawSpriteInXY            Opcode = "DrawSpriteInXY"
-	SkipIfKeyInXIsPressed     Opcode = "SkipIfKeyInXIsPressed"
-	SkipIfKeyInXNotPressed    Opcode = "SkipIfKeyInXNotPressed"
-	StoreValDTInX             Opcode = "StoreValDTInX"
-	WaitForKeyAndStoreInX     Opcode = "WaitForKeyAndStoreInX"
-	SetDTToX                  Opcode = "SetDTToX"
-	SetSTToX                  Opcode = "SetSTToX"
-	AddXToI                   Opcode = "AddXToI"
-	SetIToMemAddrOfSpriteInX  Opcode = "SetIToMemAddrOfSpriteInX"
-	StoreBCDOfXInI            Opcode = "StoreBCDOfXInI"
-	Store0ToXInI              Opcode = "Store0ToXInI"
-	Fill0ToXWithValueInAddrI  Opcode = "Fill0ToXWithValueInAddrI"
-)
-
-// DetermineOpcode will return the appropriate Opcode given the digits passed in.
-// It expects digits to have length 4.
-func DetermineOpcode(digits []int) Opcode { //nolint: funlen,gocognit,cyclop,gocyclo
-	first := digits[0]
-	last := digits[3]
-
-	//nolint:mnd
-	switch first {
-	case 0:
-		if match(digits[1:], []int{0, 0, 0}) {
-			return Nop
-		}
-
-		if match(digits[1:], []int{0, 0xE, 0}) {
-			return Clear
-		}
-
-		if match(digits[1:], []int{0, 0xE, 0xE}) {
-			return ReturnFromSub
-		}
-
-		return ExecNNN
-
-	case 1:
-		return JumpNNN
-
-	case 2:
-		return CallSub
-
-	case 3:
-		return SkipIfXEqNN
-
-	case 4:
-		return SkipIfXNotEqNN
-
-	case 5:
-		return SkipIfXEqY
-
-	case 6:
-		return StoreNNInX
-
-	case 7:
-		return AddNNToX
-
-	case 8:
-		switch last {
-		case 0:
-			return StoreYinX
-		case 1:
-			return SetXToXORY
-		case 2:
-			return SetXToXANDY
-		case 3:
-			return SetXToXXORY
-		case 4:
-			return AddYToX
-		case 5:
-			return SubYFromX
-		case 6:
-			return StoreYShiftedRightInX
-		case 7:
-			return SetXToYMinusX
-		case 0xE:
-			return StoreYShiftedLeftInX
-		default:
-			return Unknown
-		}
+//go:generate go run gen_opcodes.go
 
-	case 9:
-		if last == 0 {
-			return SkipIfXNotEqY
-		}
-
-		return Unknown
-
-	case 0xA:
-		return StoreMemAddrNNNInRegI
-
-	case 0xB:
-		return JumpToAddrNNNPlusV0
-
-	case 0xC:
-		return SetXToRandomNumWithMaskNN
+// Opcode names an instruction. The opcodes, with their patterns and syntax,
+// are listed in opcodes.json, from which opcode_table.go is generated.
+type Opcode string
 
-	case 0xD:
-		return DrawSpriteInXY
+// Unknown is the opcode of any instruction not in the table.
+const Unknown Opcode = "Unknown"
 
-	case 0xE:
-		lastTwo := digits[2:]
-		if match(lastTwo, []int{9, 0xE}) {
-			return SkipIfKeyInXIsPressed
-		}
+// OpcodeSpec describes an instruction, as listed in opcodes.json.
+type OpcodeSpec struct {
+	Op Opcode
 
-		if match(lastTwo, []int{0xA, 1}) {
-			return SkipIfKeyInXNotPressed
-		}
+	// Pattern is the instruction as written in references, e.g. "8XY4":
+	// hex digits are fixed, X, Y and N are operands.
+	Pattern string
 
-		return Unknown
+	// Value holds the fixed digits of Pattern, Mask selects them.
+	Value, Mask uint16
 
-	case 0xF:
-		lastTwo := digits[2:]
-		if match(lastTwo, []int{0, 7}) {
-			return StoreValDTInX
-		}
+	// Syntax is the instruction in Cowgod's assembly, e.g. "ADD VX, VY".
+	Syntax string
 
-		if match(lastTwo, []int{0, 0xA}) {
-			return WaitForKeyAndStoreInX
-		}
+	// Operands are the operands in Syntax, in order.
+	Operands []OperandField
 
-		if match(lastTwo, []int{1, 5}) {
-			return SetDTToX
-		}
-
-		if match(lastTwo, []int{1, 8}) {
-			return SetSTToX
-		}
-
-		if match(lastTwo, []int{1, 0xE}) {
-			return AddXToI
-		}
+	Description string
+	Platform    string
+}
 
-		if match(lastTwo, []int{2, 9}) {
-			return SetIToMemAddrOfSpriteInX
-		}
+// OperandField is where an operand is in an instruction.
+type OperandField struct {
+	Name  string // VX, VY, N, NN or NNN.
+	Shift int
+	Bits  int
+}
 
-		if match(lastTwo, []int{3, 3}) {
-			return StoreBCDOfXInI
-		}
+// Get returns the value of the operand in raw.
+func (f OperandField) Get(raw uint16) int {
+	return int(raw>>f.Shift) & (1<<f.Bits - 1)
+}
 
-		if match(lastTwo, []int{5, 5}) {
-			return Store0ToXInI
-		}
+// Specs returns the opcode table, in decoding order.
+func Specs() []OpcodeSpec {
+	return append([]OpcodeSpec(nil), opcodeSpecs...)
+}
 
-		if match(lastTwo, []int{6, 5}) {
-			return Fill0ToXWithValueInAddrI
+// SpecOf returns the spec of op.
+func SpecOf(op Opcode) (OpcodeSpec, bool) {
+	for _, spec := range opcodeSpecs {
+		if spec.Op == op {
+			return spec, true
 		}
+	}
 
-		return Unknown
+	return OpcodeSpec{}, false
+}
 
-	default:
+// DetermineOpcode will return the appropriate Opcode given the digits passed in.
+// It expects 4 digits, returning Unknown otherwise.
+func DetermineOpcode(digits []int) Opcode {
+	const digitCount = 4
+	if len(digits) != digitCount {
 		return Unknown
 	}
-}
 
-func match(in, out []int) bool {
-	if len(in) != len(out) {
-		return false
-	}
+	raw := uint16(0)
 
-	for k, v := range in {
-		if out[k] != v {
-			return false
+	for _, d := range digits {
+		if d < 0 || d > 0xF {
+			return Unknown
 		}
+
+		raw = raw<<4 | uint16(d) //nolint:mnd
 	}
 
-	return true
+	return decodeOpcode(raw)
 }
 
 func Decode(p []byte) (Instruction, error) {
+	if len(p) < InstructionSize {
+		return Instruction{Op: Unknown}, fmt.Errorf("want %d bytes, got %d", InstructionSize, len(p))
+	}
+
 	instr := toUint16(p)
 
-	d0 := (instr & 0xF000) >> (3 * 4) //nolint:mnd
 	d1 := (instr & 0x0F00) >> (2 * 4) //nolint:mnd
 	d2 := (instr & 0x00F0) >> (1 * 4) //nolint:mnd
 	d3 := (instr & 0x000F)            //nolint:mnd
 
-	digits := [4]int{
-		int(d0), int(d1), int(d2), int(d3),
-	}
+	operands := []int{int(d1), int(d2), int(d3)}
 
-	opcode := DetermineOpcode(digits[:])
+	opcode := decodeOpcode(instr)
 	if opcode == Unknown {
-		return Instruction{Op: opcode, Operands: digits[1:]}, fmt.Errorf("unknown opcode: %#0x", instr)
+		return Instruction{Op: opcode, Operands: operands}, fmt.Errorf("unknown opcode: %#0x", instr)
 	}
 
 	return Instruction{
 		Op:       opcode,
-		Operands: digits[1:],
+		Operands: operands,
 	}, nil
 }
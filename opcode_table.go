@@ -0,0 +1,374 @@
+// Code generated by gen_opcodes.go from opcodes.json; DO NOT EDIT.
+
+package chipper
+
+const (
+	// 0000: Do nothing. A 0NNN with NNN=0, which ROMs use as padding.
+	Nop Opcode = "Nop"
+	// 00E0: Clear the display.
+	Clear Opcode = "Clear"
+	// 00EE: Return from a subroutine, popping PC from the stack.
+	ReturnFromSub Opcode = "ReturnFromSub"
+	// 0NNN: Call the machine code routine at NNN. Not supported.
+	ExecNNN Opcode = "ExecNNN"
+	// 1NNN: Jump to NNN.
+	JumpNNN Opcode = "JumpNNN"
+	// 2NNN: Call the subroutine at NNN, pushing PC on the stack.
+	CallSub Opcode = "CallSub"
+	// 3XNN: Skip the next instruction if VX == NN.
+	SkipIfXEqNN Opcode = "SkipIfXEqNN"
+	// 4XNN: Skip the next instruction if VX != NN.
+	SkipIfXNotEqNN Opcode = "SkipIfXNotEqNN"
+	// 5XY0: Skip the next instruction if VX == VY.
+	SkipIfXEqY Opcode = "SkipIfXEqY"
+	// 6XNN: Set VX to NN.
+	StoreNNInX Opcode = "StoreNNInX"
+	// 7XNN: Add NN to VX, wrapping around. VF is not changed.
+	AddNNToX Opcode = "AddNNToX"
+	// 8XY0: Set VX to VY.
+	StoreYinX Opcode = "StoreYinX"
+	// 8XY1: Set VX to VX | VY. Resets VF with the Logic quirk.
+	SetXToXORY Opcode = "SetXToXORY"
+	// 8XY2: Set VX to VX & VY. Resets VF with the Logic quirk.
+	SetXToXANDY Opcode = "SetXToXANDY"
+	// 8XY3: Set VX to VX ^ VY. Resets VF with the Logic quirk.
+	SetXToXXORY Opcode = "SetXToXXORY"
+	// 8XY4: Add VY to VX, then set VF to the carry.
+	AddYToX Opcode = "AddYToX"
+	// 8XY5: Subtract VY from VX, then set VF to 0 on borrow and 1 otherwise.
+	SubYFromX Opcode = "SubYFromX"
+	// 8XY6: Set VX to VY >> 1, then set VF to the dropped bit. Shifts VX with the Shift quirk.
+	StoreYShiftedRightInX Opcode = "StoreYShiftedRightInX"
+	// 8XY7: Set VX to VY - VX, then set VF to 0 on borrow and 1 otherwise.
+	SetXToYMinusX Opcode = "SetXToYMinusX"
+	// 8XYE: Set VX to VY << 1, then set VF to the dropped bit. Shifts VX with the Shift quirk.
+	StoreYShiftedLeftInX Opcode = "StoreYShiftedLeftInX"
+	// 9XY0: Skip the next instruction if VX != VY.
+	SkipIfXNotEqY Opcode = "SkipIfXNotEqY"
+	// ANNN: Set I to NNN.
+	StoreMemAddrNNNInRegI Opcode = "StoreMemAddrNNNInRegI"
+	// BNNN: Jump to NNN + V0. Jumps to XNN + VX with the Jump quirk.
+	JumpToAddrNNNPlusV0 Opcode = "JumpToAddrNNNPlusV0"
+	// CXNN: Set VX to a random byte & NN.
+	SetXToRandomNumWithMaskNN Opcode = "SetXToRandomNumWithMaskNN"
+	// DXYN: XOR the N-byte sprite at I onto the display at (VX, VY), setting VF if a pixel is erased.
+	DrawSpriteInXY Opcode = "DrawSpriteInXY"
+	// EX9E: Skip the next instruction if the key in VX is pressed.
+	SkipIfKeyInXIsPressed Opcode = "SkipIfKeyInXIsPressed"
+	// EXA1: Skip the next instruction if the key in VX is not pressed.
+	SkipIfKeyInXNotPressed Opcode = "SkipIfKeyInXNotPressed"
+	// FX07: Set VX to the delay timer.
+	StoreValDTInX Opcode = "StoreValDTInX"
+	// FX0A: Wait for a key press and store the key in VX.
+	WaitForKeyAndStoreInX Opcode = "WaitForKeyAndStoreInX"
+	// FX15: Set the delay timer to VX.
+	SetDTToX Opcode = "SetDTToX"
+	// FX18: Set the sound timer to VX.
+	SetSTToX Opcode = "SetSTToX"
+	// FX1E: Add VX to I.
+	AddXToI Opcode = "AddXToI"
+	// FX29: Set I to the font sprite of the hex digit in VX.
+	SetIToMemAddrOfSpriteInX Opcode = "SetIToMemAddrOfSpriteInX"
+	// FX33: Store the hundreds, tens and units of VX at I, I+1 and I+2.
+	StoreBCDOfXInI Opcode = "StoreBCDOfXInI"
+	// FX55: Store V0 to VX, inclusive, in RAM from I. I is moved past them unless the memory quirks say otherwise.
+	Store0ToXInI Opcode = "Store0ToXInI"
+	// FX65: Load V0 to VX, inclusive, from RAM from I. I is moved past them unless the memory quirks say otherwise.
+	Fill0ToXWithValueInAddrI Opcode = "Fill0ToXWithValueInAddrI"
+)
+
+var opcodeSpecs = []OpcodeSpec{
+	{
+		Op: Nop, Pattern: "0000", Value: 0x0000, Mask: 0xFFFF,
+		Syntax: "NOP", Platform: "chip-8",
+		Description: "Do nothing. A 0NNN with NNN=0, which ROMs use as padding.",
+	},
+	{
+		Op: Clear, Pattern: "00E0", Value: 0x00E0, Mask: 0xFFFF,
+		Syntax: "CLS", Platform: "chip-8",
+		Description: "Clear the display.",
+	},
+	{
+		Op: ReturnFromSub, Pattern: "00EE", Value: 0x00EE, Mask: 0xFFFF,
+		Syntax: "RET", Platform: "chip-8",
+		Description: "Return from a subroutine, popping PC from the stack.",
+	},
+	{
+		Op: ExecNNN, Pattern: "0NNN", Value: 0x0000, Mask: 0xF000,
+		Syntax: "SYS NNN", Platform: "chip-8",
+		Description: "Call the machine code routine at NNN. Not supported.",
+		Operands:    []OperandField{{Name: "NNN", Shift: 0, Bits: 12}},
+	},
+	{
+		Op: JumpNNN, Pattern: "1NNN", Value: 0x1000, Mask: 0xF000,
+		Syntax: "JP NNN", Platform: "chip-8",
+		Description: "Jump to NNN.",
+		Operands:    []OperandField{{Name: "NNN", Shift: 0, Bits: 12}},
+	},
+	{
+		Op: CallSub, Pattern: "2NNN", Value: 0x2000, Mask: 0xF000,
+		Syntax: "CALL NNN", Platform: "chip-8",
+		Description: "Call the subroutine at NNN, pushing PC on the stack.",
+		Operands:    []OperandField{{Name: "NNN", Shift: 0, Bits: 12}},
+	},
+	{
+		Op: SkipIfXEqNN, Pattern: "3XNN", Value: 0x3000, Mask: 0xF000,
+		Syntax: "SE VX, NN", Platform: "chip-8",
+		Description: "Skip the next instruction if VX == NN.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "NN", Shift: 0, Bits: 8}},
+	},
+	{
+		Op: SkipIfXNotEqNN, Pattern: "4XNN", Value: 0x4000, Mask: 0xF000,
+		Syntax: "SNE VX, NN", Platform: "chip-8",
+		Description: "Skip the next instruction if VX != NN.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "NN", Shift: 0, Bits: 8}},
+	},
+	{
+		Op: SkipIfXEqY, Pattern: "5XY0", Value: 0x5000, Mask: 0xF00F,
+		Syntax: "SE VX, VY", Platform: "chip-8",
+		Description: "Skip the next instruction if VX == VY.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "VY", Shift: 4, Bits: 4}},
+	},
+	{
+		Op: StoreNNInX, Pattern: "6XNN", Value: 0x6000, Mask: 0xF000,
+		Syntax: "LD VX, NN", Platform: "chip-8",
+		Description: "Set VX to NN.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "NN", Shift: 0, Bits: 8}},
+	},
+	{
+		Op: AddNNToX, Pattern: "7XNN", Value: 0x7000, Mask: 0xF000,
+		Syntax: "ADD VX, NN", Platform: "chip-8",
+		Description: "Add NN to VX, wrapping around. VF is not changed.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "NN", Shift: 0, Bits: 8}},
+	},
+	{
+		Op: StoreYinX, Pattern: "8XY0", Value: 0x8000, Mask: 0xF00F,
+		Syntax: "LD VX, VY", Platform: "chip-8",
+		Description: "Set VX to VY.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "VY", Shift: 4, Bits: 4}},
+	},
+	{
+		Op: SetXToXORY, Pattern: "8XY1", Value: 0x8001, Mask: 0xF00F,
+		Syntax: "OR VX, VY", Platform: "chip-8",
+		Description: "Set VX to VX | VY. Resets VF with the Logic quirk.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "VY", Shift: 4, Bits: 4}},
+	},
+	{
+		Op: SetXToXANDY, Pattern: "8XY2", Value: 0x8002, Mask: 0xF00F,
+		Syntax: "AND VX, VY", Platform: "chip-8",
+		Description: "Set VX to VX & VY. Resets VF with the Logic quirk.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "VY", Shift: 4, Bits: 4}},
+	},
+	{
+		Op: SetXToXXORY, Pattern: "8XY3", Value: 0x8003, Mask: 0xF00F,
+		Syntax: "XOR VX, VY", Platform: "chip-8",
+		Description: "Set VX to VX ^ VY. Resets VF with the Logic quirk.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "VY", Shift: 4, Bits: 4}},
+	},
+	{
+		Op: AddYToX, Pattern: "8XY4", Value: 0x8004, Mask: 0xF00F,
+		Syntax: "ADD VX, VY", Platform: "chip-8",
+		Description: "Add VY to VX, then set VF to the carry.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "VY", Shift: 4, Bits: 4}},
+	},
+	{
+		Op: SubYFromX, Pattern: "8XY5", Value: 0x8005, Mask: 0xF00F,
+		Syntax: "SUB VX, VY", Platform: "chip-8",
+		Description: "Subtract VY from VX, then set VF to 0 on borrow and 1 otherwise.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "VY", Shift: 4, Bits: 4}},
+	},
+	{
+		Op: StoreYShiftedRightInX, Pattern: "8XY6", Value: 0x8006, Mask: 0xF00F,
+		Syntax: "SHR VX, VY", Platform: "chip-8",
+		Description: "Set VX to VY >> 1, then set VF to the dropped bit. Shifts VX with the Shift quirk.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "VY", Shift: 4, Bits: 4}},
+	},
+	{
+		Op: SetXToYMinusX, Pattern: "8XY7", Value: 0x8007, Mask: 0xF00F,
+		Syntax: "SUBN VX, VY", Platform: "chip-8",
+		Description: "Set VX to VY - VX, then set VF to 0 on borrow and 1 otherwise.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "VY", Shift: 4, Bits: 4}},
+	},
+	{
+		Op: StoreYShiftedLeftInX, Pattern: "8XYE", Value: 0x800E, Mask: 0xF00F,
+		Syntax: "SHL VX, VY", Platform: "chip-8",
+		Description: "Set VX to VY << 1, then set VF to the dropped bit. Shifts VX with the Shift quirk.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "VY", Shift: 4, Bits: 4}},
+	},
+	{
+		Op: SkipIfXNotEqY, Pattern: "9XY0", Value: 0x9000, Mask: 0xF00F,
+		Syntax: "SNE VX, VY", Platform: "chip-8",
+		Description: "Skip the next instruction if VX != VY.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "VY", Shift: 4, Bits: 4}},
+	},
+	{
+		Op: StoreMemAddrNNNInRegI, Pattern: "ANNN", Value: 0xA000, Mask: 0xF000,
+		Syntax: "LD I, NNN", Platform: "chip-8",
+		Description: "Set I to NNN.",
+		Operands:    []OperandField{{Name: "NNN", Shift: 0, Bits: 12}},
+	},
+	{
+		Op: JumpToAddrNNNPlusV0, Pattern: "BNNN", Value: 0xB000, Mask: 0xF000,
+		Syntax: "JP V0, NNN", Platform: "chip-8",
+		Description: "Jump to NNN + V0. Jumps to XNN + VX with the Jump quirk.",
+		Operands:    []OperandField{{Name: "NNN", Shift: 0, Bits: 12}},
+	},
+	{
+		Op: SetXToRandomNumWithMaskNN, Pattern: "CXNN", Value: 0xC000, Mask: 0xF000,
+		Syntax: "RND VX, NN", Platform: "chip-8",
+		Description: "Set VX to a random byte & NN.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "NN", Shift: 0, Bits: 8}},
+	},
+	{
+		Op: DrawSpriteInXY, Pattern: "DXYN", Value: 0xD000, Mask: 0xF000,
+		Syntax: "DRW VX, VY, N", Platform: "chip-8",
+		Description: "XOR the N-byte sprite at I onto the display at (VX, VY), setting VF if a pixel is erased.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}, {Name: "VY", Shift: 4, Bits: 4}, {Name: "N", Shift: 0, Bits: 4}},
+	},
+	{
+		Op: SkipIfKeyInXIsPressed, Pattern: "EX9E", Value: 0xE09E, Mask: 0xF0FF,
+		Syntax: "SKP VX", Platform: "chip-8",
+		Description: "Skip the next instruction if the key in VX is pressed.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}},
+	},
+	{
+		Op: SkipIfKeyInXNotPressed, Pattern: "EXA1", Value: 0xE0A1, Mask: 0xF0FF,
+		Syntax: "SKNP VX", Platform: "chip-8",
+		Description: "Skip the next instruction if the key in VX is not pressed.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}},
+	},
+	{
+		Op: StoreValDTInX, Pattern: "FX07", Value: 0xF007, Mask: 0xF0FF,
+		Syntax: "LD VX, DT", Platform: "chip-8",
+		Description: "Set VX to the delay timer.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}},
+	},
+	{
+		Op: WaitForKeyAndStoreInX, Pattern: "FX0A", Value: 0xF00A, Mask: 0xF0FF,
+		Syntax: "LD VX, K", Platform: "chip-8",
+		Description: "Wait for a key press and store the key in VX.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}},
+	},
+	{
+		Op: SetDTToX, Pattern: "FX15", Value: 0xF015, Mask: 0xF0FF,
+		Syntax: "LD DT, VX", Platform: "chip-8",
+		Description: "Set the delay timer to VX.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}},
+	},
+	{
+		Op: SetSTToX, Pattern: "FX18", Value: 0xF018, Mask: 0xF0FF,
+		Syntax: "LD ST, VX", Platform: "chip-8",
+		Description: "Set the sound timer to VX.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}},
+	},
+	{
+		Op: AddXToI, Pattern: "FX1E", Value: 0xF01E, Mask: 0xF0FF,
+		Syntax: "ADD I, VX", Platform: "chip-8",
+		Description: "Add VX to I.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}},
+	},
+	{
+		Op: SetIToMemAddrOfSpriteInX, Pattern: "FX29", Value: 0xF029, Mask: 0xF0FF,
+		Syntax: "LD F, VX", Platform: "chip-8",
+		Description: "Set I to the font sprite of the hex digit in VX.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}},
+	},
+	{
+		Op: StoreBCDOfXInI, Pattern: "FX33", Value: 0xF033, Mask: 0xF0FF,
+		Syntax: "LD B, VX", Platform: "chip-8",
+		Description: "Store the hundreds, tens and units of VX at I, I+1 and I+2.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}},
+	},
+	{
+		Op: Store0ToXInI, Pattern: "FX55", Value: 0xF055, Mask: 0xF0FF,
+		Syntax: "LD [I], VX", Platform: "chip-8",
+		Description: "Store V0 to VX, inclusive, in RAM from I. I is moved past them unless the memory quirks say otherwise.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}},
+	},
+	{
+		Op: Fill0ToXWithValueInAddrI, Pattern: "FX65", Value: 0xF065, Mask: 0xF0FF,
+		Syntax: "LD VX, [I]", Platform: "chip-8",
+		Description: "Load V0 to VX, inclusive, from RAM from I. I is moved past them unless the memory quirks say otherwise.",
+		Operands:    []OperandField{{Name: "VX", Shift: 8, Bits: 4}},
+	},
+}
+
+// decodeOpcode returns the first opcode in the table matching raw.
+func decodeOpcode(raw uint16) Opcode { //nolint:cyclop,gocyclo,funlen
+	switch {
+	case raw&0xFFFF == 0x0000:
+		return Nop
+	case raw&0xFFFF == 0x00E0:
+		return Clear
+	case raw&0xFFFF == 0x00EE:
+		return ReturnFromSub
+	case raw&0xF000 == 0x0000:
+		return ExecNNN
+	case raw&0xF000 == 0x1000:
+		return JumpNNN
+	case raw&0xF000 == 0x2000:
+		return CallSub
+	case raw&0xF000 == 0x3000:
+		return SkipIfXEqNN
+	case raw&0xF000 == 0x4000:
+		return SkipIfXNotEqNN
+	case raw&0xF00F == 0x5000:
+		return SkipIfXEqY
+	case raw&0xF000 == 0x6000:
+		return StoreNNInX
+	case raw&0xF000 == 0x7000:
+		return AddNNToX
+	case raw&0xF00F == 0x8000:
+		return StoreYinX
+	case raw&0xF00F == 0x8001:
+		return SetXToXORY
+	case raw&0xF00F == 0x8002:
+		return SetXToXANDY
+	case raw&0xF00F == 0x8003:
+		return SetXToXXORY
+	case raw&0xF00F == 0x8004:
+		return AddYToX
+	case raw&0xF00F == 0x8005:
+		return SubYFromX
+	case raw&0xF00F == 0x8006:
+		return StoreYShiftedRightInX
+	case raw&0xF00F == 0x8007:
+		return SetXToYMinusX
+	case raw&0xF00F == 0x800E:
+		return StoreYShiftedLeftInX
+	case raw&0xF00F == 0x9000:
+		return SkipIfXNotEqY
+	case raw&0xF000 == 0xA000:
+		return StoreMemAddrNNNInRegI
+	case raw&0xF000 == 0xB000:
+		return JumpToAddrNNNPlusV0
+	case raw&0xF000 == 0xC000:
+		return SetXToRandomNumWithMaskNN
+	case raw&0xF000 == 0xD000:
+		return DrawSpriteInXY
+	case raw&0xF0FF == 0xE09E:
+		return SkipIfKeyInXIsPressed
+	case raw&0xF0FF == 0xE0A1:
+		return SkipIfKeyInXNotPressed
+	case raw&0xF0FF == 0xF007:
+		return StoreValDTInX
+	case raw&0xF0FF == 0xF00A:
+		return WaitForKeyAndStoreInX
+	case raw&0xF0FF == 0xF015:
+		return SetDTToX
+	case raw&0xF0FF == 0xF018:
+		return SetSTToX
+	case raw&0xF0FF == 0xF01E:
+		return AddXToI
+	case raw&0xF0FF == 0xF029:
+		return SetIToMemAddrOfSpriteInX
+	case raw&0xF0FF == 0xF033:
+		return StoreBCDOfXInI
+	case raw&0xF0FF == 0xF055:
+		return Store0ToXInI
+	case raw&0xF0FF == 0xF065:
+		return Fill0ToXWithValueInAddrI
+	default:
+		return Unknown
+	}
+}
@@ -1,6 +1,8 @@
 package chipper
 
 import (
+	"encoding/json"
+	"os"
 	"testing"
 )
 
@@ -20,3 +22,100 @@ func TestOpcode(t *testing.T) {
 		tt.Logf("added: %#0x", vv)
 	})
 }
+
+// TestSpecs checks the generated table matches opcodes.json, and that no
+// pattern is hidden by the ones before it.
+func TestSpecs(t *testing.T) {
+	data, err := os.ReadFile("opcodes.json")
+	if err != nil {
+		t.Fatalf("could not read opcodes: %v", err)
+	}
+
+	var entries []struct {
+		Pattern     string `json:"pattern"`
+		Op          Opcode `json:"op"`
+		Syntax      string `json:"syntax"`
+		Platform    string `json:"platform"`
+		Description string `json:"description"`
+	}
+
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("could not decode opcodes: %v", err)
+	}
+
+	specs := Specs()
+	if len(specs) != len(entries) {
+		t.Fatalf("opcode_table.go is out of date, run go generate: %d specs, %d in opcodes.json", len(specs), len(entries))
+	}
+
+	for k, e := range entries {
+		s := specs[k]
+		if s.Op != e.Op || s.Pattern != e.Pattern || s.Syntax != e.Syntax || s.Platform != e.Platform || s.Description != e.Description {
+			t.Fatalf("opcode_table.go is out of date, run go generate: %+v != %+v", s, e)
+		}
+
+		// with every operand bit set, as 0NNN is Nop when NNN is 0.
+		if op := decodeOpcode(s.Value | ^s.Mask); op != s.Op {
+			t.Errorf("%s decodes to %s, want %s", s.Pattern, op, s.Op)
+		}
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	// every instruction disassembles to text that assembles back to it.
+	for raw := 0; raw <= 0xFFFF; raw++ {
+		text := Disassemble(uint16(raw))
+		if decodeOpcode(uint16(raw)) == Unknown {
+			continue
+		}
+
+		got, err := Assemble(text)
+		if err != nil {
+			t.Fatalf("%#04x: could not assemble '%s': %v", raw, text, err)
+		}
+
+		if got != uint16(raw) {
+			t.Fatalf("'%s' assembles to %#04x, want %#04x", text, got, raw)
+		}
+	}
+
+	cases := []struct {
+		line string
+		want uint16
+	}{
+		{"cls", 0x00E0},
+		{"LD V1, 0x2A", 0x612A},
+		{"ld va, #2a", 0x6A2A},
+		{"LD V1, V2", 0x8120},
+		{"LD V1, DT", 0xF107},
+		{"LD [I], V3", 0xF355},
+		{"JP V0, 0x300", 0xB300},
+		{"  DRW V0, V1, 5 ", 0xD015},
+		{"SE V4, 10", 0x340A},
+	}
+
+	for _, c := range cases {
+		got, err := Assemble(c.line)
+		if err != nil {
+			t.Fatalf("could not assemble '%s': %v", c.line, err)
+		}
+
+		if got != c.want {
+			t.Errorf("'%s': want %#04x, got %#04x", c.line, c.want, got)
+		}
+	}
+
+	for _, bad := range []string{"", "FOO V1", "LD V1", "LD V1, 0x100", "DRW V0, V1, 16", "LD VG, 1", "JP V1, 0x300"} {
+		if _, err := Assemble(bad); err == nil {
+			t.Errorf("expected an error assembling '%s'", bad)
+		}
+	}
+
+	if got := Disassemble(0xFFFF); got != "DW 0xFFFF" {
+		t.Errorf("want DW 0xFFFF, got %s", got)
+	}
+
+	if _, err := Encode(AddYToX, 1); err == nil {
+		t.Error("expected an error encoding too few operands")
+	}
+}
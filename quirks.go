@@ -0,0 +1,40 @@
+package chipper
+
+// Quirks selects between the behaviours of the instructions that differ
+// between CHIP-8 dialects. The field names follow the community CHIP-8
+// database. The zero value turns every quirk off: 8XY6/8XYE shift VY,
+// FX55/FX65 increment I by X+1, sprites are clipped, BNNN adds V0, DXYN
+// draws at once and 8XY1-8XY3 leave VF alone. The COSMAC VIP also needs
+// VBlank and Logic.
+type Quirks struct {
+	// Shift makes 8XY6 and 8XYE shift VX in place instead of storing the
+	// shifted VY in VX.
+	Shift bool
+
+	// MemoryIncrementByX makes FX55 and FX65 increment I by X instead of X+1.
+	MemoryIncrementByX bool
+
+	// MemoryLeaveIUnchanged makes FX55 and FX65 leave I untouched.
+	MemoryLeaveIUnchanged bool
+
+	// Wrap makes sprites wrap around the edges of the screen instead of
+	// being clipped.
+	Wrap bool
+
+	// Jump makes BNNN behave as BXNN, jumping to XNN + VX.
+	Jump bool
+
+	// VBlank makes DXYN wait for the next 60Hz timer tick before drawing.
+	VBlank bool
+
+	// Logic makes 8XY1, 8XY2 and 8XY3 reset VF to 0.
+	Logic bool
+}
+
+// DefaultQuirks returns the behaviour NewEmulator has always had: VY is
+// shifted, I is left unchanged by FX55/FX65 and sprites wrap around the
+// right edge of the screen. With Wrap, they also wrap around the bottom
+// edge, where they used to be cut.
+func DefaultQuirks() Quirks {
+	return Quirks{MemoryLeaveIUnchanged: true, Wrap: true}
+}
@@ -0,0 +1,56 @@
+package romdb
+
+import "github.com/aalbacetef/chipper"
+
+// Platform is a CHIP-8 interpreter a ROM was written for, identified by the
+// IDs used in the CHIP-8 database.
+type Platform struct {
+	ID   string
+	Name string
+
+	// Config is the chipper preset the platform runs as, which holds its
+	// quirks and tick rate.
+	Config chipper.Config
+}
+
+// Platforms returns the platforms known to the database. Platforms chipper has
+// no preset of its own for run as the closest one: hybrid VIP ROMs as plain
+// VIP ones, without their machine code, and SUPER-CHIP 1.0 as CHIP-48, whose
+// quirks it kept.
+func Platforms() []Platform {
+	platforms := []struct {
+		id, name, preset string
+	}{
+		{"originalChip8", "COSMAC VIP CHIP-8", "vip"},
+		{"hybridVIP", "COSMAC VIP CHIP-8 with machine code", "vip"},
+		{"modernChip8", "Modern CHIP-8", "modern"},
+		{"chip48", "CHIP-48", "chip48"},
+		{"superchip1", "SUPER-CHIP 1.0", "chip48"},
+		{"superchip", "SUPER-CHIP 1.1", "schip"},
+		{"xochip", "XO-CHIP", "xochip"},
+	}
+
+	list := make([]Platform, 0, len(platforms))
+
+	for _, p := range platforms {
+		cfg, ok := chipper.PresetByName(p.preset)
+		if !ok {
+			continue
+		}
+
+		list = append(list, Platform{ID: p.id, Name: p.name, Config: cfg})
+	}
+
+	return list
+}
+
+// PlatformByID returns the platform with the given database ID.
+func PlatformByID(id string) (Platform, bool) {
+	for _, p := range Platforms() {
+		if p.ID == id {
+			return p, true
+		}
+	}
+
+	return Platform{}, false
+}
@@ -0,0 +1,235 @@
+// Package romdb identifies CHIP-8 ROMs by their SHA-1 hash and provides the
+// settings they need to run correctly. The embedded database uses the format
+// of the community CHIP-8 database's programs.json.
+package romdb
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/aalbacetef/chipper"
+)
+
+//go:embed programs.json
+var programsJSON []byte //nolint:gochecknoglobals
+
+// Program is a single title, which may have several ROM revisions.
+//
+//nolint:tagliatelle
+type Program struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Release     string         `json:"release,omitempty"`
+	Authors     []string       `json:"authors,omitempty"`
+	ROMs        map[string]ROM `json:"roms"`
+}
+
+// ROM describes a specific ROM file, keyed by its SHA-1 in Program.ROMs.
+//
+//nolint:tagliatelle
+type ROM struct {
+	File            string                `json:"file,omitempty"`
+	EmbeddedTitle   string                `json:"embeddedTitle,omitempty"`
+	Platforms       []string              `json:"platforms"`
+	QuirkyPlatforms map[string]QuirkFlags `json:"quirkyPlatforms,omitempty"`
+	TickRate        int                   `json:"tickrate,omitempty"`
+	Keys            map[string]int        `json:"keys,omitempty"`
+}
+
+// QuirkFlags overrides some of a platform's quirks for a ROM. Unset flags keep
+// the platform's value.
+//
+//nolint:tagliatelle
+type QuirkFlags struct {
+	Shift                 *bool `json:"shift,omitempty"`
+	MemoryIncrementByX    *bool `json:"memoryIncrementByX,omitempty"`
+	MemoryLeaveIUnchanged *bool `json:"memoryLeaveIUnchanged,omitempty"`
+	Wrap                  *bool `json:"wrap,omitempty"`
+	Jump                  *bool `json:"jump,omitempty"`
+	VBlank                *bool `json:"vblank,omitempty"`
+	Logic                 *bool `json:"logic,omitempty"`
+}
+
+// Apply returns q with the flags that are set overriding its fields.
+func (f QuirkFlags) Apply(q chipper.Quirks) chipper.Quirks {
+	set := func(dst *bool, src *bool) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+
+	set(&q.Shift, f.Shift)
+	set(&q.MemoryIncrementByX, f.MemoryIncrementByX)
+	set(&q.MemoryLeaveIUnchanged, f.MemoryLeaveIUnchanged)
+	set(&q.Wrap, f.Wrap)
+	set(&q.Jump, f.Jump)
+	set(&q.VBlank, f.VBlank)
+	set(&q.Logic, f.Logic)
+
+	return q
+}
+
+// DB is a ROM database indexed by SHA-1.
+type DB struct {
+	programs []Program
+	bySHA1   map[string]int
+}
+
+// Load reads a database in the programs.json format.
+func Load(r io.Reader) (*DB, error) {
+	var programs []Program
+	if err := json.NewDecoder(r).Decode(&programs); err != nil {
+		return nil, fmt.Errorf("could not decode database: %w", err)
+	}
+
+	db := &DB{programs: programs, bySHA1: make(map[string]int)}
+
+	for k, p := range programs {
+		for hash := range p.ROMs {
+			db.bySHA1[strings.ToLower(hash)] = k
+		}
+	}
+
+	return db, nil
+}
+
+//nolint:gochecknoglobals
+var defaultDB = sync.OnceValues(func() (*DB, error) {
+	return Load(bytes.NewReader(programsJSON))
+})
+
+// Default returns the embedded database.
+func Default() (*DB, error) {
+	return defaultDB()
+}
+
+// Lookup identifies rom in the embedded database.
+func Lookup(rom []byte) (Entry, bool) {
+	db, err := Default()
+	if err != nil {
+		return Entry{}, false
+	}
+
+	return db.Lookup(rom)
+}
+
+// Lookup identifies rom by its SHA-1.
+func (db *DB) Lookup(rom []byte) (Entry, bool) {
+	return db.LookupSHA1(Hash(rom))
+}
+
+// LookupSHA1 returns the entry for the ROM with the given hex-encoded SHA-1.
+func (db *DB) LookupSHA1(hash string) (Entry, bool) {
+	hash = strings.ToLower(hash)
+
+	k, ok := db.bySHA1[hash]
+	if !ok {
+		return Entry{}, false
+	}
+
+	program := db.programs[k]
+
+	return Entry{SHA1: hash, Program: program, ROM: program.ROMs[hash]}, true
+}
+
+// Len returns the number of ROMs in the database.
+func (db *DB) Len() int {
+	return len(db.bySHA1)
+}
+
+// Hash returns the hex-encoded SHA-1 of rom.
+func Hash(rom []byte) string {
+	sum := sha1.Sum(rom) //nolint:gosec
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Entry is the result of a lookup.
+type Entry struct {
+	SHA1    string
+	Program Program
+	ROM     ROM
+}
+
+func (e Entry) String() string {
+	s := e.Program.Title
+
+	if len(e.Program.Authors) > 0 {
+		s += " by " + strings.Join(e.Program.Authors, ", ")
+	}
+
+	if e.Program.Release != "" {
+		s += " (" + e.Program.Release + ")"
+	}
+
+	return s
+}
+
+// Platform returns the first platform the ROM is known to run on.
+func (e Entry) Platform() (Platform, bool) {
+	return e.ROM.platform()
+}
+
+func (r ROM) platform() (Platform, bool) {
+	for _, id := range r.Platforms {
+		if p, ok := PlatformByID(id); ok {
+			return p, true
+		}
+	}
+
+	return Platform{}, false
+}
+
+// Quirks returns the quirks of the ROM's platform, with any per-ROM overrides
+// applied. ROMs without a known platform get chipper.DefaultQuirks.
+func (e Entry) Quirks() chipper.Quirks {
+	p, ok := e.Platform()
+	if !ok {
+		return chipper.DefaultQuirks()
+	}
+
+	q := p.Config.Quirks
+	if flags, ok := e.ROM.QuirkyPlatforms[p.ID]; ok {
+		q = flags.Apply(q)
+	}
+
+	return q
+}
+
+// TickRate returns the recommended number of instructions per frame, or 0 if
+// neither the ROM nor its platform specify one.
+func (e Entry) TickRate() int {
+	if e.ROM.TickRate > 0 {
+		return e.ROM.TickRate
+	}
+
+	p, _ := e.Platform()
+
+	return p.Config.TickRate
+}
+
+// Config returns the machine to run the ROM on: the preset of its platform,
+// or chipper.DefaultConfig if the platform is unknown, with the ROM's quirks
+// and tick rate. Build the emulator from it, as the platform decides the
+// memory, display and font too.
+func (e Entry) Config() chipper.Config {
+	cfg := chipper.DefaultConfig()
+	if p, ok := e.Platform(); ok {
+		cfg = p.Config
+	}
+
+	cfg.Quirks = e.Quirks()
+
+	if tickRate := e.TickRate(); tickRate > 0 {
+		cfg.TickRate = tickRate
+	}
+
+	return cfg
+}
@@ -0,0 +1,135 @@
+package romdb
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aalbacetef/chipper"
+)
+
+func TestLookup(t *testing.T) {
+	t.Run("it identifies a bundled ROM", func(t *testing.T) {
+		rom, err := os.ReadFile("../roms/set-2/pong.ch8")
+		if err != nil {
+			t.Fatalf("could not read rom: %v", err)
+		}
+
+		entry, ok := Lookup(rom)
+		if !ok {
+			t.Fatalf("pong was not found")
+		}
+
+		if entry.Program.Title != "Pong" {
+			t.Fatalf("want title Pong, got %s", entry.Program.Title)
+		}
+
+		if entry.ROM.Keys["up"] != 1 {
+			t.Fatalf("want up mapped to 1, got %d", entry.ROM.Keys["up"])
+		}
+
+		if entry.TickRate() <= 0 {
+			t.Fatalf("expected a tick rate, got %d", entry.TickRate())
+		}
+	})
+
+	t.Run("it does not identify unknown ROMs", func(t *testing.T) {
+		if _, ok := Lookup([]byte{0x12, 0x00}); ok {
+			t.Fatalf("unexpected match")
+		}
+	})
+
+	t.Run("every hash is a sha1", func(t *testing.T) {
+		db, err := Default()
+		if err != nil {
+			t.Fatalf("could not load database: %v", err)
+		}
+
+		for _, p := range db.programs {
+			for hash, rom := range p.ROMs {
+				const sha1Len = 40
+				if len(hash) != sha1Len {
+					t.Fatalf("%s: invalid hash '%s'", p.Title, hash)
+				}
+
+				if _, ok := rom.platform(); !ok {
+					t.Fatalf("%s: unknown platforms %v", p.Title, rom.Platforms)
+				}
+			}
+		}
+	})
+}
+
+func TestQuirks(t *testing.T) {
+	const data = `[{
+		"title": "Test",
+		"roms": {
+			"0000000000000000000000000000000000000001": {
+				"platforms": ["chip48"],
+				"tickrate": 20,
+				"quirkyPlatforms": {"chip48": {"jump": false, "wrap": true}}
+			},
+			"0000000000000000000000000000000000000002": {
+				"platforms": ["unknownPlatform"]
+			}
+		}
+	}]`
+
+	db, err := Load(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("could not load: %v", err)
+	}
+
+	entry, ok := db.LookupSHA1("0000000000000000000000000000000000000001")
+	if !ok {
+		t.Fatalf("entry not found")
+	}
+
+	want := chipper.Quirks{Shift: true, MemoryIncrementByX: true, Wrap: true}
+	if got := entry.Quirks(); got != want {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+
+	if got := entry.TickRate(); got != 20 {
+		t.Fatalf("want tick rate 20, got %d", got)
+	}
+
+	unknown, _ := db.LookupSHA1("0000000000000000000000000000000000000002")
+	if got := unknown.Quirks(); got != chipper.DefaultQuirks() {
+		t.Fatalf("want default quirks for an unknown platform, got %+v", got)
+	}
+
+	t.Run("config", func(t *testing.T) {
+		want, _ := chipper.PresetByName("chip48")
+		want.Quirks = entry.Quirks()
+		want.TickRate = 20
+
+		if got := entry.Config(); got != want {
+			t.Fatalf("want the chip48 preset with the ROM's settings, got %+v", got)
+		}
+
+		if got := unknown.Config(); got != chipper.DefaultConfig() {
+			t.Fatalf("want the default config for an unknown platform, got %+v", got)
+		}
+	})
+}
+
+func TestPlatforms(t *testing.T) {
+	const platformCount = 7
+
+	platforms := Platforms()
+	if len(platforms) != platformCount {
+		t.Fatalf("want %d platforms, each with a preset, got %d", platformCount, len(platforms))
+	}
+
+	for _, p := range platforms {
+		if err := p.Config.Validate(); err != nil {
+			t.Fatalf("%s: %v", p.ID, err)
+		}
+	}
+
+	p, _ := PlatformByID("superchip")
+	if want, _ := chipper.PresetByName("schip"); p.Config != want {
+		t.Fatalf("want superchip to run as the schip preset, got %+v", p.Config)
+	}
+}
@@ -62,3 +62,11 @@ func (s *Stack) Push(val uint16) error {
 
 	return nil
 }
+
+// Values returns a copy of the elements on the stack, from the bottom up.
+func (s *Stack) Values() []uint16 {
+	values := make([]uint16, s.pointer)
+	copy(values, s.data)
+
+	return values
+}
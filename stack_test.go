@@ -85,4 +85,31 @@ func TestStack(t *testing.T) { //nolint:funlen,gocognit
 			}
 		})
 	})
+
+	t.Run("values", func(tt *testing.T) {
+		stack, err := NewStack(testStackSize)
+		if err != nil {
+			tt.Fatalf("could not make new stack: %v", err)
+		}
+
+		if len(stack.Values()) != 0 {
+			tt.Fatalf("want no values, got %v", stack.Values())
+		}
+
+		for _, v := range []uint16{1, 2} {
+			if err := stack.Push(v); err != nil {
+				tt.Fatalf("could not push element: %v", err)
+			}
+		}
+
+		values := stack.Values()
+		if len(values) != 2 || values[0] != 1 || values[1] != 2 {
+			tt.Fatalf("want [1 2], got %v", values)
+		}
+
+		values[0] = 9
+		if got, _ := stack.Pop(); got != 2 || stack.data[0] != 1 {
+			tt.Fatal("values should be a copy")
+		}
+	})
 }
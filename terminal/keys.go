@@ -0,0 +1,185 @@
+package terminal
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/aalbacetef/chipper"
+	"github.com/aalbacetef/chipper/keymap"
+)
+
+// DefaultKeyTimeout is how long a key stays pressed after the terminal last
+// sent it. Terminals only report key presses, but repeat them while a key is
+// held, so it should be longer than the keyboard's repeat delay.
+const DefaultKeyTimeout = 250 * time.Millisecond
+
+const (
+	ctrlC = 0x03
+	esc   = 0x1B
+)
+
+// arrows are the final bytes of the escape sequences sent by the arrow keys,
+// ESC [ A or ESC O A and so on.
+var arrows = map[rune]string{'A': "up", 'B': "down", 'C': "right", 'D': "left"} //nolint:gochecknoglobals
+
+// Keys is a KeyInputSource reading key presses from a terminal in raw mode. A
+// key is released once Timeout has passed without the terminal repeating it.
+// Ctrl-C closes the channel returned by Quit.
+type Keys struct {
+	Timeout time.Duration
+
+	keymap   keymap.Keymap
+	mu       sync.Mutex
+	until    [chipper.NumKeys]time.Time
+	waiter   chan int
+	quit     chan struct{}
+	quitOnce sync.Once
+	now      func() time.Time
+}
+
+// NewKeys starts reading key presses from r, mapping them to the keypad with
+// km.
+func NewKeys(r io.Reader, km keymap.Keymap) *Keys {
+	k := &Keys{
+		Timeout: DefaultKeyTimeout,
+		keymap:  km,
+		quit:    make(chan struct{}),
+		now:     time.Now,
+	}
+
+	go k.read(r)
+
+	return k
+}
+
+func (k *Keys) read(r io.Reader) {
+	defer k.close()
+
+	br := bufio.NewReader(r)
+
+	for {
+		c, _, err := br.ReadRune()
+		if err != nil || c == ctrlC {
+			return
+		}
+
+		name := string(c)
+		if c == esc {
+			name = readEscape(br)
+		}
+
+		if key, ok := k.keymap.Lookup(name); ok {
+			k.press(key)
+		}
+	}
+}
+
+// readEscape reads the rest of an escape sequence, returning the name of the
+// arrow key it stands for, or "escape". Terminals send a sequence in one go,
+// so an escape with nothing buffered after it is the escape key itself.
+func readEscape(br *bufio.Reader) string {
+	if br.Buffered() == 0 {
+		return "escape"
+	}
+
+	intro, _, err := br.ReadRune()
+	if err != nil || (intro != '[' && intro != 'O') {
+		return "escape"
+	}
+
+	// parameters, if any, come before the final byte.
+	for br.Buffered() > 0 {
+		c, _, err := br.ReadRune()
+		if err != nil {
+			break
+		}
+
+		if c >= 0x40 && c <= 0x7E {
+			if name, ok := arrows[c]; ok {
+				return name
+			}
+
+			break
+		}
+	}
+
+	return "escape"
+}
+
+// close signals Quit, and unblocks a pending WaitUntilKeypress.
+func (k *Keys) close() {
+	k.quitOnce.Do(func() {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		close(k.quit)
+
+		if k.waiter != nil {
+			close(k.waiter)
+			k.waiter = nil
+		}
+	})
+}
+
+func (k *Keys) press(key int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.until[key] = k.now().Add(k.Timeout)
+
+	if k.waiter != nil {
+		k.waiter <- key
+		k.waiter = nil
+	}
+}
+
+// Quit is closed when Ctrl-C is pressed or the input ends.
+func (k *Keys) Quit() <-chan struct{} {
+	return k.quit
+}
+
+func (k *Keys) Get(key int) bool {
+	if key < 0 || key >= chipper.NumKeys {
+		return false
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.now().Before(k.until[key])
+}
+
+func (k *Keys) Set(key int, v bool) {
+	if key < 0 || key >= chipper.NumKeys {
+		return
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if v {
+		k.until[key] = k.now().Add(k.Timeout)
+	} else {
+		k.until[key] = time.Time{}
+	}
+}
+
+// WaitUntilKeypress returns a channel receiving the next key pressed. Keys
+// already held do not count. The channel is closed if input ends first.
+func (k *Keys) WaitUntilKeypress() <-chan int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	ch := make(chan int, 1)
+
+	select {
+	case <-k.quit:
+		close(ch)
+	default:
+		k.waiter = ch
+	}
+
+	return ch
+}
@@ -0,0 +1,93 @@
+package terminal
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aalbacetef/chipper/keymap"
+)
+
+func TestKeys(t *testing.T) {
+	r, w := io.Pipe()
+	now := time.Unix(0, 0)
+
+	k := NewKeys(r, keymap.Default())
+	k.now = func() time.Time { return now }
+
+	wait := k.WaitUntilKeypress()
+
+	if _, err := w.Write([]byte("W")); err != nil {
+		t.Fatalf("could not write: %v", err)
+	}
+
+	select {
+	case key := <-wait:
+		if key != 0x5 {
+			t.Fatalf("want key 5, got %#x", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for key press")
+	}
+
+	if !k.Get(0x5) {
+		t.Fatal("key 5 should be pressed")
+	}
+
+	now = now.Add(DefaultKeyTimeout)
+
+	if k.Get(0x5) {
+		t.Fatal("key 5 should have been released after the timeout")
+	}
+
+	wait = k.WaitUntilKeypress()
+
+	if _, err := w.Write([]byte{ctrlC}); err != nil {
+		t.Fatalf("could not write: %v", err)
+	}
+
+	select {
+	case <-k.Quit():
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for quit")
+	}
+
+	if _, ok := <-wait; ok {
+		t.Fatal("a pending wait should be released on quit")
+	}
+}
+
+func TestKeysArrows(t *testing.T) {
+	r, w := io.Pipe()
+
+	km := keymap.Default()
+	if err := km.Set("up", 0x2); err != nil {
+		t.Fatalf("could not set key: %v", err)
+	}
+
+	k := NewKeys(r, km)
+
+	// a bare escape is ignored, the arrow key is mapped.
+	for _, seq := range []string{"\x1b", "\x1b[A"} {
+		wait := k.WaitUntilKeypress()
+
+		if _, err := w.Write([]byte(seq)); err != nil {
+			t.Fatalf("could not write: %v", err)
+		}
+
+		select {
+		case key := <-wait:
+			if seq == "\x1b" {
+				t.Fatalf("escape should not press a key, got %#x", key)
+			}
+
+			if key != 0x2 {
+				t.Fatalf("want key 2, got %#x", key)
+			}
+		case <-time.After(100 * time.Millisecond):
+			if seq != "\x1b" {
+				t.Fatal("timed out waiting for the arrow key")
+			}
+		}
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package terminal
+
+import "errors"
+
+var errUnsupported = errors.New("raw terminal mode is not supported on this platform")
+
+// State is the terminal state saved by MakeRaw.
+type State struct{}
+
+func MakeRaw(_ int) (*State, error) {
+	return nil, errUnsupported
+}
+
+func Restore(_ int, _ *State) error {
+	return errUnsupported
+}
+
+func IsTerminal(_ int) bool {
+	return false
+}
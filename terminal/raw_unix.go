@@ -0,0 +1,65 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package terminal
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// State is the terminal state saved by MakeRaw.
+type State struct {
+	termios syscall.Termios
+}
+
+// MakeRaw puts the terminal connected to fd into raw mode: input is available
+// byte by byte, unechoed, and Ctrl-C no longer raises SIGINT. It returns the
+// previous state, to be passed to Restore.
+func MakeRaw(fd int) (*State, error) {
+	var old syscall.Termios
+	if err := ioctl(fd, ioctlGetTermios, &old); err != nil {
+		return nil, fmt.Errorf("could not get terminal state: %w", err)
+	}
+
+	raw := old
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+		syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	if err := ioctl(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, fmt.Errorf("could not set raw mode: %w", err)
+	}
+
+	return &State{termios: old}, nil
+}
+
+// Restore puts the terminal back into the given state.
+func Restore(fd int, state *State) error {
+	if err := ioctl(fd, ioctlSetTermios, &state.termios); err != nil {
+		return fmt.Errorf("could not restore terminal state: %w", err)
+	}
+
+	return nil
+}
+
+// IsTerminal reports whether fd is connected to a terminal.
+func IsTerminal(fd int) bool {
+	var t syscall.Termios
+
+	return ioctl(fd, ioctlGetTermios, &t) == nil
+}
+
+func ioctl(fd int, req uintptr, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+// Package terminal implements a playable text frontend: raw keyboard input
+// mapped to the hex keypad, and a renderer drawing a Display with Unicode
+// block or braille characters.
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aalbacetef/chipper"
+)
+
+// Mode selects the characters used to draw pixels.
+type Mode int
+
+const (
+	// HalfBlock draws 1x2 pixels per character cell using ▀, ▄ and █.
+	HalfBlock Mode = iota
+	// Braille draws 2x4 pixels per character cell using braille patterns.
+	Braille
+)
+
+// ParseMode returns the Mode named by s.
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(s) {
+	case "halfblock", "half-block", "block":
+		return HalfBlock, nil
+	case "braille":
+		return Braille, nil
+	default:
+		return 0, fmt.Errorf("unknown render mode '%s', want one of: halfblock, braille", s)
+	}
+}
+
+func (m Mode) cellSize() (int, int) {
+	if m == Braille {
+		return 2, 4 //nolint:mnd
+	}
+
+	return 1, 2 //nolint:mnd
+}
+
+const (
+	escClearScreen = "\x1b[2J"
+	escHideCursor  = "\x1b[?25l"
+	escShowCursor  = "\x1b[?25h"
+	escResetStyle  = "\x1b[0m"
+)
+
+// Renderer draws a Display to a terminal, only rewriting the character cells
+// that changed since the previous frame.
+type Renderer struct {
+	w      *bufio.Writer
+	mode   Mode
+	prev   []rune // nil until the first frame is drawn.
+	cols   int
+	rows   int
+	cursor int // cell the cursor is at, or -1 if unknown.
+}
+
+func NewRenderer(w io.Writer, mode Mode) *Renderer {
+	return &Renderer{w: bufio.NewWriter(w), mode: mode, cursor: -1}
+}
+
+// Render draws the current contents of d.
+func (r *Renderer) Render(d chipper.Display) error {
+	cells, cols, rows := r.cells(d)
+
+	if r.prev == nil || cols != r.cols || rows != r.rows {
+		r.w.WriteString(escClearScreen + escHideCursor)
+		r.prev = make([]rune, len(cells))
+		r.cols, r.rows = cols, rows
+		r.cursor = -1
+	}
+
+	for k, c := range cells {
+		if c == r.prev[k] {
+			continue
+		}
+
+		if k != r.cursor {
+			fmt.Fprintf(r.w, "\x1b[%d;%dH", k/cols+1, k%cols+1)
+		}
+
+		r.w.WriteRune(c)
+		r.prev[k] = c
+		r.cursor = k + 1
+
+		if r.cursor%cols == 0 {
+			r.cursor = -1 // past the end of the row, the terminal may wrap or not.
+		}
+	}
+
+	if err := r.w.Flush(); err != nil {
+		return fmt.Errorf("could not write frame: %w", err)
+	}
+
+	return nil
+}
+
+// Close moves the cursor below the picture and shows it again.
+func (r *Renderer) Close() error {
+	fmt.Fprintf(r.w, "\x1b[%d;1H%s%s\r\n", r.rows+1, escResetStyle, escShowCursor)
+
+	if err := r.w.Flush(); err != nil {
+		return fmt.Errorf("could not reset terminal: %w", err)
+	}
+
+	return nil
+}
+
+// cells converts the display into character cells, in row-major order.
+func (r *Renderer) cells(d chipper.Display) ([]rune, int, int) {
+	b := d.Bounds()
+	cw, ch := r.mode.cellSize()
+	cols := (b.Dx() + cw - 1) / cw
+	rows := (b.Dy() + ch - 1) / ch
+	set := d.ColorSet()
+
+	lit := func(x, y int) bool {
+		x, y = x+b.Min.X, y+b.Min.Y
+		if x >= b.Max.X || y >= b.Max.Y {
+			return false
+		}
+
+		return chipper.ColorEq(d.At(x, y), set)
+	}
+
+	cells := make([]rune, cols*rows)
+
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			x, y := col*cw, row*ch
+
+			if r.mode == Braille {
+				cells[row*cols+col] = brailleCell(lit, x, y)
+			} else {
+				cells[row*cols+col] = halfBlockCell(lit(x, y), lit(x, y+1))
+			}
+		}
+	}
+
+	return cells, cols, rows
+}
+
+func halfBlockCell(top, bottom bool) rune {
+	switch {
+	case top && bottom:
+		return '█'
+	case top:
+		return '▀'
+	case bottom:
+		return '▄'
+	default:
+		return ' '
+	}
+}
+
+// brailleDots holds the bit of each dot of a braille cell, indexed by [y][x].
+//
+//nolint:gochecknoglobals
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+func brailleCell(lit func(x, y int) bool, x, y int) rune {
+	const (
+		blank      = 0x2800
+		emptyCell  = ' '
+		brailleRow = 4
+		brailleCol = 2
+	)
+
+	c := rune(blank)
+
+	for dy := 0; dy < brailleRow; dy++ {
+		for dx := 0; dx < brailleCol; dx++ {
+			if lit(x+dx, y+dy) {
+				c |= brailleDots[dy][dx]
+			}
+		}
+	}
+
+	if c == blank {
+		return emptyCell
+	}
+
+	return c
+}
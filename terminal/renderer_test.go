@@ -0,0 +1,85 @@
+package terminal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aalbacetef/chipper"
+)
+
+func TestRenderer(t *testing.T) {
+	d, err := chipper.NewDebugDisplay(4, 4)
+	if err != nil {
+		t.Fatalf("could not create display: %v", err)
+	}
+
+	d.Set(0, 0, d.ColorSet())
+	d.Set(1, 1, d.ColorSet())
+	d.Set(2, 0, d.ColorSet())
+	d.Set(2, 1, d.ColorSet())
+
+	t.Run("halfblock", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		r := NewRenderer(buf, HalfBlock)
+
+		if err := r.Render(d); err != nil {
+			t.Fatalf("could not render: %v", err)
+		}
+
+		out := buf.String()
+		if !strings.HasPrefix(out, escClearScreen+escHideCursor) {
+			t.Fatalf("first frame should clear the screen, got %q", out)
+		}
+
+		if !strings.Contains(out, "\x1b[1;1H▀▄█") {
+			t.Fatalf("unexpected first row: %q", out)
+		}
+
+		// only the changed cell is redrawn.
+		buf.Reset()
+		d.Set(3, 3, d.ColorSet())
+
+		if err := r.Render(d); err != nil {
+			t.Fatalf("could not render: %v", err)
+		}
+
+		if got, want := buf.String(), "\x1b[2;4H▄"; got != want {
+			t.Fatalf("want %q, got %q", want, got)
+		}
+
+		buf.Reset()
+
+		if err := r.Render(d); err != nil {
+			t.Fatalf("could not render: %v", err)
+		}
+
+		if buf.Len() != 0 {
+			t.Fatalf("unchanged frame should write nothing, got %q", buf.String())
+		}
+	})
+
+	t.Run("braille", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		r := NewRenderer(buf, Braille)
+
+		if err := r.Render(d); err != nil {
+			t.Fatalf("could not render: %v", err)
+		}
+
+		// dots (0,0), (1,1) and (0,0), (0,1), (1,3) of the second cell.
+		if !strings.Contains(buf.String(), "\x1b[1;1H⠑⢃") {
+			t.Fatalf("unexpected output: %q", buf.String())
+		}
+	})
+}
+
+func TestParseMode(t *testing.T) {
+	if m, err := ParseMode("Braille"); err != nil || m != Braille {
+		t.Fatalf("want braille, got %v (%v)", m, err)
+	}
+
+	if _, err := ParseMode("ascii"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
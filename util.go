@@ -74,7 +74,7 @@ func bcdOfInt(v int) ([]byte, error) {
 	const base = 10
 
 	for k := 0; k < n; k++ {
-		div := int(math.Pow(base, float64(n-k)))
+		div := int(math.Pow(base, float64(n-1-k)))
 		p[k] = byte(v / div)
 		v = v - (int(p[k]) * div)
 	}
@@ -83,8 +83,12 @@ func bcdOfInt(v int) ([]byte, error) {
 }
 
 func DumpEmu(emu *Emulator) {
-	p := make([]byte, InstructionSize)
-	copy(p, emu.RAM[emu.PC:emu.PC+2])
+	p, err := emu.readBytes(int(emu.PC), InstructionSize)
+	if err != nil {
+		fmt.Println("could not read instruction: ", err)
+
+		return
+	}
 
 	instr, err := Decode(p)
 	if err != nil {
@@ -16,5 +16,12 @@ declare global {
   function SetTickPeriod(periodMilliseconds: number): void;
   function LoadROM(arr: Uint8Array, n: number): void;
   function GetDisplay(buf: Uint8Array): number;
+  function SetPersistence(mode: 'none' | 'or' | 'blend'): number;
   function SendKeyboardEvent(key: number, repeat: boolean, direction: KeyDirection): void;
+  function SendHostKeyEvent(name: string, repeat: boolean, direction: KeyDirection): number;
+  function LoadKeymap(json: string): number;
+  function SetPlatform(id: string): number;
+  function GetDisplaySize(): [number, number];
+  function SetFont(id: string): number;
+  function LoadFont(json: string): number;
 }
@@ -34,6 +34,7 @@ export function render(
 }
 
 const clearValue = 0;
+const halfValue = 2;
 
 // drawImage will take the display data and generate an ImageData to draw on the canvas.
 function drawImage(
@@ -47,12 +48,17 @@ function drawImage(
   const data = imgData.data;
   const n = buf.length;
 
+  // pixels half-lit by the blend persistence filter.
+  const half = colors.set.map((v, j) => (v + colors.clear[j]) >> 1);
+
   for (let k = 0; k < n; k++) {
     const index = k * 4;
 
     let color = colors.set;
     if (buf[k] === clearValue) {
       color = colors.clear;
+    } else if (buf[k] === halfValue) {
+      color = half;
     }
 
     for (let j = 0; j < 4; j++) {
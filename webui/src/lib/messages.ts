@@ -15,6 +15,7 @@ export enum Event {
   CanvasCreated = 'canvas-created',
   SetColors = 'set-colors',
   SetTickPeriod = 'set-tick-period',
+  SetPlatform = 'set-platform',
 }
 
 export enum MessageType {
@@ -26,8 +27,13 @@ export enum MessageType {
   RestartEmu = 'restart-emu',
   TransferOffscreenCanvas = 'transfer-offscreen-canvas',
   KeyEvent = 'key-event',
+  HostKeyEvent = 'host-key-event',
+  LoadKeymap = 'load-keymap',
   SetColors = 'set-colors',
   SetTickPeriod = 'set-tick-period',
+  SetPlatform = 'set-platform',
+  SetFont = 'set-font',
+  LoadFont = 'load-font',
 }
 
 export type WorkerEvent = {
@@ -83,6 +89,31 @@ export type SetTickPeriod = {
   data: number;
 };
 
+// SetPlatform picks the machine to emulate by the ID of a chipper preset,
+// e.g. 'vip' or 'schip'.
+export type SetPlatform = {
+  type: MessageType.SetPlatform;
+  data: {
+    id: string;
+  };
+};
+
+// SetFont picks a built-in font by its ID, e.g. 'vip'.
+export type SetFont = {
+  type: MessageType.SetFont;
+  data: {
+    id: string;
+  };
+};
+
+// LoadFont holds a font in the JSON format of chipper.LoadFont.
+export type LoadFont = {
+  type: MessageType.LoadFont;
+  data: {
+    json: string;
+  };
+};
+
 export enum KeyDirection {
   Up,
   Down,
@@ -96,3 +127,22 @@ export type KeyEvent = {
     direction: KeyDirection;
   };
 };
+
+// HostKeyEvent sends a host key, as named by KeyboardEvent.key, to be mapped
+// by the emulator's keymap.
+export type HostKeyEvent = {
+  type: MessageType.HostKeyEvent;
+  data: {
+    repeat: boolean;
+    name: string;
+    direction: KeyDirection;
+  };
+};
+
+// LoadKeymap holds a keymap file in the JSON format of the keymap package.
+export type LoadKeymap = {
+  type: MessageType.LoadKeymap;
+  data: {
+    json: string;
+  };
+};
@@ -5,6 +5,7 @@ import {
   type RestartEmu,
   type SetColors,
   type SetTickPeriod,
+  type SetPlatform,
 } from '@/lib/messages';
 
 import {
@@ -16,6 +17,10 @@ import {
   type WorkerEvent,
   type StartEmu,
   type KeyEvent,
+  type HostKeyEvent,
+  type LoadKeymap,
+  type SetFont,
+  type LoadFont,
 } from '@/lib/messages';
 import { MissingKeyError, mapHexToKey, type ColorOptions, type KeyList } from './game';
 
@@ -114,6 +119,15 @@ export class WorkerPeer {
     });
   }
 
+  setPlatform(id: string): void {
+    this.on(Event.SetPlatform, () => console.log(`set platform to: ${id}`), RunOnce);
+
+    this.postMessage<SetPlatform>({
+      type: MessageType.SetPlatform,
+      data: { id },
+    });
+  }
+
   setOnscreenCanvas(canvas: HTMLCanvasElement): void {
     this.onscreenCanvas = canvas;
   }
@@ -160,6 +174,40 @@ export class WorkerPeer {
     });
   }
 
+  // sendHostKeyEvent sends a key with no fixed keypad mapping, to be mapped by
+  // the emulator's keymap.
+  sendHostKeyEvent(direction: KeyDirection, repeat: boolean, name: string): void {
+    this.postMessage<HostKeyEvent>({
+      type: MessageType.HostKeyEvent,
+      data: {
+        name,
+        repeat,
+        direction,
+      },
+    });
+  }
+
+  loadKeymap(json: string): void {
+    this.postMessage<LoadKeymap>({
+      type: MessageType.LoadKeymap,
+      data: { json },
+    });
+  }
+
+  setFont(id: string): void {
+    this.postMessage<SetFont>({
+      type: MessageType.SetFont,
+      data: { id },
+    });
+  }
+
+  loadFont(json: string): void {
+    this.postMessage<LoadFont>({
+      type: MessageType.LoadFont,
+      data: { json },
+    });
+  }
+
   postMessage<T>(msg: T): void {
     this.worker.postMessage(msg);
   }
@@ -114,6 +114,14 @@ export const useAppStore = defineStore('app', () => {
     workerPeer!.setTickPeriod(period);
   }
 
+  function setPlatform(id: string): void {
+    workerPeer!.setPlatform(id);
+  }
+
+  function setFont(id: string): void {
+    workerPeer!.setFont(id);
+  }
+
   function setKeyState(key: keyof KeyStateMap, state: KeyDirection): void {
     keyStates.value[key] = state;
   }
@@ -133,6 +141,8 @@ export const useAppStore = defineStore('app', () => {
     isROMLoaded,
     setColor,
     setTickPeriod,
+    setPlatform,
+    setFont,
     setKeyState,
   };
 });
@@ -1,11 +1,16 @@
 import { defaultColors, render, type ColorOptions, type Dims } from '@/lib/game';
 import type {
   GenericMessage,
+  HostKeyEvent,
   KeyEvent,
+  LoadFont,
+  LoadKeymap,
   LoadROM,
   LoadWASM,
   RestartEmu,
   SetColors,
+  SetFont,
+  SetPlatform,
   SetTickPeriod,
   StartEmu,
   StopEmu,
@@ -23,11 +28,6 @@ export function initialize() {
   notifyStateChange(Event.WorkerLoaded);
 }
 
-const emulatorConstants = {
-  w: 64,
-  h: 32,
-};
-
 // WorkerInstance implements the core functionality of our Web Worker
 // in which we run our WASM emulator.
 class WorkerInstance {
@@ -44,18 +44,22 @@ class WorkerInstance {
       return;
     }
 
-    const { w, h } = emulatorConstants;
-    const buf: Uint8Array = new Uint8Array(w * h);
-
-    this.loop(buf, ctx, w, h);
+    this.loop(new Uint8Array(0), ctx);
   }
 
-  loop(buf: Uint8Array, ctx: OffscreenCanvasRenderingContext2D, w: number, h: number) {
+  // loop renders a frame and schedules the next one. The display size
+  // depends on the platform, so it is checked on every frame.
+  loop(buf: Uint8Array, ctx: OffscreenCanvasRenderingContext2D) {
     const colors = this.colors;
-    const dims: Dims = [w, h];
+    const dims: Dims = GetDisplaySize();
+    const [w, h] = dims;
+
+    if (buf.length !== w * h) {
+      buf = new Uint8Array(w * h);
+    }
 
     render(buf, ctx, dims, colors);
-    requestAnimationFrame(() => this.loop(buf, ctx, w, h));
+    requestAnimationFrame(() => this.loop(buf, ctx));
   }
 
   handleMessage(msg: GenericMessage) {
@@ -81,12 +85,27 @@ class WorkerInstance {
       case MessageType.KeyEvent:
         return this.handleKeyEvent(msg as KeyEvent);
 
+      case MessageType.HostKeyEvent:
+        return this.handleHostKeyEvent(msg as HostKeyEvent);
+
+      case MessageType.LoadKeymap:
+        return this.handleLoadKeymap(msg as LoadKeymap);
+
       case MessageType.SetColors:
         return this.handleSetColors(msg as SetColors);
 
       case MessageType.SetTickPeriod:
         return this.handleSetTickPeriod(msg as SetTickPeriod);
 
+      case MessageType.SetPlatform:
+        return this.handleSetPlatform(msg as SetPlatform);
+
+      case MessageType.SetFont:
+        return this.handleSetFont(msg as SetFont);
+
+      case MessageType.LoadFont:
+        return this.handleLoadFont(msg as LoadFont);
+
       default:
         console.log('unhandled message: ', msg);
     }
@@ -145,10 +164,43 @@ class WorkerInstance {
     SendKeyboardEvent(key, repeat, direction);
   }
 
+  handleHostKeyEvent(msg: HostKeyEvent): void {
+    const { name, repeat, direction } = msg.data;
+
+    SendHostKeyEvent(name, repeat, direction);
+  }
+
+  handleLoadKeymap(msg: LoadKeymap): void {
+    if (LoadKeymap(msg.data.json) !== 0) {
+      console.error('failed to load keymap');
+    }
+  }
+
   handleSetTickPeriod(msg: SetTickPeriod): void {
     self.SetTickPeriod(msg.data);
     notifyStateChange(Event.SetTickPeriod);
   }
+
+  handleSetPlatform(msg: SetPlatform): void {
+    if (SetPlatform(msg.data.id) !== 0) {
+      console.error('failed to set platform');
+      return;
+    }
+
+    notifyStateChange(Event.SetPlatform);
+  }
+
+  handleSetFont(msg: SetFont): void {
+    if (SetFont(msg.data.id) !== 0) {
+      console.error('failed to set font');
+    }
+  }
+
+  handleLoadFont(msg: LoadFont): void {
+    if (LoadFont(msg.data.json) !== 0) {
+      console.error('failed to load font');
+    }
+  }
 }
 
 function registerHandlers() {